@@ -9,6 +9,8 @@ import (
 	"github.com/burmanm/k8ssandra-client/cmd/kubectl-k8ssandra/migrate"
 	"github.com/burmanm/k8ssandra-client/cmd/kubectl-k8ssandra/nodetool"
 	"github.com/burmanm/k8ssandra-client/cmd/kubectl-k8ssandra/operate"
+	"github.com/burmanm/k8ssandra-client/cmd/kubectl-k8ssandra/status"
+	"github.com/burmanm/k8ssandra-client/cmd/kubectl-k8ssandra/task"
 	"github.com/burmanm/k8ssandra-client/cmd/kubectl-k8ssandra/users"
 
 	"github.com/spf13/cobra"
@@ -45,10 +47,13 @@ func NewCmd(streams genericclioptions.IOStreams) *cobra.Command {
 	cmd.AddCommand(operate.NewStartCmd(streams))
 	cmd.AddCommand(operate.NewRestartCmd(streams))
 	cmd.AddCommand(operate.NewStopCmd(streams))
+	cmd.AddCommand(operate.NewScaleCmd(streams))
 	cmd.AddCommand(list.NewCmd(streams))
 	cmd.AddCommand(migrate.NewCmd(streams))
 	cmd.AddCommand(users.NewCmd(streams))
 	cmd.AddCommand(migrate.NewInstallCmd(streams))
+	cmd.AddCommand(task.NewCmd(streams))
+	cmd.AddCommand(status.NewCmd(streams))
 
 	// cmd.Flags().BoolVar(&o.listNamespaces, "list", o.listNamespaces, "if true, print the list of all namespaces in the current KUBECONFIG")
 	o.configFlags.AddFlags(cmd.Flags())
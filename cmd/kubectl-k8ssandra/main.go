@@ -9,10 +9,10 @@ command selection, such as:
 |nodetool <node>				=> use nodetool on node X
 |restart <cluster>				=> issue rolling restart for cluster X
 |cqlsh <node>					=> exec cqlsh in the node
-task							=> execute CassandraTasks
+|task							=> execute CassandraTasks (create, list, status, cancel)
 
-	rebuild <dc> --keyspace --source <dc> 	=> rebuild the datacenter using another datacenter as source
-	replacenode <dc> <podName>				=> replace a running node
+	|rebuild <dc> --keyspace --source <dc> 	=> rebuild the datacenter using another datacenter as source (task create <dc> rebuild)
+	|replacenode <dc> <podName>				=> replace a running node (task create <dc> replacenode --pod)
 	rollingrestart 							=> (* or use the existing command under operate?)
 
 backup 							=> fetch backup information? *
@@ -35,7 +35,7 @@ install 						=> install Cassandra cluster, present config editor and auto-creat
 cleancache						=> remove cached Helm releases
 upgrade							=> upgrade k8ssandra version
 clientstatus					=> show cache size, show newest available versions (stable + devel?)
-status							=> show detailed status of installed clusters (like list, but with more details such as fetced from nodetool status)
+|status							=> show detailed status of installed clusters (like list, but with more details such as fetced from nodetool status)
 
 	Include CassandraDatacenter status also, not just Helm status
 
@@ -1,12 +1,12 @@
 package migrate
 
 import (
-	"context"
 	"fmt"
-	"sync"
+	"time"
 
 	"github.com/burmanm/k8ssandra-client/pkg/cassdcutil"
 	"github.com/burmanm/k8ssandra-client/pkg/migrate"
+	"github.com/burmanm/k8ssandra-client/pkg/util"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
@@ -25,6 +25,12 @@ var (
 
 	# Override nodetool location
 	%[1]s import add --nodetool-path=/usr/bin/nodetool
+
+	# Migrate the node running in the Docker container cassandra1
+	%[1]s import add --container=cassandra1 --container-runtime=docker
+
+	# Migrate up to two nodes of the same rack at the same time, give up if the lock is not acquired in an hour
+	%[1]s import add --lock-scope=rack --lock-concurrency=2 --lock-timeout=1h
 	`
 	errNoCassandraHome = fmt.Errorf("cassandra-home was not detected")
 )
@@ -32,12 +38,14 @@ var (
 type addOptions struct {
 	configFlags *genericclioptions.ConfigFlags
 	genericclioptions.IOStreams
+	installationOptions
 	namespace     string
-	nodetoolPath  string
-	cassandraHome string
 	dseConfigDir  string
 	cassConfigDir string
 	configDir     string
+	snapshot      bool
+	tolerate      bool
+	lock          migrate.LockConfig
 }
 
 func newAddOptions(streams genericclioptions.IOStreams) *addOptions {
@@ -78,6 +86,12 @@ func NewAddCmd(streams genericclioptions.IOStreams) *cobra.Command {
 	fl.StringVarP(&o.cassConfigDir, "cass-config-dir", "c", "", "override cassandra.yaml configuration directory")
 	fl.StringVarP(&o.dseConfigDir, "dse-config-dir", "c", "", "override dse.yaml configuration directory")
 	fl.StringVarP(&o.configDir, "config-dir", "f", "", "path to cassandra/DSE configuration directory")
+	fl.BoolVar(&o.snapshot, "snapshot", true, "take a nodetool snapshot before draining the node")
+	fl.BoolVar(&o.tolerate, "tolerate-node-taints", false, "add tolerations for the taints of the target Kubernetes node to the pod and the CassandraDatacenter")
+	fl.StringVar(&o.lock.Scope, "lock-scope", "", "scope of the migrator lock: namespace, datacenter or rack. Nodes in different scopes are migrated concurrently, make sure the replication keeps enough replicas available. The first migrated node stores the scope for the datacenter, defaults to namespace")
+	fl.IntVar(&o.lock.Concurrency, "lock-concurrency", 0, "amount of nodes that can be migrated at the same time within the lock scope. The first migrated node stores the concurrency for the datacenter, defaults to 1")
+	fl.DurationVar(&o.lock.Timeout, "lock-timeout", 0, "maximum time to wait for the migrator lock, 0 waits forever")
+	o.addContainerFlags(cmd)
 	o.configFlags.AddFlags(fl)
 	return cmd
 }
@@ -96,13 +110,10 @@ func (c *addOptions) Complete(cmd *cobra.Command, args []string) error {
 
 // Validate ensures that all required arguments and flag values are provided
 func (c *addOptions) Validate() error {
-	cassandraHome, nodetoolPath, err := migrate.DetectInstallation(c.cassandraHome, c.nodetoolPath)
-	if err != nil {
+	if err := c.detect(); err != nil {
 		return err
 	}
-	c.cassandraHome = cassandraHome
-	c.nodetoolPath = nodetoolPath
-	return nil
+	return c.lock.Validate()
 }
 
 // Run removes the finalizers for a release X in the given namespace
@@ -124,34 +135,24 @@ func (c *addOptions) Run() error {
 
 	pterm.Success.Println("Connected to Kubernetes node")
 
-	// TODO This logic belongs to the pkg
-
-	lock, err := migrate.NewResourceLock(c.namespace)
+	execOptions, err := util.GetExecOptions(genericclioptions.NewTestIOStreamsDiscard(), c.configFlags)
 	if err != nil {
-		pterm.Error.Printf("Failed to create resource lock: %v", err)
 		return err
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	wg := &sync.WaitGroup{}
-	wg.Add(1)
-
-	p.UpdateText("Waiting for migrator lock")
-
-	// Gain leader election and then proceed
-	go migrate.RunLeaderElection(ctx, wg, lock)
-	wg.Wait()
-
-	pterm.Success.Println("Acquired node migrator lock")
-
 	n := migrate.NewNodeMigrator(kubeClient, c.namespace)
-	if err != nil {
-		return err
-	}
+	n.ExecOptions = execOptions
+	n.NodetoolPath = c.nodetoolPath
+	n.CassandraHome = c.cassandraHome
+	n.CassConfigOverride = c.cassConfigDir
+	n.DseConfigOverride = c.dseConfigDir
+	n.Container = c.container
+	n.Snapshot = c.snapshot
+	n.Lock = c.lock
+	n.TolerateTaints = c.tolerate
 
 	err = n.MigrateNode(p)
+	printTimings(n.Timings(), n.DowntimeTotal())
 	if err != nil {
 		pterm.Error.Printf("Failed to migrate local Cassandra node to Kubernetes: %v", err)
 		return err
@@ -161,3 +162,28 @@ func (c *addOptions) Run() error {
 
 	return nil
 }
+
+// printTimings prints the duration of each migration step, marking the steps that were part of the downtime
+func printTimings(timings []migrate.StepTiming, downtime time.Duration) {
+	if len(timings) == 0 {
+		return
+	}
+
+	tableData := pterm.TableData{
+		{"Step", "Duration", "Downtime"},
+	}
+
+	var total time.Duration
+	for _, timing := range timings {
+		down := ""
+		if timing.Downtime {
+			down = "yes"
+		}
+		tableData = append(tableData, []string{timing.Name, timing.Duration.Round(time.Millisecond).String(), down})
+		total += timing.Duration
+	}
+
+	pterm.Println() // Blank line
+	_ = pterm.DefaultTable.WithHasHeader().WithHeaderRowSeparator("-").WithData(tableData).Render()
+	pterm.Info.Printf("Total %s, of which the node was down %s\n", total.Round(time.Millisecond), downtime.Round(time.Millisecond))
+}
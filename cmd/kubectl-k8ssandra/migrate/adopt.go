@@ -0,0 +1,136 @@
+package migrate
+
+import (
+	"fmt"
+
+	"github.com/burmanm/k8ssandra-client/pkg/cassdcutil"
+	"github.com/burmanm/k8ssandra-client/pkg/migrate"
+	"github.com/burmanm/k8ssandra-client/pkg/util"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+var (
+	importAdoptExample = `
+	# move the pods of the StatefulSet cassandra, installed by the Bitnami Helm chart, to cass-operator
+	%[1]s import adopt cassandra --namespace=cassandra
+
+	# the Cassandra container of the StatefulSet's pods is not named cassandra
+	%[1]s import adopt my-cassandra --container=server
+
+	`
+	errNoStatefulSet = fmt.Errorf("StatefulSet parameter is required")
+)
+
+type adoptOptions struct {
+	configFlags *genericclioptions.ConfigFlags
+	genericclioptions.IOStreams
+	namespace     string
+	statefulSet   string
+	containerName string
+}
+
+func newAdoptOptions(streams genericclioptions.IOStreams) *adoptOptions {
+	return &adoptOptions{
+		configFlags: genericclioptions.NewConfigFlags(true),
+		IOStreams:   streams,
+	}
+}
+
+// NewAdoptCmd provides a cobra command wrapping adoptOptions
+func NewAdoptCmd(streams genericclioptions.IOStreams) *cobra.Command {
+	o := newAdoptOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:          "adopt <statefulset> [flags]",
+		Short:        "move the pods of a Cassandra StatefulSet from another Helm chart to cass-operator, keeping the volumes",
+		Example:      fmt.Sprintf(importAdoptExample, "kubectl k8ssandra"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	fl := cmd.Flags()
+	fl.StringVar(&o.containerName, "container", migrate.CassandraContainerName, "name of the Cassandra container in the StatefulSet's pods")
+	o.configFlags.AddFlags(fl)
+	return cmd
+}
+
+// Complete parses the arguments and necessary flags to options
+func (c *adoptOptions) Complete(cmd *cobra.Command, args []string) error {
+	var err error
+
+	if len(args) < 1 {
+		return errNoStatefulSet
+	}
+
+	// The pods are replaced in the StatefulSet's namespace, the volumes can't be moved to another one
+	c.namespace, _, err = c.configFlags.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+
+	c.statefulSet = args[0]
+
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (c *adoptOptions) Validate() error {
+	if len(c.statefulSet) == 0 {
+		return errNoStatefulSet
+	}
+	if len(c.containerName) == 0 {
+		return fmt.Errorf("container name can not be empty")
+	}
+	return nil
+}
+
+// Run replaces the pods of the StatefulSet with cass-operator's pods
+func (c *adoptOptions) Run() error {
+	spinnerLiveText, _ := pterm.DefaultSpinner.Start("Preparing to adopt the StatefulSet...")
+
+	spinnerLiveText.UpdateText("Creating Kubernetes client to namespace " + c.namespace)
+
+	restConfig, err := c.configFlags.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+
+	kubeClient, err := cassdcutil.GetClientInNamespace(restConfig, c.namespace)
+	if err != nil {
+		pterm.Error.Printf("Failed to connect to Kubernetes node: %v", err)
+		return err
+	}
+
+	pterm.Success.Println("Connected to Kubernetes node")
+
+	execOptions, err := util.GetExecOptions(genericclioptions.NewTestIOStreamsDiscard(), c.configFlags)
+	if err != nil {
+		return err
+	}
+
+	adopter := migrate.NewStatefulSetAdopter(kubeClient, execOptions, c.namespace, c.statefulSet)
+	adopter.ContainerName = c.containerName
+
+	if err := adopter.Adopt(spinnerLiveText); err != nil {
+		pterm.Error.Printf("Failed to adopt StatefulSet %s: %v", c.statefulSet, err)
+		return err
+	}
+
+	spinnerLiveText.Success("StatefulSet pods have been moved to cass-operator")
+
+	return nil
+}
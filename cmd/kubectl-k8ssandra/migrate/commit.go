@@ -5,6 +5,7 @@ import (
 
 	"github.com/burmanm/k8ssandra-client/pkg/cassdcutil"
 	"github.com/burmanm/k8ssandra-client/pkg/migrate"
+	"github.com/burmanm/k8ssandra-client/pkg/util"
 	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
@@ -15,6 +16,9 @@ var (
 	# finish Cassandra to k8ssandra migration for Datacenter dc1
 	%[1]s import commit dc1 [<args>]
 
+	# finish the migration and remove the snapshots taken before the nodes were drained
+	%[1]s import commit dc1 --clear-snapshots
+
 	`
 	errNoDatacenter = fmt.Errorf("datacenter parameter is required")
 )
@@ -22,8 +26,9 @@ var (
 type commitOptions struct {
 	configFlags *genericclioptions.ConfigFlags
 	genericclioptions.IOStreams
-	namespace  string
-	datacenter string
+	namespace      string
+	datacenter     string
+	clearSnapshots bool
 }
 
 func newCommitOptions(streams genericclioptions.IOStreams) *commitOptions {
@@ -40,7 +45,7 @@ func NewCommitCmd(streams genericclioptions.IOStreams) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:          "commit <datacenter> [flags]",
 		Short:        "finish importing Cassandra installation to Kubernetes",
-		Example:      fmt.Sprintf(importExample, "kubectl k8ssandra"),
+		Example:      fmt.Sprintf(importCommitExample, "kubectl k8ssandra"),
 		SilenceUsage: true,
 		RunE: func(c *cobra.Command, args []string) error {
 			if err := o.Complete(c, args); err != nil {
@@ -58,6 +63,7 @@ func NewCommitCmd(streams genericclioptions.IOStreams) *cobra.Command {
 	}
 
 	fl := cmd.Flags()
+	fl.BoolVar(&o.clearSnapshots, "clear-snapshots", false, "remove the snapshots taken during the migration once the datacenter is ready")
 	o.configFlags.AddFlags(fl)
 	return cmd
 }
@@ -119,5 +125,26 @@ func (c *commitOptions) Run() error {
 		pterm.Error.Printf("Failed to finish the k8ssandra installation: %v", err)
 		return err
 	}
+
+	execOptions, err := util.GetExecOptions(genericclioptions.NewTestIOStreamsDiscard(), c.configFlags)
+	if err != nil {
+		return err
+	}
+	execOptions.Namespace = c.namespace
+
+	spinnerLiveText.UpdateText("Verifying the pods against the nodes before the migration...")
+	if _, err := migrate.VerifyNodes(kubeClient, execOptions, c.namespace, c.datacenter, migrate.VerificationCommitted); err != nil {
+		pterm.Warning.Printf("%v. Use 'import verify %s' for the details\n", err, c.datacenter)
+	} else {
+		pterm.Success.Println("Migrated pods match the nodes before the migration")
+	}
+
+	if c.clearSnapshots {
+		if err := migrator.ClearSnapshots(execOptions); err != nil {
+			pterm.Error.Printf("Failed to clear the migration snapshots: %v", err)
+			return err
+		}
+	}
+
 	return nil
 }
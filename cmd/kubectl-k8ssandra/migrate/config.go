@@ -0,0 +1,153 @@
+package migrate
+
+import (
+	"fmt"
+
+	"github.com/burmanm/k8ssandra-client/pkg/cassdcutil"
+	"github.com/burmanm/k8ssandra-client/pkg/migrate"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+var (
+	importConfigExample = `
+	# show the stored configuration of Datacenter dc1
+	%[1]s import config show dc1
+
+	# compare the stored configuration to the local node's configuration
+	%[1]s import config diff dc1 [<args>]
+
+	# edit the stored configuration
+	%[1]s import config edit dc1
+	`
+)
+
+type configOptions struct {
+	configFlags *genericclioptions.ConfigFlags
+	genericclioptions.IOStreams
+	namespace     string
+	datacenter    string
+	cassandraHome string
+	dseConfigDir  string
+	cassConfigDir string
+}
+
+func newConfigOptions(streams genericclioptions.IOStreams) *configOptions {
+	return &configOptions{
+		configFlags: genericclioptions.NewConfigFlags(true),
+		IOStreams:   streams,
+	}
+}
+
+// NewConfigCmd provides the subcommands to review and modify the stored configuration
+func NewConfigCmd(streams genericclioptions.IOStreams) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "config [subcommand] [flags]",
+		Short:   "review and modify the configuration stored during the import",
+		Example: fmt.Sprintf(importConfigExample, "kubectl k8ssandra"),
+	}
+
+	cmd.AddCommand(newConfigSubCmd(streams, "show", "show the stored configuration", func(o *configOptions, reviewer *migrate.ConfigReviewer) error {
+		return reviewer.Show(o.Out)
+	}))
+
+	diffCmd := newConfigSubCmd(streams, "diff", "compare the stored configuration to the local node's configuration", func(o *configOptions, reviewer *migrate.ConfigReviewer) error {
+		differences, err := reviewer.Diff(o.Out, o.cassConfigDir, o.dseConfigDir, o.cassandraHome)
+		if err != nil {
+			return err
+		}
+		if !differences {
+			pterm.Success.Println("Local configuration matches the stored configuration")
+		}
+		return nil
+	})
+	cmd.AddCommand(diffCmd)
+
+	cmd.AddCommand(newConfigSubCmd(streams, "edit", "edit the stored configuration", func(o *configOptions, reviewer *migrate.ConfigReviewer) error {
+		return reviewer.Edit()
+	}))
+
+	return cmd
+}
+
+func newConfigSubCmd(streams genericclioptions.IOStreams, name, short string, run func(*configOptions, *migrate.ConfigReviewer) error) *cobra.Command {
+	o := newConfigOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:          fmt.Sprintf("%s <datacenter> [flags]", name),
+		Short:        short,
+		Example:      fmt.Sprintf(importConfigExample, "kubectl k8ssandra"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+
+			reviewer, err := o.reviewer()
+			if err != nil {
+				return err
+			}
+
+			return run(o, reviewer)
+		},
+	}
+
+	fl := cmd.Flags()
+	if name == "diff" {
+		fl.StringVar(&o.cassandraHome, "cassandra-home", "", "path to override cassandra/DSE installation directory")
+		fl.StringVar(&o.cassConfigDir, "cass-config-dir", "", "override cassandra.yaml configuration directory")
+		fl.StringVar(&o.dseConfigDir, "dse-config-dir", "", "override dse.yaml configuration directory")
+	}
+	o.configFlags.AddFlags(fl)
+	return cmd
+}
+
+// Complete parses the arguments and necessary flags to options
+func (c *configOptions) Complete(cmd *cobra.Command, args []string) error {
+	var err error
+
+	if len(args) < 1 {
+		return errNoDatacenter
+	}
+
+	c.namespace, _, err = c.configFlags.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+
+	c.datacenter = args[0]
+
+	// migrate is our default namespace
+	if c.namespace == "default" || c.namespace == "" {
+		c.namespace = releaseName
+	}
+
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (c *configOptions) Validate() error {
+	if len(c.datacenter) == 0 {
+		return errNoDatacenter
+	}
+	return nil
+}
+
+func (c *configOptions) reviewer() (*migrate.ConfigReviewer, error) {
+	restConfig, err := c.configFlags.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	kubeClient, err := cassdcutil.GetClientInNamespace(restConfig, c.namespace)
+	if err != nil {
+		pterm.Error.Printf("Failed to connect to Kubernetes node: %v", err)
+		return nil, err
+	}
+
+	return migrate.NewConfigReviewer(kubeClient, c.namespace, c.datacenter), nil
+}
@@ -0,0 +1,194 @@
+package migrate
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/burmanm/k8ssandra-client/pkg/cassdcutil"
+	"github.com/burmanm/k8ssandra-client/pkg/migrate"
+	"github.com/burmanm/k8ssandra-client/pkg/secrets"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+var (
+	importCredentialsExample = `
+	# import the superuser cassandra of datacenter dc1, the password is prompted
+	%[1]s import credentials dc1 --username=cassandra
+
+	# import the superuser admin from a file with username=password lines
+	%[1]s import credentials dc1 --file=users.txt --username=admin
+
+	# import the superuser from a directory with username and password files, such as a mounted Secret
+	%[1]s import credentials dc1 --file=/etc/cassandra-credentials
+
+	`
+)
+
+type credentialsOptions struct {
+	configFlags *genericclioptions.ConfigFlags
+	genericclioptions.IOStreams
+	installationOptions
+	namespace  string
+	datacenter string
+	host       string
+	file       string
+	username   string
+	password   string
+}
+
+func newCredentialsOptions(streams genericclioptions.IOStreams) *credentialsOptions {
+	return &credentialsOptions{
+		configFlags: genericclioptions.NewConfigFlags(true),
+		IOStreams:   streams,
+	}
+}
+
+// NewCredentialsCmd provides a cobra command wrapping credentialsOptions
+func NewCredentialsCmd(streams genericclioptions.IOStreams) *cobra.Command {
+	o := newCredentialsOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:          "credentials <datacenter> [flags]",
+		Short:        "import the existing superuser credentials to the datacenter's superuser Secret",
+		Example:      fmt.Sprintf(importCredentialsExample, "kubectl k8ssandra"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	fl := cmd.Flags()
+	fl.StringVarP(&o.file, "file", "f", "", "file with username=password lines or a directory with username and password files")
+	fl.StringVarP(&o.username, "username", "u", "", "superuser name, selects the superuser if --file has several users")
+	fl.StringVar(&o.host, "host", "", "address of the local node's native transport, read from the cassandra.yaml if not set")
+	fl.StringVarP(&o.nodetoolPath, "nodetool-path", "p", "", "path to nodetool executable directory")
+	fl.StringVar(&o.cassandraHome, "cassandra-home", "", "path to cassandra/DSE installation directory")
+	o.addContainerFlags(cmd)
+	o.configFlags.AddFlags(fl)
+	return cmd
+}
+
+// Complete parses the arguments and necessary flags to options
+func (c *credentialsOptions) Complete(cmd *cobra.Command, args []string) error {
+	var err error
+
+	if len(args) < 1 {
+		return errNoDatacenter
+	}
+
+	c.namespace, _, err = c.configFlags.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+
+	c.datacenter = args[0]
+
+	// migrate is our default namespace
+	if c.namespace == "default" || c.namespace == "" {
+		c.namespace = releaseName
+	}
+
+	users := make(map[string]string)
+	if c.file != "" {
+		if users, err = secrets.ReadTargetPath(c.file); err != nil {
+			return err
+		}
+		delete(users, "")
+	}
+
+	if c.username == "" {
+		if len(users) != 1 {
+			return fmt.Errorf("--username is required to select the superuser")
+		}
+		for username := range users {
+			c.username = username
+		}
+	}
+
+	password, found := users[c.username]
+	if !found {
+		if password, err = c.readPassword(); err != nil {
+			return err
+		}
+	}
+	c.password = password
+
+	return nil
+}
+
+// readPassword prompts for the superuser's password
+func (c *credentialsOptions) readPassword() (string, error) {
+	fmt.Fprintf(c.Out, "Password for %s: ", c.username)
+	if f, ok := c.In.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		password, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(c.Out)
+		return string(password), err
+	}
+
+	password, err := bufio.NewReader(c.In).ReadString('\n')
+	if err != nil && password == "" {
+		return "", err
+	}
+	return strings.TrimRight(password, "\r\n"), nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (c *credentialsOptions) Validate() error {
+	if len(c.datacenter) == 0 {
+		return errNoDatacenter
+	}
+	if len(c.password) == 0 {
+		return fmt.Errorf("password of %s can not be empty", c.username)
+	}
+	return c.detect()
+}
+
+// Run verifies the credentials against the local node and stores them to the datacenter's superuser Secret
+func (c *credentialsOptions) Run() error {
+	spinnerLiveText, _ := pterm.DefaultSpinner.Start("Preparing to import credentials...")
+
+	spinnerLiveText.UpdateText("Creating Kubernetes client to namespace " + c.namespace)
+
+	restConfig, err := c.configFlags.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+
+	kubeClient, err := cassdcutil.GetClientInNamespace(restConfig, c.namespace)
+	if err != nil {
+		pterm.Error.Printf("Failed to connect to Kubernetes node: %v", err)
+		return err
+	}
+
+	pterm.Success.Println("Connected to Kubernetes node")
+
+	importer := migrate.NewCredentialsImporter(kubeClient, c.namespace, c.datacenter)
+	importer.NodetoolPath = c.nodetoolPath
+	importer.CassandraHome = c.cassandraHome
+	importer.Container = c.container
+	importer.Host = c.host
+
+	if err := importer.Import(spinnerLiveText, c.username, c.password); err != nil {
+		pterm.Error.Printf("Failed to import credentials: %v", err)
+		return err
+	}
+
+	spinnerLiveText.Success("Credentials are stored and used by the CassandraDatacenter created with 'import commit'")
+
+	return nil
+}
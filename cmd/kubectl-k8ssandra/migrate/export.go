@@ -0,0 +1,138 @@
+package migrate
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/burmanm/k8ssandra-client/pkg/cassdcutil"
+	"github.com/burmanm/k8ssandra-client/pkg/migrate"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+var (
+	importExportExample = `
+	# write the manifests of the imported datacenter dc1 to the directory clusters/dc1
+	%[1]s import export dc1 -o clusters/dc1
+
+	# also write the datacenter as values of the k8ssandra Helm chart
+	%[1]s import export dc1 -o clusters/dc1 --helm-values
+
+	`
+)
+
+type exportOptions struct {
+	configFlags *genericclioptions.ConfigFlags
+	genericclioptions.IOStreams
+	namespace  string
+	datacenter string
+	outputDir  string
+	helmValues bool
+}
+
+func newExportOptions(streams genericclioptions.IOStreams) *exportOptions {
+	return &exportOptions{
+		configFlags: genericclioptions.NewConfigFlags(true),
+		IOStreams:   streams,
+	}
+}
+
+// NewExportCmd provides a cobra command wrapping exportOptions
+func NewExportCmd(streams genericclioptions.IOStreams) *cobra.Command {
+	o := newExportOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:          "export <datacenter> [flags]",
+		Short:        "write the imported datacenter as manifests without status, to be stored in Git",
+		Example:      fmt.Sprintf(importExportExample, "kubectl k8ssandra"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	fl := cmd.Flags()
+	fl.StringVarP(&o.outputDir, "output-dir", "o", "", "directory the manifests are written to")
+	fl.BoolVar(&o.helmValues, "helm-values", false, "also write the datacenter as values of the k8ssandra Helm chart to the helm subdirectory")
+	o.configFlags.AddFlags(fl)
+	return cmd
+}
+
+// Complete parses the arguments and necessary flags to options
+func (c *exportOptions) Complete(cmd *cobra.Command, args []string) error {
+	var err error
+
+	if len(args) < 1 {
+		return errNoDatacenter
+	}
+
+	c.namespace, _, err = c.configFlags.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+
+	c.datacenter = args[0]
+
+	// migrate is our default namespace
+	if c.namespace == "default" || c.namespace == "" {
+		c.namespace = releaseName
+	}
+
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (c *exportOptions) Validate() error {
+	if len(c.datacenter) == 0 {
+		return errNoDatacenter
+	}
+	if len(c.outputDir) == 0 {
+		return fmt.Errorf("output directory is required")
+	}
+	return nil
+}
+
+// Run writes the manifests of the datacenter to the output directory
+func (c *exportOptions) Run() error {
+	restConfig, err := c.configFlags.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+
+	kubeClient, err := cassdcutil.GetClientInNamespace(restConfig, c.namespace)
+	if err != nil {
+		pterm.Error.Printf("Failed to connect to Kubernetes node: %v", err)
+		return err
+	}
+
+	exporter := migrate.NewClusterExporter(kubeClient, c.namespace, c.datacenter)
+	files, warnings, err := exporter.Export(c.helmValues)
+	if err != nil {
+		pterm.Error.Printf("Failed to export datacenter %s: %v", c.datacenter, err)
+		return err
+	}
+
+	if err := migrate.WriteExportedFiles(c.outputDir, files); err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		pterm.Success.Printf("Wrote %s\n", filepath.Join(c.outputDir, file.Name))
+	}
+	for _, warning := range warnings {
+		pterm.Warning.Println(warning)
+	}
+
+	return nil
+}
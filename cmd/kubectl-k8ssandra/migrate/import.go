@@ -1,6 +1,8 @@
 package migrate
 
 import (
+	"github.com/burmanm/k8ssandra-client/pkg/migrate"
+	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 )
@@ -31,9 +33,60 @@ func NewCmd(streams genericclioptions.IOStreams) *cobra.Command {
 	cmd.AddCommand(NewAddCmd(streams))
 	cmd.AddCommand(NewCommitCmd(streams))
 	cmd.AddCommand(NewInstallCmd(streams))
+	cmd.AddCommand(NewNetworkCmd(streams))
+	cmd.AddCommand(NewConfigCmd(streams))
+	cmd.AddCommand(NewAdoptCmd(streams))
+	cmd.AddCommand(NewCredentialsCmd(streams))
+	cmd.AddCommand(NewVerifyCmd(streams))
+	cmd.AddCommand(NewExportCmd(streams))
+	cmd.AddCommand(NewStatusCmd(streams))
 
 	// cmd.Flags().BoolVar(&o.listNamespaces, "list", o.listNamespaces, "if true, print the list of all namespaces in the current KUBECONFIG")
 	o.configFlags.AddFlags(cmd.Flags())
 
 	return cmd
 }
+
+// installationOptions locate the local Cassandra installation, either on the host or in a container
+type installationOptions struct {
+	nodetoolPath     string
+	cassandraHome    string
+	containerRuntime string
+	containerName    string
+
+	container *migrate.ContainerInstallation
+}
+
+func (i *installationOptions) addContainerFlags(cmd *cobra.Command) {
+	fl := cmd.Flags()
+	fl.StringVar(&i.containerName, "container", "", "name or id of the container running Cassandra, if the node runs in Docker or Podman")
+	fl.StringVar(&i.containerRuntime, "container-runtime", "", "container runtime of the Cassandra container: docker or podman, detected if not set")
+}
+
+// detect finds the local installation. If no installation is found on the host, a running Cassandra container is
+// searched for.
+func (i *installationOptions) detect() error {
+	if i.containerName != "" || i.containerRuntime != "" {
+		container, err := migrate.DetectContainer(i.containerRuntime, i.containerName)
+		if err != nil {
+			return err
+		}
+		i.container = container
+		return nil
+	}
+
+	cassandraHome, nodetoolPath, err := migrate.DetectInstallation(i.cassandraHome, i.nodetoolPath)
+	if err != nil {
+		container, containerErr := migrate.DetectContainer("", "")
+		if containerErr != nil {
+			return err
+		}
+		pterm.Info.Printf("Cassandra was not found on the host, using container %s\n", container)
+		i.container = container
+		return nil
+	}
+
+	i.cassandraHome = cassandraHome
+	i.nodetoolPath = nodetoolPath
+	return nil
+}
@@ -30,6 +30,9 @@ var (
 	# Use nodetool from outside $PATH
 	%[1]s import init --cassandra-home=$CASSANDRA_HOME
 
+	# Cassandra runs in the Podman container cassandra1
+	%[1]s import init --container=cassandra1 --container-runtime=podman
+
 	`
 	// errNotEnoughParameters = fmt.Errorf("not enough parameters to run nodetool")
 )
@@ -41,9 +44,8 @@ const (
 type options struct {
 	configFlags *genericclioptions.ConfigFlags
 	genericclioptions.IOStreams
+	installationOptions
 	namespace     string
-	nodetoolPath  string
-	cassandraHome string
 	dseConfigDir  string
 	cassConfigDir string
 	configDir     string
@@ -90,6 +92,7 @@ func NewInitCmd(streams genericclioptions.IOStreams) *cobra.Command {
 	fl.StringVarP(&o.cassandraHome, "cassandra-home", "c", "", "path to cassandra/DSE installation directory")
 	fl.StringVarP(&o.cassConfigDir, "cass-config-dir", "c", "", "override cassandra.yaml configuration directory")
 	fl.StringVarP(&o.dseConfigDir, "dse-config-dir", "c", "", "override dse.yaml configuration directory")
+	o.addContainerFlags(cmd)
 	o.configFlags.AddFlags(fl)
 	return cmd
 }
@@ -120,14 +123,7 @@ func (c *options) Complete(cmd *cobra.Command, args []string) error {
 
 // Validate ensures that all required arguments and flag values are provided
 func (c *options) Validate() error {
-	cassandraHome, nodetoolPath, err := migrate.DetectInstallation(c.cassandraHome, c.nodetoolPath)
-	if err != nil {
-		return err
-	}
-	c.cassandraHome = cassandraHome
-	c.nodetoolPath = nodetoolPath
-
-	return nil
+	return c.detect()
 }
 
 // Run removes the finalizers for a release X in the given namespace
@@ -158,6 +154,11 @@ func (c *options) Run() error {
 	if err != nil {
 		return err
 	}
+	migrator.NodetoolPath = c.nodetoolPath
+	migrator.CassandraHome = c.cassandraHome
+	migrator.CassConfigOverride = c.cassConfigDir
+	migrator.DseConfigOverride = c.dseConfigDir
+	migrator.Container = c.container
 
 	// TODO All of this is in the install command already
 
@@ -0,0 +1,125 @@
+package migrate
+
+import (
+	"fmt"
+
+	"github.com/burmanm/k8ssandra-client/pkg/cassdcutil"
+	"github.com/burmanm/k8ssandra-client/pkg/migrate"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+var (
+	importNetworkExample = `
+	# move the imported Datacenter dc1 from host networking to pod networking
+	%[1]s import network dc1 [<args>]
+
+	`
+)
+
+type networkOptions struct {
+	configFlags *genericclioptions.ConfigFlags
+	genericclioptions.IOStreams
+	namespace  string
+	datacenter string
+}
+
+func newNetworkOptions(streams genericclioptions.IOStreams) *networkOptions {
+	return &networkOptions{
+		configFlags: genericclioptions.NewConfigFlags(true),
+		IOStreams:   streams,
+	}
+}
+
+// NewNetworkCmd provides a cobra command wrapping networkOptions
+func NewNetworkCmd(streams genericclioptions.IOStreams) *cobra.Command {
+	o := newNetworkOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:          "network <datacenter> [flags]",
+		Short:        "move imported Cassandra installation from host networking to pod networking",
+		Example:      fmt.Sprintf(importNetworkExample, "kubectl k8ssandra"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	fl := cmd.Flags()
+	o.configFlags.AddFlags(fl)
+	return cmd
+}
+
+// Complete parses the arguments and necessary flags to options
+func (c *networkOptions) Complete(cmd *cobra.Command, args []string) error {
+	var err error
+
+	if len(args) < 1 {
+		return errNoDatacenter
+	}
+
+	c.namespace, _, err = c.configFlags.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+
+	c.datacenter = args[0]
+
+	// migrate is our default namespace
+	if c.namespace == "default" || c.namespace == "" {
+		c.namespace = releaseName
+	}
+
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (c *networkOptions) Validate() error {
+	if len(c.datacenter) == 0 {
+		return errNoDatacenter
+	}
+	return nil
+}
+
+// Run replaces the host networking of the imported datacenter with pod networking
+func (c *networkOptions) Run() error {
+	spinnerLiveText, _ := pterm.DefaultSpinner.Start("Preparing to move the datacenter to pod networking...")
+
+	spinnerLiveText.UpdateText("Creating Kubernetes client to namespace " + c.namespace)
+
+	restConfig, err := c.configFlags.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+
+	kubeClient, err := cassdcutil.GetClientInNamespace(restConfig, c.namespace)
+	if err != nil {
+		pterm.Error.Printf("Failed to connect to Kubernetes node: %v", err)
+		return err
+	}
+
+	pterm.Success.Println("Connected to Kubernetes node")
+
+	migrator := migrate.NewNetworkMigrator(kubeClient, c.namespace, c.datacenter)
+
+	err = migrator.MigrateToPodNetwork(spinnerLiveText)
+	if err != nil {
+		pterm.Error.Printf("Failed to move the datacenter to pod networking: %v", err)
+		return err
+	}
+
+	spinnerLiveText.Success("Datacenter has been moved to pod networking")
+
+	return nil
+}
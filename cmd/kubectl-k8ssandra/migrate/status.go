@@ -0,0 +1,163 @@
+package migrate
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/burmanm/k8ssandra-client/pkg/cassdcutil"
+	"github.com/burmanm/k8ssandra-client/pkg/migrate"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+var (
+	importStatusExample = `
+	# show the audit trail of the migration of datacenter dc1
+	%[1]s import status dc1
+
+	# show only the last 20 steps
+	%[1]s import status dc1 --tail=20
+
+	`
+)
+
+type statusOptions struct {
+	configFlags *genericclioptions.ConfigFlags
+	genericclioptions.IOStreams
+	namespace  string
+	datacenter string
+	tail       int
+}
+
+func newStatusOptions(streams genericclioptions.IOStreams) *statusOptions {
+	return &statusOptions{
+		configFlags: genericclioptions.NewConfigFlags(true),
+		IOStreams:   streams,
+	}
+}
+
+// NewStatusCmd provides a cobra command wrapping statusOptions
+func NewStatusCmd(streams genericclioptions.IOStreams) *cobra.Command {
+	o := newStatusOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:          "status <datacenter> [flags]",
+		Short:        "show the migration steps run for the datacenter, with the host, user and duration of each step",
+		Example:      fmt.Sprintf(importStatusExample, "kubectl k8ssandra"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	fl := cmd.Flags()
+	fl.IntVar(&o.tail, "tail", 0, "show only the given number of the latest steps")
+	o.configFlags.AddFlags(fl)
+	return cmd
+}
+
+// Complete parses the arguments and necessary flags to options
+func (c *statusOptions) Complete(cmd *cobra.Command, args []string) error {
+	var err error
+
+	if len(args) < 1 {
+		return errNoDatacenter
+	}
+
+	c.namespace, _, err = c.configFlags.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+
+	c.datacenter = args[0]
+
+	// migrate is our default namespace
+	if c.namespace == "default" || c.namespace == "" {
+		c.namespace = releaseName
+	}
+
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (c *statusOptions) Validate() error {
+	if len(c.datacenter) == 0 {
+		return errNoDatacenter
+	}
+	if c.tail < 0 {
+		return fmt.Errorf("--tail can not be negative")
+	}
+	return nil
+}
+
+// Run prints the audit trail of the datacenter
+func (c *statusOptions) Run() error {
+	restConfig, err := c.configFlags.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+
+	kubeClient, err := cassdcutil.GetClientInNamespace(restConfig, c.namespace)
+	if err != nil {
+		pterm.Error.Printf("Failed to connect to Kubernetes node: %v", err)
+		return err
+	}
+
+	records, err := migrate.FetchAuditTrail(kubeClient, c.namespace, c.datacenter)
+	if err != nil {
+		return err
+	}
+
+	if len(records) == 0 {
+		pterm.Info.Printf("No migration steps have been recorded for datacenter %s\n", c.datacenter)
+		return nil
+	}
+
+	if c.tail > 0 && len(records) > c.tail {
+		records = records[len(records)-c.tail:]
+	}
+
+	tableData := pterm.TableData{
+		{"Time", "Host", "User", "Node", "Step", "Result", "Duration"},
+	}
+	for _, record := range records {
+		node := record.Pod
+		if node == "" {
+			node = record.HostID
+		}
+		result := pterm.Green(record.Result)
+		if record.Result == migrate.AuditFailed {
+			result = pterm.Red(record.Result)
+		}
+		tableData = append(tableData, []string{
+			record.Time.Local().Format("2006-01-02 15:04:05"),
+			record.Host,
+			record.User,
+			node,
+			record.Step,
+			result,
+			record.Duration.Round(time.Second).String(),
+		})
+	}
+
+	_ = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+	for _, record := range records {
+		if record.Result == migrate.AuditFailed && record.Message != "" {
+			pterm.Error.Printf("%s %s: %s\n", record.Time.Local().Format("2006-01-02 15:04:05"), record.Step, record.Message)
+		}
+	}
+
+	return nil
+}
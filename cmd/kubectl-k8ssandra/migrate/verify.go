@@ -0,0 +1,186 @@
+package migrate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/burmanm/k8ssandra-client/pkg/cassdcutil"
+	"github.com/burmanm/k8ssandra-client/pkg/migrate"
+	"github.com/burmanm/k8ssandra-client/pkg/util"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+var (
+	importVerifyExample = `
+	# compare the migrated pods of datacenter dc1 to the nodes before the migration
+	%[1]s import verify dc1
+
+	# show the stored verification records without checking the pods again
+	%[1]s import verify dc1 --stored
+
+	`
+)
+
+type verifyOptions struct {
+	configFlags *genericclioptions.ConfigFlags
+	genericclioptions.IOStreams
+	namespace  string
+	datacenter string
+	stored     bool
+}
+
+func newVerifyOptions(streams genericclioptions.IOStreams) *verifyOptions {
+	return &verifyOptions{
+		configFlags: genericclioptions.NewConfigFlags(true),
+		IOStreams:   streams,
+	}
+}
+
+// NewVerifyCmd provides a cobra command wrapping verifyOptions
+func NewVerifyCmd(streams genericclioptions.IOStreams) *cobra.Command {
+	o := newVerifyOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:          "verify <datacenter> [flags]",
+		Short:        "verify the host ID, tokens, schema and data of the migrated nodes did not change, exits with an error on mismatch",
+		Example:      fmt.Sprintf(importVerifyExample, "kubectl k8ssandra"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	fl := cmd.Flags()
+	fl.BoolVar(&o.stored, "stored", false, "report the stored verification records without checking the pods again")
+	o.configFlags.AddFlags(fl)
+	return cmd
+}
+
+// Complete parses the arguments and necessary flags to options
+func (c *verifyOptions) Complete(cmd *cobra.Command, args []string) error {
+	var err error
+
+	if len(args) < 1 {
+		return errNoDatacenter
+	}
+
+	c.namespace, _, err = c.configFlags.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+
+	c.datacenter = args[0]
+
+	// migrate is our default namespace
+	if c.namespace == "default" || c.namespace == "" {
+		c.namespace = releaseName
+	}
+
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (c *verifyOptions) Validate() error {
+	if len(c.datacenter) == 0 {
+		return errNoDatacenter
+	}
+	return nil
+}
+
+// Run checks the migrated pods and prints the verification report
+func (c *verifyOptions) Run() error {
+	restConfig, err := c.configFlags.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+
+	kubeClient, err := cassdcutil.GetClientInNamespace(restConfig, c.namespace)
+	if err != nil {
+		pterm.Error.Printf("Failed to connect to Kubernetes node: %v", err)
+		return err
+	}
+
+	var verifications []migrate.NodeVerification
+	if c.stored {
+		verifications, err = migrate.FetchVerifications(kubeClient, c.namespace, c.datacenter)
+	} else {
+		execOptions, execErr := util.GetExecOptions(genericclioptions.NewTestIOStreamsDiscard(), c.configFlags)
+		if execErr != nil {
+			return execErr
+		}
+		verifications, err = migrate.VerifyNodes(kubeClient, execOptions, c.namespace, c.datacenter, migrate.VerificationManual)
+	}
+	if verifications == nil && err != nil {
+		return err
+	}
+
+	if len(verifications) == 0 {
+		return fmt.Errorf("no verification records found for datacenter %s", c.datacenter)
+	}
+
+	printVerifications(verifications)
+
+	if err != nil {
+		return err
+	}
+
+	failed := make([]string, 0)
+	for _, verification := range verifications {
+		if check := verification.Latest(); check == nil || len(check.Mismatches) > 0 {
+			failed = append(failed, verification.Pod)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("verification failed for pods %s", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+// printVerifications prints the latest check of the nodes followed by its changes and mismatches
+func printVerifications(verifications []migrate.NodeVerification) {
+	tableData := pterm.TableData{
+		{"Pod", "Host ID", "Captured before", "Stage", "Checked", "Result"},
+	}
+
+	for _, verification := range verifications {
+		before := verification.Before.Captured.Format("2006-01-02 15:04:05")
+		check := verification.Latest()
+		if check == nil {
+			tableData = append(tableData, []string{verification.Pod, verification.HostID, before, "-", "-", "not checked"})
+			continue
+		}
+		result := pterm.Green("match")
+		if len(check.Mismatches) > 0 {
+			result = pterm.Red(fmt.Sprintf("%d mismatches", len(check.Mismatches)))
+		}
+		tableData = append(tableData, []string{verification.Pod, verification.HostID, before, check.Stage, check.State.Captured.Format("2006-01-02 15:04:05"), result})
+	}
+
+	_ = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+	for _, verification := range verifications {
+		check := verification.Latest()
+		if check == nil {
+			continue
+		}
+		for _, change := range check.Changes {
+			pterm.Info.Printf("%s: %s\n", verification.Pod, change)
+		}
+		for _, mismatch := range check.Mismatches {
+			pterm.Error.Printf("%s: %s\n", verification.Pod, mismatch)
+		}
+	}
+}
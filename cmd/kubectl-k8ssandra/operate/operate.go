@@ -2,16 +2,28 @@ package operate
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/burmanm/k8ssandra-client/cmd/kubectl-k8ssandra/task"
 	"github.com/burmanm/k8ssandra-client/pkg/cassdcutil"
+	cassdcapi "github.com/k8ssandra/cass-operator/apis/cassandra/v1beta1"
+	"github.com/pterm/pterm"
 	"github.com/spf13/cobra"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 var (
 	startExample = `
 	# start an existing cluster that was stopped
 	%[1]s start <cluster>
+
+	# start every datacenter of the Cassandra cluster "Test Cluster" in all namespaces, one datacenter at a time
+	%[1]s start "Test Cluster" --cassandra-cluster
+
+	# start the datacenters of the K8ssandraCluster demo
+	%[1]s start demo --k8ssandra-cluster
 	`
 
 	stopExample = `
@@ -20,14 +32,28 @@ var (
 
 	# shutdown an existing cluster and wait for all the pods to shutdown
 	%[1]s stop <cluster> --wait
+
+	# shutdown every datacenter of the Cassandra cluster "Test Cluster" in all namespaces, one datacenter at a time
+	%[1]s stop "Test Cluster" --cassandra-cluster
 	`
 
 	restartExample = `
 	# request a rolling restart for cluster
 	%[1]s restart <cluster>
+
+	# restart the datacenters of the K8ssandraCluster demo one at a time, waiting for each to be Ready
+	%[1]s restart demo --k8ssandra-cluster
+
+	# restart only the pods of rack r1 with a CassandraTask, Ctrl-C deletes the task
+	%[1]s restart <cluster> --rack r1
+
+	# restart a single pod by deleting it, the StatefulSet recreates it
+	%[1]s restart <cluster> --pod cluster1-dc1-r1-sts-0
 	`
 
 	errNoClusterDefined = fmt.Errorf("no target cluster defined, could not modify state")
+	errClusterFlags     = fmt.Errorf("--cassandra-cluster and --k8ssandra-cluster can not be used together")
+	errTargetFlags      = fmt.Errorf("--rack and --pod target a single datacenter and can not be used with --cassandra-cluster or --k8ssandra-cluster")
 )
 
 type options struct {
@@ -36,7 +62,13 @@ type options struct {
 	namespace   string
 	dcName      string
 	wait        bool
+	cluster     bool
+	k8ssandra   bool
+	rack        string
+	pod         string
+	timeout     time.Duration
 	cassManager *cassdcutil.CassManager
+	datacenters []cassdcapi.CassandraDatacenter
 }
 
 func newOptions(streams genericclioptions.IOStreams) *options {
@@ -71,6 +103,7 @@ func NewStartCmd(streams genericclioptions.IOStreams) *cobra.Command {
 
 	fl := cmd.Flags()
 	fl.BoolVarP(&o.wait, "wait", "w", false, "wait until all pods have started")
+	o.addClusterFlags(cmd)
 	o.configFlags.AddFlags(fl)
 	return cmd
 }
@@ -81,7 +114,7 @@ func NewRestartCmd(streams genericclioptions.IOStreams) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:          "restart [cluster]",
 		Short:        "request rolling restart for an existing running Cassandra cluster",
-		Example:      fmt.Sprintf(startExample, "kubectl k8ssandra"),
+		Example:      fmt.Sprintf(restartExample, "kubectl k8ssandra"),
 		SilenceUsage: true,
 		RunE: func(c *cobra.Command, args []string) error {
 			if err := o.Complete(c, args); err != nil {
@@ -100,6 +133,9 @@ func NewRestartCmd(streams genericclioptions.IOStreams) *cobra.Command {
 
 	fl := cmd.Flags()
 	fl.BoolVarP(&o.wait, "wait", "w", false, "wait until all pods have restarted")
+	fl.StringVar(&o.rack, "rack", "", "restart only the pods of the rack with a CassandraTask and wait for it to finish")
+	fl.StringVar(&o.pod, "pod", "", "restart only the pod by deleting it, with --wait until the recreated pod is ready")
+	o.addClusterFlags(cmd)
 	o.configFlags.AddFlags(fl)
 	return cmd
 }
@@ -129,10 +165,18 @@ func NewStopCmd(streams genericclioptions.IOStreams) *cobra.Command {
 
 	fl := cmd.Flags()
 	fl.BoolVarP(&o.wait, "wait", "w", false, "wait until all pods have terminated")
+	o.addClusterFlags(cmd)
 	o.configFlags.AddFlags(fl)
 	return cmd
 }
 
+func (c *options) addClusterFlags(cmd *cobra.Command) {
+	fl := cmd.Flags()
+	fl.BoolVar(&c.cluster, "cassandra-cluster", false, "target every CassandraDatacenter of the named Cassandra cluster in all namespaces, one datacenter at a time")
+	fl.BoolVar(&c.k8ssandra, "k8ssandra-cluster", false, "target every CassandraDatacenter of the named K8ssandraCluster, one datacenter at a time")
+	fl.DurationVar(&c.timeout, "timeout", 0, "maximum time to wait for a datacenter that makes no progress, 5 minutes per node and at least 10 minutes if not set")
+}
+
 // Complete parses the arguments and necessary flags to options
 func (c *options) Complete(cmd *cobra.Command, args []string) error {
 	var err error
@@ -153,33 +197,165 @@ func (c *options) Complete(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	kubeClient, err := cassdcutil.GetClientInNamespace(restConfig, c.namespace)
+	if c.cluster && c.k8ssandra {
+		return errClusterFlags
+	}
+
+	if (c.rack != "" || c.pod != "") && (c.cluster || c.k8ssandra) {
+		return errTargetFlags
+	}
+
+	var kubeClient client.Client
+	if c.cluster || c.k8ssandra {
+		// Datacenters of the cluster can be in any namespace
+		kubeClient, err = cassdcutil.GetClient(restConfig)
+	} else {
+		kubeClient, err = cassdcutil.GetClientInNamespace(restConfig, c.namespace)
+	}
 	if err != nil {
 		return err
 	}
 
 	c.cassManager = cassdcutil.NewManager(kubeClient)
+	c.cassManager.SetWaitTimeout(c.timeout)
 
 	return nil
 }
 
 // Validate ensures that all required arguments and flag values are provided
 func (c *options) Validate() error {
+	var err error
+	if c.cluster {
+		c.datacenters, err = c.cassManager.ClusterDatacenters(c.dcName)
+		return err
+	}
+	if c.k8ssandra {
+		c.datacenters, err = c.cassManager.K8ssandraClusterDatacenters(c.dcName, c.namespace)
+		return err
+	}
+
 	// Verify target cluster exists
-	_, err := c.cassManager.CassandraDatacenter(c.dcName, c.namespace)
+	cassdc, err := c.cassManager.CassandraDatacenter(c.dcName, c.namespace)
 	if err != nil {
 		// NotFound is still an error
 		return err
 	}
-	return nil
+	return c.cassManager.ValidateRestartTarget(cassdc, c.rack, c.pod)
 }
 
 // Run starts an interactive cqlsh shell on target pod
 func (c *options) Run(stop bool) error {
+	if c.datacenters != nil {
+		err := c.cassManager.ModifyClusterStoppedState(c.datacenters, stop, printProgress)
+		return c.reportCluster(err)
+	}
 	return c.cassManager.ModifyStoppedState(c.dcName, c.namespace, stop, c.wait)
 }
 
 // Restart issues a restart command to target cluster
 func (c *options) Restart() error {
+	if c.datacenters != nil {
+		// Restarting a datacenter while another one is down could make data unavailable
+		if notReady := cassdcutil.NotReadyDatacenters(c.datacenters); len(notReady) > 0 {
+			return fmt.Errorf("datacenters %s are not ready, refusing to restart the cluster", strings.Join(notReady, ", "))
+		}
+		err := c.cassManager.ClusterRollingRestart(c.datacenters, printProgress)
+		return c.reportCluster(err)
+	}
+	if c.pod != "" {
+		return c.restartPod()
+	}
+	if c.rack != "" {
+		return c.restartTask()
+	}
 	return c.cassManager.RollingRestart(c.dcName, c.namespace, c.wait)
 }
+
+// restartPod deletes the pod, cass-operator's restart job can not target a single pod
+func (c *options) restartPod() error {
+	cassdc, err := c.cassManager.CassandraDatacenter(c.dcName, c.namespace)
+	if err != nil {
+		return err
+	}
+
+	if err := c.cassManager.RestartPod(cassdc, c.pod, c.wait); err != nil {
+		pterm.Error.Printf("Failed to restart pod %s: %v\n", c.pod, err)
+		return err
+	}
+
+	if c.wait {
+		pterm.Success.Printf("Pod %s restarted\n", c.pod)
+	} else {
+		pterm.Info.Printf("Deleted pod %s, the StatefulSet recreates it\n", c.pod)
+	}
+	return nil
+}
+
+// restartTask restarts the rack with a CassandraTask and follows it until it has finished. An interrupt cancels the
+// task.
+func (c *options) restartTask() error {
+	cassdc, err := c.cassManager.CassandraDatacenter(c.dcName, c.namespace)
+	if err != nil {
+		return err
+	}
+
+	support, err := c.cassManager.TaskSupport()
+	if err != nil {
+		return err
+	}
+	if !support.Supports(cassdcutil.CommandRestart) {
+		return fmt.Errorf("the installed cass-operator does not run restart tasks, restart the whole datacenter or the pods one at a time with --pod")
+	}
+
+	restartTask, err := cassdcutil.NewRestartTask(cassdc, c.rack, *support)
+	if err != nil {
+		return err
+	}
+
+	if err := c.cassManager.CreateTask(restartTask, *support); err != nil {
+		pterm.Error.Printf("Failed to create the restart task: %v\n", err)
+		return err
+	}
+	pterm.Info.Printf("Created CassandraTask %s/%s\n", restartTask.Namespace, restartTask.Name)
+
+	return task.Follow(c.cassManager, restartTask)
+}
+
+func (c *options) reportCluster(err error) error {
+	if err != nil {
+		pterm.Error.Printf("Cluster %s was not fully modified: %v\n", c.dcName, err)
+		return err
+	}
+	pterm.Success.Printf("All %d datacenters of cluster %s were modified\n", len(c.datacenters), c.dcName)
+	return nil
+}
+
+// printProgress prints the combined state of the datacenters in the cluster-wide operation
+func printProgress(progress []cassdcutil.DatacenterProgress) {
+	tableData := pterm.TableData{
+		{"Namespace", "Datacenter", "State", "Duration"},
+	}
+
+	for _, dc := range progress {
+		state := dc.State
+		switch dc.State {
+		case cassdcutil.DatacenterDone:
+			state = pterm.Green(dc.State)
+		case cassdcutil.DatacenterUpdating:
+			state = pterm.Yellow(dc.State)
+		case cassdcutil.DatacenterFailed:
+			state = pterm.Red(dc.State)
+		}
+
+		duration := "-"
+		if !dc.Finished.IsZero() {
+			duration = dc.Finished.Sub(dc.Started).Round(time.Second).String()
+		} else if !dc.Started.IsZero() {
+			duration = time.Since(dc.Started).Round(time.Second).String()
+		}
+		tableData = append(tableData, []string{dc.Namespace, dc.Name, state, duration})
+	}
+
+	fmt.Println()
+	_ = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+}
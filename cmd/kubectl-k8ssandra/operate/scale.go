@@ -0,0 +1,210 @@
+package operate
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/burmanm/k8ssandra-client/cmd/kubectl-k8ssandra/task"
+	"github.com/burmanm/k8ssandra-client/pkg/cassdcutil"
+	"github.com/burmanm/k8ssandra-client/pkg/util"
+	cassdcapi "github.com/k8ssandra/cass-operator/apis/cassandra/v1beta1"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/kubectl/pkg/cmd/exec"
+)
+
+var (
+	scaleExample = `
+	# scale datacenter dc1 to 6 nodes, wait for the new nodes and run cleanup on every node
+	%[1]s scale dc1 --size 6
+
+	# scale down to 3 nodes after checking the remaining nodes have room for the decommissioned data
+	%[1]s scale dc1 --size 3
+
+	# scale up without waiting for the cleanup task to finish
+	%[1]s scale dc1 --size 9 --no-wait-cleanup
+	`
+)
+
+type scaleOptions struct {
+	configFlags *genericclioptions.ConfigFlags
+	genericclioptions.IOStreams
+	namespace     string
+	dcName        string
+	size          int32
+	timeout       time.Duration
+	skipDiskCheck bool
+	skipCleanup   bool
+	noWaitCleanup bool
+	cassManager   *cassdcutil.CassManager
+	execOptions   *exec.ExecOptions
+	cassdc        *cassdcapi.CassandraDatacenter
+}
+
+func newScaleOptions(streams genericclioptions.IOStreams) *scaleOptions {
+	return &scaleOptions{
+		configFlags: genericclioptions.NewConfigFlags(true),
+		IOStreams:   streams,
+	}
+}
+
+// NewScaleCmd provides a cobra command wrapping scaleOptions
+func NewScaleCmd(streams genericclioptions.IOStreams) *cobra.Command {
+	o := newScaleOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:          "scale [cluster] --size N",
+		Short:        "change the number of nodes of the Cassandra datacenter with checks before and after the change",
+		Example:      fmt.Sprintf(scaleExample, "kubectl k8ssandra"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	fl := cmd.Flags()
+	fl.Int32Var(&o.size, "size", 0, "new number of nodes, must be a multiple of the rack count")
+	fl.DurationVar(&o.timeout, "timeout", time.Hour, "maximum time to wait for the nodes to join or decommission")
+	fl.BoolVar(&o.skipDiskCheck, "skip-disk-check", false, "scale down without checking the free disk of the nodes")
+	fl.BoolVar(&o.skipCleanup, "skip-cleanup", false, "do not create a cleanup task after scaling up")
+	fl.BoolVar(&o.noWaitCleanup, "no-wait-cleanup", false, "do not wait for the cleanup task to finish")
+	o.configFlags.AddFlags(fl)
+	return cmd
+}
+
+// Complete parses the arguments and necessary flags to options
+func (c *scaleOptions) Complete(cmd *cobra.Command, args []string) error {
+	var err error
+
+	if len(args) < 1 {
+		return errNoClusterDefined
+	}
+
+	c.dcName = args[0]
+
+	c.namespace, _, err = c.configFlags.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+
+	restConfig, err := c.configFlags.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+
+	kubeClient, err := cassdcutil.GetClientInNamespace(restConfig, c.namespace)
+	if err != nil {
+		return err
+	}
+
+	c.cassManager = cassdcutil.NewManager(kubeClient)
+
+	c.execOptions, err = util.GetExecOptions(genericclioptions.NewTestIOStreamsDiscard(), c.configFlags)
+	return err
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (c *scaleOptions) Validate() error {
+	var err error
+
+	c.cassdc, err = c.cassManager.CassandraDatacenter(c.dcName, c.namespace)
+	if err != nil {
+		return err
+	}
+
+	if c.cassdc.Spec.Stopped {
+		return fmt.Errorf("datacenter %s is stopped, start it before scaling", c.dcName)
+	}
+
+	return cassdcutil.ValidateScale(c.cassdc, c.size)
+}
+
+// Run checks the datacenter can be scaled, changes the size, waits for the operator to finish and cleans up the
+// data moved to the new nodes
+func (c *scaleOptions) Run() error {
+	if notReady := cassdcutil.NotReadyDatacenters([]cassdcapi.CassandraDatacenter{*c.cassdc}); len(notReady) > 0 {
+		return fmt.Errorf("datacenter %s is not ready, refusing to scale", c.dcName)
+	}
+
+	scaleDown := c.size < c.cassdc.Spec.Size
+	if scaleDown && !c.skipDiskCheck {
+		if err := c.checkCapacity(); err != nil {
+			return err
+		}
+	}
+
+	generation, err := c.cassManager.ModifySize(c.cassdc, c.size)
+	if err != nil {
+		pterm.Error.Printf("Failed to modify the size of datacenter %s: %v\n", c.dcName, err)
+		return err
+	}
+
+	spinner, _ := pterm.DefaultSpinner.Start(fmt.Sprintf("Scaling datacenter %s from %d to %d nodes...", c.dcName, c.cassdc.Spec.Size, c.size))
+	if err := c.cassManager.WaitForSize(c.cassdc, generation, c.size, c.timeout); err != nil {
+		spinner.Fail(fmt.Sprintf("Datacenter %s did not reach size %d: %v", c.dcName, c.size, err))
+		return err
+	}
+	spinner.Success(fmt.Sprintf("Datacenter %s has %d nodes", c.dcName, c.size))
+
+	if scaleDown {
+		return nil
+	}
+
+	if c.skipCleanup {
+		pterm.Warning.Println("Cleanup was skipped, the existing nodes keep the data of the ranges moved to the new nodes until cleanup is run")
+		return nil
+	}
+
+	cleanupTask, err := c.cassManager.CreateCleanupTask(c.cassdc)
+	if err != nil {
+		pterm.Error.Printf("Failed to create the cleanup task, run it with 'task create %s cleanup': %v\n", c.dcName, err)
+		return err
+	}
+	pterm.Success.Printf("Created CassandraTask %s/%s to run cleanup\n", cleanupTask.Namespace, cleanupTask.Name)
+
+	if c.noWaitCleanup {
+		return nil
+	}
+
+	return task.Follow(c.cassManager, cleanupTask)
+}
+
+// checkCapacity refuses the scale down if any node does not have room for its share of the decommissioned data
+func (c *scaleOptions) checkCapacity() error {
+	capacities, err := c.cassManager.ScaleDownCapacity(c.cassdc, c.execOptions, c.size)
+	if err != nil {
+		pterm.Error.Printf("Failed to check the free disk of the nodes, use --skip-disk-check to scale anyway: %v\n", err)
+		return err
+	}
+
+	tableData := pterm.TableData{
+		{"Pod", "Load", "Free disk", "Required", "Result"},
+	}
+
+	insufficient := 0
+	for _, capacity := range capacities {
+		result := pterm.Green("ok")
+		if !capacity.Sufficient() {
+			result = pterm.Red("insufficient")
+			insufficient++
+		}
+		tableData = append(tableData, []string{capacity.Pod, cassdcutil.FormatBytes(capacity.Load), cassdcutil.FormatBytes(capacity.Free), cassdcutil.FormatBytes(capacity.Required), result})
+	}
+	_ = pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+
+	if insufficient > 0 {
+		return fmt.Errorf("%d nodes do not have enough free disk for the data of the decommissioned nodes", insufficient)
+	}
+	return nil
+}
@@ -0,0 +1,329 @@
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/burmanm/k8ssandra-client/pkg/cassdcutil"
+	"github.com/burmanm/k8ssandra-client/pkg/util"
+	cassdcapi "github.com/k8ssandra/cass-operator/apis/cassandra/v1beta1"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/kubectl/pkg/cmd/exec"
+)
+
+var (
+	statusExample = `
+	# show the status of every datacenter in the namespace
+	%[1]s status
+
+	# show the status of datacenter dc1
+	%[1]s status dc1
+
+	# show the status of every datacenter of the Cassandra cluster in all namespaces
+	%[1]s status "Test Cluster"
+
+	# print the status as JSON without querying Cassandra
+	%[1]s status dc1 -o json --no-ring
+
+	# refresh the status every 10 seconds
+	%[1]s status dc1 --watch --interval 10s
+	`
+
+	errOutputFormat = fmt.Errorf("unsupported output format, use json or yaml")
+)
+
+type options struct {
+	configFlags *genericclioptions.ConfigFlags
+	genericclioptions.IOStreams
+	namespace   string
+	target      string
+	output      string
+	watch       bool
+	interval    time.Duration
+	events      int
+	noRing      bool
+	cassManager *cassdcutil.CassManager
+	execOptions *exec.ExecOptions
+}
+
+func newOptions(streams genericclioptions.IOStreams) *options {
+	return &options{
+		configFlags: genericclioptions.NewConfigFlags(true),
+		IOStreams:   streams,
+	}
+}
+
+// NewCmd provides a cobra command wrapping options
+func NewCmd(streams genericclioptions.IOStreams) *cobra.Command {
+	o := newOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:          "status [datacenter|cluster] [flags]",
+		Short:        "show detailed status of the datacenters, their pods, ring state and recent events",
+		Example:      fmt.Sprintf(statusExample, "kubectl k8ssandra"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	fl := cmd.Flags()
+	fl.StringVarP(&o.output, "output", "o", "", "output format, json or yaml")
+	fl.BoolVarP(&o.watch, "watch", "w", false, "refresh the status until interrupted")
+	fl.DurationVar(&o.interval, "interval", 5*time.Second, "refresh interval of --watch")
+	fl.IntVar(&o.events, "events", 10, "number of the latest events shown per datacenter")
+	fl.BoolVar(&o.noRing, "no-ring", false, "do not query the ring state and schema versions from Cassandra")
+	o.configFlags.AddFlags(fl)
+	return cmd
+}
+
+// Complete parses the arguments and necessary flags to options
+func (c *options) Complete(cmd *cobra.Command, args []string) error {
+	var err error
+
+	if len(args) > 0 {
+		c.target = args[0]
+	}
+
+	c.namespace, _, err = c.configFlags.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return err
+	}
+
+	restConfig, err := c.configFlags.ToRESTConfig()
+	if err != nil {
+		return err
+	}
+
+	// Datacenters of a cluster can be in any namespace
+	kubeClient, err := cassdcutil.GetClient(restConfig)
+	if err != nil {
+		return err
+	}
+	c.cassManager = cassdcutil.NewManager(kubeClient)
+
+	if !c.noRing {
+		if c.execOptions, err = util.GetExecOptions(genericclioptions.NewTestIOStreamsDiscard(), c.configFlags); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (c *options) Validate() error {
+	if c.output != "" && c.output != "json" && c.output != "yaml" {
+		return errOutputFormat
+	}
+	if c.interval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+	return nil
+}
+
+// Run prints the status once or until interrupted with --watch
+func (c *options) Run() error {
+	if !c.watch {
+		return c.printStatus()
+	}
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupted)
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		if c.output == "" {
+			// Clear the screen before redrawing the tables
+			fmt.Fprint(c.Out, "\033[H\033[2J")
+			pterm.Info.Printf("Every %s, updated %s\n\n", c.interval, time.Now().Format("2006-01-02 15:04:05"))
+		}
+		if err := c.printStatus(); err != nil {
+			return err
+		}
+
+		select {
+		case <-interrupted:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// datacenters returns the target datacenter, the datacenters of the target cluster or every datacenter of the namespace
+func (c *options) datacenters() ([]cassdcapi.CassandraDatacenter, error) {
+	if c.target == "" {
+		dcs, err := c.cassManager.Datacenters(c.namespace)
+		if err == nil && len(dcs) == 0 {
+			return nil, fmt.Errorf("no CassandraDatacenters found in namespace %s", c.namespace)
+		}
+		return dcs, err
+	}
+
+	cassdc, err := c.cassManager.CassandraDatacenter(c.target, c.namespace)
+	if err == nil {
+		return []cassdcapi.CassandraDatacenter{*cassdc}, nil
+	}
+	if !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	return c.cassManager.ClusterDatacenters(c.target)
+}
+
+func (c *options) printStatus() error {
+	dcs, err := c.datacenters()
+	if err != nil {
+		return err
+	}
+
+	statuses := make([]*cassdcutil.DatacenterStatus, 0, len(dcs))
+	for i := range dcs {
+		status, err := c.cassManager.DatacenterStatus(&dcs[i], c.execOptions, c.events)
+		if err != nil {
+			return err
+		}
+		statuses = append(statuses, status)
+	}
+
+	switch c.output {
+	case "json":
+		b, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(c.Out, string(b))
+		return nil
+	case "yaml":
+		// yaml.v3 has no json tag support, convert through JSON to keep the field names
+		b, err := json.Marshal(statuses)
+		if err != nil {
+			return err
+		}
+		var generic interface{}
+		if err := json.Unmarshal(b, &generic); err != nil {
+			return err
+		}
+		out, err := yaml.Marshal(generic)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(c.Out, "---\n"+string(out))
+		return nil
+	}
+
+	for _, status := range statuses {
+		printDatacenter(status)
+	}
+	return nil
+}
+
+func printDatacenter(status *cassdcutil.DatacenterStatus) {
+	state := pterm.Green("running")
+	if status.Stopped {
+		state = pterm.Yellow("stopped")
+	}
+	pterm.DefaultSection.Printf("Datacenter %s/%s of cluster %s (%s, size %d, %s)", status.Namespace, status.Name, status.Cluster, state, status.Size, status.Progress)
+
+	conditions := make([]string, 0, len(status.Conditions))
+	for _, condition := range status.Conditions {
+		text := condition.Type
+		if condition.Status == "True" {
+			text = pterm.Green(text)
+		} else {
+			text = pterm.Gray(text + "=" + condition.Status)
+		}
+		conditions = append(conditions, text)
+	}
+	pterm.Println("Conditions: " + strings.Join(conditions, ", "))
+	pterm.Println()
+
+	podData := pterm.TableData{
+		{"Pod", "Rack", "Phase", "Ready", "Node state", "Restarts"},
+	}
+	for _, pod := range status.Pods {
+		ready := pterm.Red("false")
+		if pod.Ready {
+			ready = pterm.Green("true")
+		}
+		podData = append(podData, []string{pod.Name, pod.Rack, pod.Phase, ready, pod.NodeState, fmt.Sprintf("%d", pod.Restarts)})
+	}
+	_ = pterm.DefaultTable.WithHasHeader().WithData(podData).Render()
+
+	if len(status.Ring) > 0 {
+		pterm.Println()
+		ringData := pterm.TableData{
+			{"State", "Address", "Load", "Owns", "Host ID", "Datacenter", "Rack"},
+		}
+		for _, node := range status.Ring {
+			nodeState := node.State
+			if strings.HasPrefix(nodeState, "UN") {
+				nodeState = pterm.Green(nodeState)
+			} else {
+				nodeState = pterm.Red(nodeState)
+			}
+			ringData = append(ringData, []string{nodeState, node.Address, node.Load, node.Owns, node.HostID, node.Datacenter, node.Rack})
+		}
+		_ = pterm.DefaultTable.WithHasHeader().WithData(ringData).Render()
+	}
+
+	if len(status.SchemaVersions) > 0 {
+		pterm.Println()
+		versions := make([]string, 0, len(status.SchemaVersions))
+		for version := range status.SchemaVersions {
+			versions = append(versions, version)
+		}
+		sort.Strings(versions)
+		if len(versions) == 1 {
+			pterm.Success.Printf("Schema agreement on version %s\n", versions[0])
+		} else {
+			pterm.Warning.Printf("Schema disagreement, %d versions\n", len(versions))
+			for _, version := range versions {
+				pterm.Println(fmt.Sprintf("  %s: %s", version, strings.Join(status.SchemaVersions[version], ", ")))
+			}
+		}
+	}
+
+	if len(status.Events) > 0 {
+		pterm.Println()
+		eventData := pterm.TableData{
+			{"Time", "Type", "Object", "Reason", "Message"},
+		}
+		for _, event := range status.Events {
+			eventType := event.Type
+			if eventType == "Warning" {
+				eventType = pterm.Yellow(eventType)
+			}
+			eventData = append(eventData, []string{event.Time.Local().Format("2006-01-02 15:04:05"), eventType, event.Object, event.Reason, event.Message})
+		}
+		_ = pterm.DefaultTable.WithHasHeader().WithData(eventData).Render()
+	}
+
+	for _, warning := range status.Warnings {
+		pterm.Warning.Println(warning)
+	}
+	pterm.Println()
+}
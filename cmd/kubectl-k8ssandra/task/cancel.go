@@ -0,0 +1,93 @@
+package task
+
+import (
+	"fmt"
+
+	"github.com/burmanm/k8ssandra-client/pkg/cassdcutil"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+var (
+	cancelExample = `
+	# delete the task so the job is not started on more pods, the pods already running the job or restarting are not stopped
+	%[1]s task cancel dc1-cleanup-x7k2p
+	`
+)
+
+type cancelOptions struct {
+	configFlags *genericclioptions.ConfigFlags
+	genericclioptions.IOStreams
+	namespace   string
+	taskName    string
+	cassManager *cassdcutil.CassManager
+}
+
+func newCancelOptions(streams genericclioptions.IOStreams) *cancelOptions {
+	return &cancelOptions{
+		configFlags: genericclioptions.NewConfigFlags(true),
+		IOStreams:   streams,
+	}
+}
+
+// NewCancelCmd provides a cobra command wrapping cancelOptions
+func NewCancelCmd(streams genericclioptions.IOStreams) *cobra.Command {
+	o := newCancelOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:          "cancel <task> [flags]",
+		Short:        "delete a CassandraTask so its job is not started on more pods",
+		Example:      fmt.Sprintf(cancelExample, "kubectl k8ssandra"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	o.configFlags.AddFlags(cmd.Flags())
+	return cmd
+}
+
+// Complete parses the arguments and necessary flags to options
+func (c *cancelOptions) Complete(cmd *cobra.Command, args []string) error {
+	var err error
+
+	if len(args) < 1 {
+		return errNoTask
+	}
+	c.taskName = args[0]
+
+	c.cassManager, c.namespace, err = newManager(c.configFlags)
+	return err
+}
+
+// Run deletes the task and reports the pods whose job or restart can not be stopped
+func (c *cancelOptions) Run() error {
+	task, err := c.cassManager.Task(c.taskName, c.namespace)
+	if err != nil {
+		return err
+	}
+
+	state := cassdcutil.TaskState(task)
+	if state == cassdcutil.TaskCompleted || state == cassdcutil.TaskFailed {
+		pterm.Warning.Printf("Task %s has already finished as %s\n", task.Name, state)
+	}
+
+	if err := c.cassManager.CancelTask(task); err != nil {
+		return err
+	}
+
+	pterm.Success.Printf("Deleted CassandraTask %s\n", task.Name)
+	if state == cassdcutil.TaskRunning {
+		pterm.Warning.Println("The operator does not start the job on more pods, but the pods already restarting or running the job are not stopped")
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package task
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/burmanm/k8ssandra-client/pkg/cassdcutil"
+	controlapi "github.com/k8ssandra/cass-operator/apis/control/v1alpha1"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+var (
+	createExample = `
+	# run cleanup on every pod of datacenter dc1
+	%[1]s task create dc1 cleanup
+
+	# rebuild datacenter dc2 from dc1 and wait for it to finish
+	%[1]s task create dc2 rebuild --source-datacenter dc1 --wait
+
+	# replace the node of a pod
+	%[1]s task create dc1 replacenode --pod cluster1-dc1-r1-sts-0
+
+	# restart the pods of rack r1
+	%[1]s task create dc1 restart --rack r1 --wait
+
+	# flush a single table
+	%[1]s task create dc1 flush --keyspace ks1 --tables table1
+	`
+
+	errNoCommand = fmt.Errorf("no task command given, supported commands are %s", supportedCommands())
+)
+
+type createOptions struct {
+	configFlags *genericclioptions.ConfigFlags
+	genericclioptions.IOStreams
+	namespace   string
+	dcName      string
+	command     controlapi.CassandraCommand
+	args        cassdcutil.TaskArguments
+	wait        bool
+	cassManager *cassdcutil.CassManager
+}
+
+func newCreateOptions(streams genericclioptions.IOStreams) *createOptions {
+	return &createOptions{
+		configFlags: genericclioptions.NewConfigFlags(true),
+		IOStreams:   streams,
+	}
+}
+
+// NewCreateCmd provides a cobra command wrapping createOptions
+func NewCreateCmd(streams genericclioptions.IOStreams) *cobra.Command {
+	o := newCreateOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:          "create <datacenter> <command> [flags]",
+		Short:        fmt.Sprintf("create a CassandraTask running one of %s, older operators only run cleanup and rebuild", supportedCommands()),
+		Example:      fmt.Sprintf(createExample, "kubectl k8ssandra"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	fl := cmd.Flags()
+	fl.StringVar(&o.args.Keyspace, "keyspace", "", "target keyspace, every keyspace if not set")
+	fl.StringSliceVar(&o.args.Tables, "tables", nil, "target tables of the keyspace, every table if not set")
+	fl.StringVar(&o.args.SourceDatacenter, "source-datacenter", "", "datacenter to rebuild from")
+	fl.StringVar(&o.args.Pod, "pod", "", "pod to replace")
+	fl.StringVar(&o.args.Rack, "rack", "", "rack to restart")
+	fl.IntVar(&o.args.Jobs, "jobs", 0, "number of sstables processed concurrently by cleanup, upgradesstables and scrub")
+	fl.BoolVarP(&o.wait, "wait", "w", false, "wait until the task has finished and show the progress of each pod, interrupting deletes the task")
+	o.configFlags.AddFlags(fl)
+	return cmd
+}
+
+func supportedCommands() string {
+	commands := make([]string, 0, len(cassdcutil.TaskCommands))
+	for _, command := range cassdcutil.TaskCommands {
+		commands = append(commands, string(command))
+	}
+	return strings.Join(commands, ", ")
+}
+
+// Complete parses the arguments and necessary flags to options
+func (c *createOptions) Complete(cmd *cobra.Command, args []string) error {
+	var err error
+
+	if len(args) < 1 {
+		return errNoDatacenter
+	}
+	if len(args) < 2 {
+		return errNoCommand
+	}
+
+	c.dcName = args[0]
+	c.command = controlapi.CassandraCommand(args[1])
+
+	c.cassManager, c.namespace, err = newManager(c.configFlags)
+	return err
+}
+
+// Validate ensures that all required arguments and flag values are provided
+func (c *createOptions) Validate() error {
+	for _, command := range cassdcutil.TaskCommands {
+		if command == c.command {
+			return nil
+		}
+	}
+	return errNoCommand
+}
+
+// Run creates the task if the installed operator supports it and optionally follows it until it has finished
+func (c *createOptions) Run() error {
+	cassdc, err := c.cassManager.CassandraDatacenter(c.dcName, c.namespace)
+	if err != nil {
+		return err
+	}
+
+	support, err := c.cassManager.TaskSupport()
+	if err != nil {
+		return err
+	}
+
+	if c.command == cassdcutil.CommandRestart || c.command == cassdcutil.CommandReplaceNode {
+		if err := c.cassManager.ValidateRestartTarget(cassdc, c.args.Rack, c.args.Pod); err != nil {
+			return err
+		}
+	}
+
+	task, err := cassdcutil.NewTask(cassdc, c.command, c.args, *support)
+	if err != nil {
+		return err
+	}
+
+	if err := c.cassManager.CreateTask(task, *support); err != nil {
+		pterm.Error.Printf("Failed to create the task: %v\n", err)
+		return err
+	}
+	pterm.Success.Printf("Created CassandraTask %s/%s\n", task.Namespace, task.Name)
+
+	if !c.wait {
+		return nil
+	}
+
+	return Follow(c.cassManager, task)
+}
@@ -0,0 +1,115 @@
+package task
+
+import (
+	"fmt"
+
+	"github.com/burmanm/k8ssandra-client/pkg/cassdcutil"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+var (
+	listExample = `
+	# list the CassandraTasks of the namespace
+	%[1]s task list
+
+	# list the CassandraTasks of datacenter dc1
+	%[1]s task list dc1
+	`
+)
+
+type listOptions struct {
+	configFlags *genericclioptions.ConfigFlags
+	genericclioptions.IOStreams
+	namespace   string
+	dcName      string
+	cassManager *cassdcutil.CassManager
+}
+
+func newListOptions(streams genericclioptions.IOStreams) *listOptions {
+	return &listOptions{
+		configFlags: genericclioptions.NewConfigFlags(true),
+		IOStreams:   streams,
+	}
+}
+
+// NewListCmd provides a cobra command wrapping listOptions
+func NewListCmd(streams genericclioptions.IOStreams) *cobra.Command {
+	o := newListOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:          "list [datacenter] [flags]",
+		Short:        "list the CassandraTasks and their state",
+		Example:      fmt.Sprintf(listExample, "kubectl k8ssandra"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	o.configFlags.AddFlags(cmd.Flags())
+	return cmd
+}
+
+// Complete parses the arguments and necessary flags to options
+func (c *listOptions) Complete(cmd *cobra.Command, args []string) error {
+	var err error
+
+	if len(args) > 0 {
+		c.dcName = args[0]
+	}
+
+	c.cassManager, c.namespace, err = newManager(c.configFlags)
+	return err
+}
+
+// Run prints the tasks
+func (c *listOptions) Run() error {
+	tasks, err := c.cassManager.Tasks(c.namespace, c.dcName)
+	if err != nil {
+		return err
+	}
+
+	if len(tasks) == 0 {
+		pterm.Info.Printf("No CassandraTasks found in namespace %s\n", c.namespace)
+		return nil
+	}
+
+	tableData := pterm.TableData{
+		{"Name", "Datacenter", "Command", "State", "Started", "Completed"},
+	}
+
+	for i := range tasks {
+		task := &tasks[i]
+		started, completed := "-", "-"
+		if task.Status.StartTime != nil {
+			started = task.Status.StartTime.Format("2006-01-02 15:04:05")
+		}
+		if task.Status.CompletionTime != nil {
+			completed = task.Status.CompletionTime.Format("2006-01-02 15:04:05")
+		}
+		tableData = append(tableData, []string{task.Name, task.Spec.Datacenter.Name, commands(task), stateText(cassdcutil.TaskState(task)), started, completed})
+	}
+
+	return pterm.DefaultTable.WithHasHeader().WithData(tableData).Render()
+}
+
+func stateText(state string) string {
+	switch state {
+	case cassdcutil.TaskCompleted:
+		return pterm.Green(state)
+	case cassdcutil.TaskRunning:
+		return pterm.Yellow(state)
+	case cassdcutil.TaskFailed:
+		return pterm.Red(state)
+	}
+	return state
+}
@@ -0,0 +1,155 @@
+package task
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/burmanm/k8ssandra-client/pkg/cassdcutil"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+var (
+	statusExample = `
+	# show the state of the task, its jobs and the job status of each pod
+	%[1]s task status dc1-cleanup-x7k2p
+
+	# follow the task until it has finished
+	%[1]s task status dc1-cleanup-x7k2p --wait
+	`
+)
+
+type statusOptions struct {
+	configFlags *genericclioptions.ConfigFlags
+	genericclioptions.IOStreams
+	namespace   string
+	taskName    string
+	wait        bool
+	cassManager *cassdcutil.CassManager
+}
+
+func newStatusOptions(streams genericclioptions.IOStreams) *statusOptions {
+	return &statusOptions{
+		configFlags: genericclioptions.NewConfigFlags(true),
+		IOStreams:   streams,
+	}
+}
+
+// NewStatusCmd provides a cobra command wrapping statusOptions
+func NewStatusCmd(streams genericclioptions.IOStreams) *cobra.Command {
+	o := newStatusOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:          "status <task> [flags]",
+		Short:        "show the state of a CassandraTask and its pods",
+		Example:      fmt.Sprintf(statusExample, "kubectl k8ssandra"),
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := o.Complete(c, args); err != nil {
+				return err
+			}
+			if err := o.Run(); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	fl := cmd.Flags()
+	fl.BoolVarP(&o.wait, "wait", "w", false, "wait until the task has finished and show the progress of each pod, interrupting deletes the task")
+	o.configFlags.AddFlags(fl)
+	return cmd
+}
+
+// Complete parses the arguments and necessary flags to options
+func (c *statusOptions) Complete(cmd *cobra.Command, args []string) error {
+	var err error
+
+	if len(args) < 1 {
+		return errNoTask
+	}
+	c.taskName = args[0]
+
+	c.cassManager, c.namespace, err = newManager(c.configFlags)
+	return err
+}
+
+// Run prints the task's state
+func (c *statusOptions) Run() error {
+	task, err := c.cassManager.Task(c.taskName, c.namespace)
+	if err != nil {
+		return err
+	}
+
+	tableData := pterm.TableData{
+		{"Name", task.Name},
+		{"Datacenter", task.Spec.Datacenter.Name},
+		{"State", stateText(cassdcutil.TaskState(task))},
+		{"Active", fmt.Sprintf("%d", task.Status.Active)},
+		{"Succeeded", fmt.Sprintf("%d", task.Status.Succeeded)},
+		{"Failed", fmt.Sprintf("%d", task.Status.Failed)},
+	}
+	if task.Status.StartTime != nil {
+		tableData = append(tableData, []string{"Started", task.Status.StartTime.Format("2006-01-02 15:04:05")})
+	}
+	if task.Status.CompletionTime != nil {
+		tableData = append(tableData, []string{"Completed", task.Status.CompletionTime.Format("2006-01-02 15:04:05")})
+	}
+	if err := pterm.DefaultTable.WithData(tableData).Render(); err != nil {
+		return err
+	}
+
+	jobData := pterm.TableData{
+		{"Job", "Command", "Arguments"},
+	}
+	for _, job := range task.Spec.Jobs {
+		args := make([]string, 0, len(job.Arguments))
+		for k, v := range job.Arguments {
+			args = append(args, fmt.Sprintf("%s=%s", k, v))
+		}
+		sort.Strings(args)
+		jobData = append(jobData, []string{job.Name, string(job.Command), strings.Join(args, " ")})
+	}
+	pterm.Println()
+	if err := pterm.DefaultTable.WithHasHeader().WithData(jobData).Render(); err != nil {
+		return err
+	}
+
+	pods, err := c.cassManager.TaskPods(task)
+	if err != nil {
+		return err
+	}
+
+	// The operator removes the pods' job status once the task has finished
+	if cassdcutil.TaskState(task) == cassdcutil.TaskRunning {
+		podData := pterm.TableData{
+			{"Pod", "Job status"},
+		}
+		for i := range pods {
+			status := cassdcutil.PodJobStatus(task, &pods[i])
+			if status == "" {
+				status = "-"
+			}
+			podData = append(podData, []string{pods[i].Name, status})
+		}
+		pterm.Println()
+		if err := pterm.DefaultTable.WithHasHeader().WithData(podData).Render(); err != nil {
+			return err
+		}
+	}
+
+	for _, condition := range task.Status.Conditions {
+		if condition.Message != "" {
+			pterm.Info.Printf("%s: %s\n", condition.Type, condition.Message)
+		}
+	}
+
+	if c.wait && cassdcutil.TaskState(task) != cassdcutil.TaskCompleted && cassdcutil.TaskState(task) != cassdcutil.TaskFailed {
+		return Follow(c.cassManager, task)
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/burmanm/k8ssandra-client/pkg/cassdcutil"
+	controlapi "github.com/k8ssandra/cass-operator/apis/control/v1alpha1"
+	"github.com/pterm/pterm"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+)
+
+var (
+	errNoDatacenter = fmt.Errorf("no target datacenter given")
+	errNoTask       = fmt.Errorf("no target task given")
+)
+
+type ClientOptions struct {
+	configFlags *genericclioptions.ConfigFlags
+	genericclioptions.IOStreams
+}
+
+// NewClientOptions provides an instance of NamespaceOptions with default values
+func NewClientOptions(streams genericclioptions.IOStreams) *ClientOptions {
+	return &ClientOptions{
+		configFlags: genericclioptions.NewConfigFlags(true),
+		IOStreams:   streams,
+	}
+}
+
+// NewCmd provides a cobra command wrapping NamespaceOptions
+func NewCmd(streams genericclioptions.IOStreams) *cobra.Command {
+	o := NewClientOptions(streams)
+
+	cmd := &cobra.Command{
+		Use:   "task [subcommand] [flags]",
+		Short: "create and follow CassandraTasks running operations on the datacenter's pods",
+	}
+
+	// Add subcommands
+	cmd.AddCommand(NewCreateCmd(streams))
+	cmd.AddCommand(NewListCmd(streams))
+	cmd.AddCommand(NewStatusCmd(streams))
+	cmd.AddCommand(NewCancelCmd(streams))
+
+	o.configFlags.AddFlags(cmd.Flags())
+
+	return cmd
+}
+
+// newManager creates a CassManager to the namespace of the configFlags
+func newManager(configFlags *genericclioptions.ConfigFlags) (*cassdcutil.CassManager, string, error) {
+	namespace, _, err := configFlags.ToRawKubeConfigLoader().Namespace()
+	if err != nil {
+		return nil, "", err
+	}
+
+	restConfig, err := configFlags.ToRESTConfig()
+	if err != nil {
+		return nil, "", err
+	}
+
+	kubeClient, err := cassdcutil.GetClientInNamespace(restConfig, namespace)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return cassdcutil.NewManager(kubeClient), namespace, nil
+}
+
+// Follow waits for the task to finish and prints the state changes of its pods. An interrupt deletes the task.
+func Follow(cassManager *cassdcutil.CassManager, task *controlapi.CassandraTask) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(interrupted)
+
+	go func() {
+		select {
+		case <-interrupted:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	err := cassManager.WaitForTask(ctx, task, printPodEvent)
+
+	// The context is only cancelled by the interrupt before we return
+	if ctx.Err() != nil {
+		pterm.Warning.Printf("Interrupted, deleting CassandraTask %s\n", task.Name)
+		if cancelErr := cassManager.CancelTask(task); cancelErr != nil {
+			pterm.Error.Printf("Failed to delete the task: %v\n", cancelErr)
+			return cancelErr
+		}
+		pterm.Warning.Println("No new pods will be started, but the pods already restarting or running the job will finish")
+		return fmt.Errorf("task %s was deleted before it finished", task.Name)
+	}
+
+	if err != nil {
+		pterm.Error.Printf("Task %s did not finish: %v\n", task.Name, err)
+		return err
+	}
+
+	pterm.Success.Printf("Task %s has finished\n", task.Name)
+	return nil
+}
+
+func printPodEvent(event cassdcutil.TaskPodEvent) {
+	switch event.State {
+	case cassdcutil.PodRestarting:
+		pterm.Info.Printf("Restarting pod %s\n", event.Pod)
+	case cassdcutil.PodRestarted:
+		pterm.Success.Printf("Pod %s restarted\n", event.Pod)
+	case "completed":
+		pterm.Success.Printf("Pod %s completed the job\n", event.Pod)
+	case "error":
+		pterm.Error.Printf("Pod %s failed the job\n", event.Pod)
+	default:
+		pterm.Info.Printf("Pod %s is %s\n", event.Pod, event.State)
+	}
+}
+
+// commands returns the job commands of the task
+func commands(task *controlapi.CassandraTask) string {
+	names := ""
+	for i, job := range task.Spec.Jobs {
+		if i > 0 {
+			names += ", "
+		}
+		names += string(job.Command)
+	}
+	return names
+}
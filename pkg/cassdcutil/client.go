@@ -4,6 +4,7 @@ import (
 	"context"
 
 	cassdcapi "github.com/k8ssandra/cass-operator/apis/cassandra/v1beta1"
+	controlapi "github.com/k8ssandra/cass-operator/apis/control/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -11,14 +12,18 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-// GetClient returns a controller-runtime client with cass-operator API defined
+// GetClient returns a controller-runtime client with cass-operator APIs defined
 func GetClient(restConfig *rest.Config) (client.Client, error) {
 	c, err := client.New(restConfig, client.Options{})
 	if err != nil {
 		return nil, err
 	}
 
-	err = cassdcapi.AddToScheme(c.Scheme())
+	if err = cassdcapi.AddToScheme(c.Scheme()); err != nil {
+		return nil, err
+	}
+
+	err = controlapi.AddToScheme(c.Scheme())
 
 	return c, err
 }
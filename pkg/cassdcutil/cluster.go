@@ -0,0 +1,301 @@
+package cassdcutil
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/apis/cassandra/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// k8ssandra-operator labels the CassandraDatacenters it creates with the owning K8ssandraCluster
+	k8ssandraClusterNameLabel      = "k8ssandra.io/cluster-name"
+	k8ssandraClusterNamespaceLabel = "k8ssandra.io/cluster-namespace"
+
+	// restartedAtAnnotation is set to the pod template to restart the pods of a datacenter managed by k8ssandra-operator
+	restartedAtAnnotation = "control.k8ssandra.io/restartedAt"
+
+	DatacenterPending  = "pending"
+	DatacenterUpdating = "updating"
+	DatacenterDone     = "done"
+	DatacenterFailed   = "failed"
+)
+
+var k8ssandraClusterGVK = schema.GroupVersionKind{Group: "k8ssandra.io", Version: "v1alpha1", Kind: "K8ssandraCluster"}
+
+// DatacenterProgress is the state of a single datacenter in a cluster-wide operation
+type DatacenterProgress struct {
+	Name      string
+	Namespace string
+	State     string
+	Started   time.Time
+	Finished  time.Time
+	Err       error
+}
+
+// ProgressFunc is called every time the state of a datacenter in a cluster-wide operation changes
+type ProgressFunc func(progress []DatacenterProgress)
+
+// ClusterDatacenters returns the CassandraDatacenters of the Cassandra cluster in every namespace. The cluster is matched
+// by its name or by the cassandra.datastax.com/cluster label value.
+func (c *CassManager) ClusterDatacenters(cluster string) ([]cassdcapi.CassandraDatacenter, error) {
+	dcList := &cassdcapi.CassandraDatacenterList{}
+	if err := c.client.List(context.TODO(), dcList); err != nil {
+		return nil, err
+	}
+
+	dcs := make([]cassdcapi.CassandraDatacenter, 0, len(dcList.Items))
+	for _, dc := range dcList.Items {
+		if dc.Spec.ClusterName == cluster || dc.Labels[cassdcapi.ClusterLabel] == cluster || dc.Labels[cassdcapi.ClusterLabel] == cassdcapi.CleanupForKubernetes(cluster) {
+			dcs = append(dcs, dc)
+		}
+	}
+
+	if len(dcs) == 0 {
+		return nil, fmt.Errorf("no CassandraDatacenters found for cluster %s", cluster)
+	}
+
+	sortDatacenters(dcs)
+	return dcs, nil
+}
+
+// K8ssandraClusterDatacenters returns the CassandraDatacenters created for the K8ssandraCluster in every namespace of
+// the current Kubernetes cluster
+func (c *CassManager) K8ssandraClusterDatacenters(name, namespace string) ([]cassdcapi.CassandraDatacenter, error) {
+	kc := &unstructured.Unstructured{}
+	kc.SetGroupVersionKind(k8ssandraClusterGVK)
+	if err := c.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, kc); err != nil {
+		return nil, err
+	}
+
+	dcList := &cassdcapi.CassandraDatacenterList{}
+	err := c.client.List(context.TODO(), dcList, client.MatchingLabels(map[string]string{
+		k8ssandraClusterNameLabel:      name,
+		k8ssandraClusterNamespaceLabel: namespace,
+	}))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(dcList.Items) == 0 {
+		return nil, fmt.Errorf("no CassandraDatacenters found for K8ssandraCluster %s/%s", namespace, name)
+	}
+
+	dcs := dcList.Items
+	order := k8ssandraClusterOrder(kc)
+	sort.SliceStable(dcs, func(i, j int) bool {
+		oi, foundI := order[dcs[i].Name]
+		oj, foundJ := order[dcs[j].Name]
+		if foundI != foundJ {
+			return foundI
+		}
+		if foundI && oi != oj {
+			return oi < oj
+		}
+		return datacenterKey(dcs[i]) < datacenterKey(dcs[j])
+	})
+	return dcs, nil
+}
+
+// k8ssandraClusterOrder returns the position of each datacenter in the K8ssandraCluster's spec
+func k8ssandraClusterOrder(kc *unstructured.Unstructured) map[string]int {
+	order := make(map[string]int)
+	datacenters, _, _ := unstructured.NestedSlice(kc.Object, "spec", "cassandra", "datacenters")
+	for i, dc := range datacenters {
+		dcMap, ok := dc.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if name, found, _ := unstructured.NestedString(dcMap, "metadata", "name"); found {
+			order[name] = i
+		}
+	}
+	return order
+}
+
+func datacenterKey(dc cassdcapi.CassandraDatacenter) string {
+	return dc.Namespace + "/" + dc.Name
+}
+
+func sortDatacenters(dcs []cassdcapi.CassandraDatacenter) {
+	sort.SliceStable(dcs, func(i, j int) bool {
+		return datacenterKey(dcs[i]) < datacenterKey(dcs[j])
+	})
+}
+
+// ClusterOperationOrder returns the datacenters in the order they are modified. Stopping goes through the datacenters
+// in reverse order, so the first datacenter is the last one stopped and the first one started.
+func ClusterOperationOrder(dcs []cassdcapi.CassandraDatacenter, stop bool) []cassdcapi.CassandraDatacenter {
+	ordered := make([]cassdcapi.CassandraDatacenter, len(dcs))
+	copy(ordered, dcs)
+	if stop {
+		for i, j := 0, len(ordered)-1; i < j; i, j = i+1, j-1 {
+			ordered[i], ordered[j] = ordered[j], ordered[i]
+		}
+	}
+	return ordered
+}
+
+// NotReadyDatacenters returns the datacenters that are stopped or not Ready
+func NotReadyDatacenters(dcs []cassdcapi.CassandraDatacenter) []string {
+	notReady := make([]string, 0)
+	for _, dc := range dcs {
+		if dc.Spec.Stopped || dc.Status.GetConditionStatus(cassdcapi.DatacenterReady) != corev1.ConditionTrue {
+			notReady = append(notReady, datacenterKey(dc))
+		}
+	}
+	return notReady
+}
+
+// ModifyClusterStoppedState stops or starts the datacenters one at a time, waiting for each datacenter to finish before
+// moving to the next one. The operation stops at the first datacenter that fails. Datacenters managed by
+// k8ssandra-operator are modified through their K8ssandraCluster, the operator reverts changes done to the
+// CassandraDatacenters.
+func (c *CassManager) ModifyClusterStoppedState(dcs []cassdcapi.CassandraDatacenter, stop bool, progress ProgressFunc) error {
+	return c.runClusterOperation(ClusterOperationOrder(dcs, stop), progress, func(dc cassdcapi.CassandraDatacenter) error {
+		if kcKey := k8ssandraClusterKey(dc); kcKey != nil {
+			err := c.modifyK8ssandraDatacenter(*kcKey, dc.Name, func(template map[string]interface{}) error {
+				return unstructured.SetNestedField(template, stop, "stopped")
+			})
+			if err != nil {
+				return err
+			}
+			return c.waitForStoppedState(&dc, stop)
+		}
+		if dc.Spec.Stopped == stop {
+			// Already in the requested state, but make sure the datacenter has finished the transition
+			return c.waitForStoppedState(&dc, stop)
+		}
+		return c.ModifyStoppedState(dc.Name, dc.Namespace, stop, true)
+	})
+}
+
+// ClusterRollingRestart restarts the datacenters one at a time, the next datacenter is restarted after the previous one
+// has finished its rolling restart and is Ready again. Datacenters managed by k8ssandra-operator are restarted by
+// annotating the pods of the datacenter in the K8ssandraCluster.
+func (c *CassManager) ClusterRollingRestart(dcs []cassdcapi.CassandraDatacenter, progress ProgressFunc) error {
+	return c.runClusterOperation(ClusterOperationOrder(dcs, false), progress, func(dc cassdcapi.CassandraDatacenter) error {
+		if kcKey := k8ssandraClusterKey(dc); kcKey != nil {
+			restartedAt := time.Now().UTC().Format(time.RFC3339)
+			err := c.modifyK8ssandraDatacenter(*kcKey, dc.Name, func(template map[string]interface{}) error {
+				return unstructured.SetNestedField(template, restartedAt, "metadata", "pods", "annotations", restartedAtAnnotation)
+			})
+			if err != nil {
+				return err
+			}
+			return c.waitForDatacenter(&dc, func(current *cassdcapi.CassandraDatacenter) bool {
+				return restartFinished(current, dc.Generation)
+			})
+		}
+		return c.RollingRestart(dc.Name, dc.Namespace, true)
+	})
+}
+
+// k8ssandraClusterKey returns the K8ssandraCluster that created the datacenter, nil if the datacenter is not managed
+// by k8ssandra-operator
+func k8ssandraClusterKey(dc cassdcapi.CassandraDatacenter) *types.NamespacedName {
+	name, found := dc.Labels[k8ssandraClusterNameLabel]
+	if !found {
+		return nil
+	}
+	namespace, found := dc.Labels[k8ssandraClusterNamespaceLabel]
+	if !found {
+		namespace = dc.Namespace
+	}
+	return &types.NamespacedName{Name: name, Namespace: namespace}
+}
+
+// modifyK8ssandraDatacenter updates the template of the datacenter in the K8ssandraCluster's spec
+func (c *CassManager) modifyK8ssandraDatacenter(kcKey types.NamespacedName, datacenter string, modify func(template map[string]interface{}) error) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		kc := &unstructured.Unstructured{}
+		kc.SetGroupVersionKind(k8ssandraClusterGVK)
+		if err := c.client.Get(context.TODO(), kcKey, kc); err != nil {
+			return err
+		}
+
+		if err := modifyDatacenterTemplate(kc, datacenter, modify); err != nil {
+			return err
+		}
+
+		return c.client.Update(context.TODO(), kc)
+	})
+}
+
+// modifyDatacenterTemplate modifies the datacenter in spec.cassandra.datacenters of the K8ssandraCluster
+func modifyDatacenterTemplate(kc *unstructured.Unstructured, datacenter string, modify func(template map[string]interface{}) error) error {
+	datacenters, _, err := unstructured.NestedSlice(kc.Object, "spec", "cassandra", "datacenters")
+	if err != nil {
+		return err
+	}
+
+	for i, dc := range datacenters {
+		template, ok := dc.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if name, _, _ := unstructured.NestedString(template, "metadata", "name"); name != datacenter {
+			continue
+		}
+		if err := modify(template); err != nil {
+			return err
+		}
+		datacenters[i] = template
+		return unstructured.SetNestedSlice(kc.Object, datacenters, "spec", "cassandra", "datacenters")
+	}
+
+	return fmt.Errorf("datacenter %s not found in K8ssandraCluster %s/%s", datacenter, kc.GetNamespace(), kc.GetName())
+}
+
+// restartFinished returns true when k8ssandra-operator has updated the datacenter after the given generation and
+// cass-operator has finished updating its pods
+func restartFinished(cassdc *cassdcapi.CassandraDatacenter, generation int64) bool {
+	if cassdc.Generation <= generation || cassdc.Status.ObservedGeneration < cassdc.Generation {
+		return false
+	}
+	if cassdc.Status.GetConditionStatus(cassdcapi.DatacenterUpdating) == corev1.ConditionTrue {
+		return false
+	}
+	return cassdc.Status.GetConditionStatus(cassdcapi.DatacenterReady) == corev1.ConditionTrue
+}
+
+func (c *CassManager) runClusterOperation(dcs []cassdcapi.CassandraDatacenter, progress ProgressFunc, operation func(cassdcapi.CassandraDatacenter) error) error {
+	states := make([]DatacenterProgress, 0, len(dcs))
+	for _, dc := range dcs {
+		states = append(states, DatacenterProgress{Name: dc.Name, Namespace: dc.Namespace, State: DatacenterPending})
+	}
+
+	report := func() {
+		if progress != nil {
+			progress(states)
+		}
+	}
+
+	report()
+	for i, dc := range dcs {
+		states[i].State = DatacenterUpdating
+		states[i].Started = time.Now()
+		report()
+
+		err := operation(dc)
+		states[i].Finished = time.Now()
+		if err != nil {
+			states[i].State = DatacenterFailed
+			states[i].Err = err
+			report()
+			return fmt.Errorf("datacenter %s failed, the remaining datacenters were not modified: %w", datacenterKey(dc), err)
+		}
+		states[i].State = DatacenterDone
+		report()
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package cassdcutil
+
+import (
+	"testing"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/apis/cassandra/v1beta1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func datacenter(namespace, name string, ready bool) cassdcapi.CassandraDatacenter {
+	dc := cassdcapi.CassandraDatacenter{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+	}
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	dc.Status.SetCondition(*cassdcapi.NewDatacenterCondition(cassdcapi.DatacenterReady, status))
+	return dc
+}
+
+func TestClusterOperationOrder(t *testing.T) {
+	require := require.New(t)
+	dcs := []cassdcapi.CassandraDatacenter{datacenter("a", "dc1", true), datacenter("b", "dc2", true), datacenter("c", "dc3", true)}
+
+	started := ClusterOperationOrder(dcs, false)
+	require.Equal("dc1", started[0].Name)
+	require.Equal("dc3", started[2].Name)
+
+	stopped := ClusterOperationOrder(dcs, true)
+	require.Equal("dc3", stopped[0].Name)
+	require.Equal("dc1", stopped[2].Name)
+
+	// The original order is not modified
+	require.Equal("dc1", dcs[0].Name)
+}
+
+func TestNotReadyDatacenters(t *testing.T) {
+	require := require.New(t)
+	stopped := datacenter("b", "dc2", true)
+	stopped.Spec.Stopped = true
+
+	notReady := NotReadyDatacenters([]cassdcapi.CassandraDatacenter{datacenter("a", "dc1", true), stopped, datacenter("c", "dc3", false)})
+	require.Equal([]string{"b/dc2", "c/dc3"}, notReady)
+}
+
+func TestK8ssandraClusterOrder(t *testing.T) {
+	require := require.New(t)
+	kc := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"cassandra": map[string]interface{}{
+				"datacenters": []interface{}{
+					map[string]interface{}{"metadata": map[string]interface{}{"name": "dc2"}},
+					map[string]interface{}{"metadata": map[string]interface{}{"name": "dc1"}},
+				},
+			},
+		},
+	}}
+
+	require.Equal(map[string]int{"dc2": 0, "dc1": 1}, k8ssandraClusterOrder(kc))
+}
+
+func TestK8ssandraClusterKey(t *testing.T) {
+	require := require.New(t)
+
+	dc := datacenter("dcs", "dc1", true)
+	require.Nil(k8ssandraClusterKey(dc))
+
+	dc.Labels = map[string]string{k8ssandraClusterNameLabel: "demo", k8ssandraClusterNamespaceLabel: "k8ssandra"}
+	require.Equal(&types.NamespacedName{Name: "demo", Namespace: "k8ssandra"}, k8ssandraClusterKey(dc))
+}
+
+func TestModifyDatacenterTemplate(t *testing.T) {
+	require := require.New(t)
+	kc := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"cassandra": map[string]interface{}{
+				"datacenters": []interface{}{
+					map[string]interface{}{"metadata": map[string]interface{}{"name": "dc1"}},
+					map[string]interface{}{"metadata": map[string]interface{}{"name": "dc2"}},
+				},
+			},
+		},
+	}}
+
+	require.NoError(modifyDatacenterTemplate(kc, "dc2", func(template map[string]interface{}) error {
+		return unstructured.SetNestedField(template, true, "stopped")
+	}))
+	datacenters, _, err := unstructured.NestedSlice(kc.Object, "spec", "cassandra", "datacenters")
+	require.NoError(err)
+	require.NotContains(datacenters[0], "stopped")
+	require.Equal(true, datacenters[1].(map[string]interface{})["stopped"])
+
+	require.Error(modifyDatacenterTemplate(kc, "dc3", func(template map[string]interface{}) error { return nil }))
+}
+
+func TestRestartFinished(t *testing.T) {
+	require := require.New(t)
+
+	dc := datacenter("dcs", "dc1", true)
+	dc.Generation = 2
+	dc.Status.ObservedGeneration = 2
+	require.False(restartFinished(&dc, 2))
+
+	dc.Generation = 3
+	require.False(restartFinished(&dc, 2))
+
+	dc.Status.ObservedGeneration = 3
+	dc.Status.SetCondition(*cassdcapi.NewDatacenterCondition(cassdcapi.DatacenterUpdating, corev1.ConditionTrue))
+	require.False(restartFinished(&dc, 2))
+
+	dc.Status.SetCondition(*cassdcapi.NewDatacenterCondition(cassdcapi.DatacenterUpdating, corev1.ConditionFalse))
+	require.True(restartFinished(&dc, 2))
+}
+
+func TestDatacenterProgress(t *testing.T) {
+	require := require.New(t)
+
+	dc := datacenter("dcs", "dc1", false)
+	dc.Status.SetCondition(*cassdcapi.NewDatacenterCondition(cassdcapi.DatacenterRollingRestart, corev1.ConditionTrue))
+	pods := []corev1.Pod{pod("sts-0", "a", true), pod("sts-1", "b", false)}
+	progress := datacenterProgress(&dc, pods)
+
+	require.Equal(progress, datacenterProgress(&dc, pods))
+
+	// A pod restarted and became ready again
+	pods[1] = pod("sts-1", "c", true)
+	require.NotEqual(progress, datacenterProgress(&dc, pods))
+
+	progress = datacenterProgress(&dc, pods)
+	dc.Status.SetCondition(*cassdcapi.NewDatacenterCondition(cassdcapi.DatacenterRollingRestart, corev1.ConditionFalse))
+	require.NotEqual(progress, datacenterProgress(&dc, pods))
+
+	manager := NewManager(nil)
+	dc.Spec.Size = 1
+	require.Equal(minWaitTimeout, manager.datacenterWaitTimeout(&dc))
+	dc.Spec.Size = 6
+	require.Equal(30*time.Minute, manager.datacenterWaitTimeout(&dc))
+	manager.SetWaitTimeout(time.Hour)
+	require.Equal(time.Hour, manager.datacenterWaitTimeout(&dc))
+}
@@ -2,17 +2,28 @@ package cassdcutil
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	cassdcapi "github.com/k8ssandra/cass-operator/apis/cassandra/v1beta1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	waitutil "k8s.io/apimachinery/pkg/util/wait"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+const (
+	// minWaitTimeout and nodeWaitTimeout scale the default timeout of a datacenter operation with its size
+	minWaitTimeout  = 10 * time.Minute
+	nodeWaitTimeout = 5 * time.Minute
+)
+
 type CassManager struct {
-	client client.Client
+	client      client.Client
+	waitTimeout time.Duration
 }
 
 func NewManager(client client.Client) *CassManager {
@@ -60,36 +71,24 @@ func (c *CassManager) ModifyStoppedState(name, namespace string, stop, wait bool
 	}
 
 	if wait {
-		if stop {
-			err = waitutil.PollImmediate(10*time.Second, 10*time.Minute, func() (bool, error) {
-				return c.RefreshStatus(cassdc, cassdcapi.DatacenterStopped, corev1.ConditionTrue)
-			})
-			if err != nil {
-				return err
-			}
-
-			// And wait for it to finish..
-			return waitutil.PollImmediate(10*time.Second, 10*time.Minute, func() (bool, error) {
-				return c.RefreshStatus(cassdc, cassdcapi.DatacenterReady, corev1.ConditionFalse)
-			})
-		}
-
-		err = waitutil.PollImmediate(10*time.Second, 10*time.Minute, func() (bool, error) {
-			return c.RefreshStatus(cassdc, cassdcapi.DatacenterStopped, corev1.ConditionFalse)
-		})
-		if err != nil {
-			return err
-		}
-
-		// And wait for it to finish..
-		return waitutil.PollImmediate(10*time.Second, 10*time.Minute, func() (bool, error) {
-			return c.RefreshStatus(cassdc, cassdcapi.DatacenterReady, corev1.ConditionTrue)
-		})
+		return c.waitForStoppedState(cassdc, stop)
 	}
 
 	return nil
 }
 
+// waitForStoppedState waits until the datacenter has stopped and is no longer Ready, or has started and is Ready
+func (c *CassManager) waitForStoppedState(cassdc *cassdcapi.CassandraDatacenter, stop bool) error {
+	stopped, ready := corev1.ConditionFalse, corev1.ConditionTrue
+	if stop {
+		stopped, ready = corev1.ConditionTrue, corev1.ConditionFalse
+	}
+	return c.waitForDatacenter(cassdc, func(current *cassdcapi.CassandraDatacenter) bool {
+		return current.Status.GetConditionStatus(cassdcapi.DatacenterStopped) == stopped &&
+			current.Status.GetConditionStatus(cassdcapi.DatacenterReady) == ready
+	})
+}
+
 // RollingRestart causes the CassandraDatacenter to restart all its pods
 func (c *CassManager) RollingRestart(name, namespace string, wait bool) error {
 	cassdc, err := c.CassandraDatacenter(name, namespace)
@@ -106,21 +105,114 @@ func (c *CassManager) RollingRestart(name, namespace string, wait bool) error {
 	}
 
 	if wait {
-		// Wait for rolling restart to start..
-		err = waitutil.PollImmediate(10*time.Second, 10*time.Minute, func() (bool, error) {
-			return c.RefreshStatus(cassdc, cassdcapi.DatacenterRollingRestart, corev1.ConditionTrue)
+		// The operator clears the request when it starts the rolling restart
+		return c.waitForDatacenter(cassdc, func(current *cassdcapi.CassandraDatacenter) bool {
+			return !current.Spec.RollingRestartRequested &&
+				current.Status.GetConditionStatus(cassdcapi.DatacenterRollingRestart) != corev1.ConditionTrue &&
+				current.Status.GetConditionStatus(cassdcapi.DatacenterReady) == corev1.ConditionTrue
 		})
+	}
+
+	return nil
+}
+
+// RestartPod restarts a single pod of the datacenter by deleting it, the StatefulSet recreates the pod with the same
+// data. With wait, it waits until the new pod is Ready.
+func (c *CassManager) RestartPod(cassdc *cassdcapi.CassandraDatacenter, name string, wait bool) error {
+	pod := &corev1.Pod{}
+	if err := c.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: cassdc.Namespace}, pod); err != nil {
+		return err
+	}
+
+	if err := c.client.Delete(context.TODO(), pod); err != nil {
+		return err
+	}
+
+	if !wait {
+		return nil
+	}
+
+	timeout := minWaitTimeout
+	if c.waitTimeout > 0 {
+		timeout = c.waitTimeout
+	}
+	return waitutil.PollImmediate(10*time.Second, timeout, func() (bool, error) {
+		current := &corev1.Pod{}
+		if err := c.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: cassdc.Namespace}, current); err != nil {
+			if errors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		return current.UID != pod.UID && podReady(current), nil
+	})
+}
+
+// SetWaitTimeout sets the time a datacenter operation may go without progress. Zero scales the timeout with the size
+// of the datacenter.
+func (c *CassManager) SetWaitTimeout(timeout time.Duration) {
+	c.waitTimeout = timeout
+}
+
+func (c *CassManager) datacenterWaitTimeout(cassdc *cassdcapi.CassandraDatacenter) time.Duration {
+	if c.waitTimeout > 0 {
+		return c.waitTimeout
+	}
+	timeout := time.Duration(cassdc.Spec.Size) * nodeWaitTimeout
+	if timeout < minWaitTimeout {
+		return minWaitTimeout
+	}
+	return timeout
+}
+
+// waitForDatacenter polls the datacenter until done returns true. The timeout restarts whenever the operator makes
+// progress, so large datacenters are waited for as long as their pods keep restarting.
+func (c *CassManager) waitForDatacenter(cassdc *cassdcapi.CassandraDatacenter, done func(current *cassdcapi.CassandraDatacenter) bool) error {
+	timeout := c.datacenterWaitTimeout(cassdc)
+	deadline := time.Now().Add(timeout)
+	lastProgress := ""
+
+	return waitutil.PollImmediateInfinite(10*time.Second, func() (bool, error) {
+		current, err := c.CassandraDatacenter(cassdc.Name, cassdc.Namespace)
 		if err != nil {
-			return err
+			return false, err
+		}
+		if done(current) {
+			return true, nil
 		}
 
-		// And wait for it to finish..
-		return waitutil.PollImmediate(10*time.Second, 10*time.Minute, func() (bool, error) {
-			return c.RefreshStatus(cassdc, cassdcapi.DatacenterRollingRestart, corev1.ConditionFalse)
-		})
+		pods, err := c.CassandraDatacenterPods(current)
+		if err != nil {
+			return false, err
+		}
+		if progress := datacenterProgress(current, pods.Items); progress != lastProgress {
+			lastProgress = progress
+			deadline = time.Now().Add(timeout)
+		}
+
+		if time.Now().After(deadline) {
+			return false, fmt.Errorf("datacenter %s made no progress in %s", cassdc.Name, timeout)
+		}
+		return false, nil
+	})
+}
+
+// datacenterProgress summarizes the operator's work on the datacenter, a change means the operation is progressing
+func datacenterProgress(cassdc *cassdcapi.CassandraDatacenter, pods []corev1.Pod) string {
+	conditions := make([]string, 0)
+	for _, condition := range []cassdcapi.DatacenterConditionType{cassdcapi.DatacenterReady, cassdcapi.DatacenterStopped, cassdcapi.DatacenterRollingRestart, cassdcapi.DatacenterUpdating} {
+		conditions = append(conditions, string(cassdc.Status.GetConditionStatus(condition)))
 	}
 
-	return nil
+	readyPods := make([]string, 0, len(pods))
+	for i := range pods {
+		if podReady(&pods[i]) {
+			readyPods = append(readyPods, string(pods[i].UID))
+		}
+	}
+	sort.Strings(readyPods)
+
+	return fmt.Sprintf("%d/%s/%s", cassdc.Status.ObservedGeneration, strings.Join(conditions, ","), strings.Join(readyPods, ","))
 }
 
 func (c *CassManager) RefreshStatus(cassdc *cassdcapi.CassandraDatacenter, status cassdcapi.DatacenterConditionType, wanted corev1.ConditionStatus) (bool, error) {
@@ -0,0 +1,237 @@
+package cassdcutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/apis/cassandra/v1beta1"
+	controlapi "github.com/k8ssandra/cass-operator/apis/control/v1alpha1"
+	"github.com/k8ssandra/cass-operator/pkg/httphelper"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	waitutil "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/kubectl/pkg/cmd/exec"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// cassandraDataDirectory is the mount path of the server data volume in the cass-operator's pods
+	cassandraDataDirectory = "/var/lib/cassandra"
+
+	// scaleDownHeadroom is the extra free space required on top of the streamed data, compaction needs room to work
+	scaleDownHeadroom = 1.2
+)
+
+// NodeCapacity is the load and the free disk of a node that remains after the scale down
+type NodeCapacity struct {
+	Pod      string
+	Load     float64
+	Free     float64
+	Required float64
+}
+
+// Sufficient returns true if the node has enough free disk to receive its share of the decommissioned data
+func (n NodeCapacity) Sufficient() bool {
+	return n.Free >= n.Required
+}
+
+// ValidateScale ensures the new size is different from the current one and can be evenly distributed to the racks
+func ValidateScale(cassdc *cassdcapi.CassandraDatacenter, size int32) error {
+	if size < 1 {
+		return fmt.Errorf("size must be at least 1")
+	}
+
+	if size == cassdc.Spec.Size {
+		return fmt.Errorf("datacenter %s already has size %d", cassdc.Name, size)
+	}
+
+	racks := int32(len(cassdc.GetRacks()))
+	if size%racks != 0 {
+		return fmt.Errorf("size %d is not a multiple of the %d racks of datacenter %s", size, racks, cassdc.Name)
+	}
+
+	return nil
+}
+
+// requiredFreeSpace estimates the data each remaining node receives when the datacenter is scaled down from current to
+// target nodes. The data of the decommissioned nodes is assumed to be spread evenly to the remaining nodes.
+func requiredFreeSpace(totalLoad float64, current, target int32) float64 {
+	if target >= current || current == 0 || target == 0 {
+		return 0
+	}
+	removed := totalLoad / float64(current) * float64(current-target)
+	return removed / float64(target) * scaleDownHeadroom
+}
+
+// parseDfAvailable returns the available bytes from the POSIX output of df -P -k
+func parseDfAvailable(output string) (float64, error) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) < 2 {
+		return 0, fmt.Errorf("unexpected df output: %s", output)
+	}
+
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) < 4 {
+		return 0, fmt.Errorf("unexpected df output: %s", output)
+	}
+
+	available, err := strconv.ParseFloat(fields[3], 64)
+	if err != nil {
+		return 0, fmt.Errorf("unable to parse available space from df output: %w", err)
+	}
+	return available * 1024, nil
+}
+
+// ScaleDownCapacity checks the load and the free disk of every node of the datacenter. The load is fetched from the
+// management API and the free disk of the data volume with df. Every node is checked as the operator decides which
+// nodes are decommissioned.
+func (c *CassManager) ScaleDownCapacity(cassdc *cassdcapi.CassandraDatacenter, execOptions *exec.ExecOptions, size int32) ([]NodeCapacity, error) {
+	pods, err := c.CassandraDatacenterPods(cassdc)
+	if err != nil {
+		return nil, err
+	}
+
+	var queryPod *corev1.Pod
+	for i := range pods.Items {
+		if podReady(&pods.Items[i]) {
+			queryPod = &pods.Items[i]
+			break
+		}
+	}
+	if queryPod == nil {
+		return nil, fmt.Errorf("no ready pods in datacenter %s to check the load", cassdc.Name)
+	}
+
+	out, err := execInPod(execOptions, queryPod, "wget", "-q", "-O", "-", managementAPIURL+"/api/v0/metadata/endpoints")
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := httphelper.CassMetadataEndpoints{}
+	if err := json.Unmarshal(out, &endpoints); err != nil {
+		return nil, fmt.Errorf("unable to parse the ring state from the management API: %w", err)
+	}
+
+	loads := make(map[string]float64)
+	for _, endpoint := range endpoints.Entity {
+		if endpoint.Datacenter != cassdc.Name {
+			continue
+		}
+		if load, err := strconv.ParseFloat(endpoint.Load, 64); err == nil {
+			loads[endpoint.EndpointIP] = load
+		}
+	}
+
+	totalLoad := 0.0
+	for _, load := range loads {
+		totalLoad += load
+	}
+	required := requiredFreeSpace(totalLoad, int32(len(pods.Items)), size)
+
+	capacities := make([]NodeCapacity, 0, len(pods.Items))
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		load, found := loads[pod.Status.PodIP]
+		if !found {
+			return nil, fmt.Errorf("no load reported for pod %s, is the node up?", pod.Name)
+		}
+
+		out, err := execInPod(execOptions, pod, "df", "-P", "-k", cassandraDataDirectory)
+		if err != nil {
+			return nil, err
+		}
+		free, err := parseDfAvailable(string(out))
+		if err != nil {
+			return nil, err
+		}
+
+		capacities = append(capacities, NodeCapacity{Pod: pod.Name, Load: load, Free: free, Required: required})
+	}
+
+	return capacities, nil
+}
+
+// ModifySize patches the size of the datacenter and returns the generation the operator has to observe. The size of a
+// datacenter managed by k8ssandra-operator is set in its K8ssandraCluster, the operator reverts changes done to the
+// CassandraDatacenter.
+func (c *CassManager) ModifySize(cassdc *cassdcapi.CassandraDatacenter, size int32) (int64, error) {
+	if kcKey := k8ssandraClusterKey(*cassdc); kcKey != nil {
+		err := c.modifyK8ssandraDatacenter(*kcKey, cassdc.Name, func(template map[string]interface{}) error {
+			return unstructured.SetNestedField(template, int64(size), "size")
+		})
+		if err != nil {
+			return 0, err
+		}
+		// k8ssandra-operator updates the CassandraDatacenter's spec after the K8ssandraCluster
+		return cassdc.Generation + 1, nil
+	}
+
+	patched := cassdc.DeepCopy()
+	patched.Spec.Size = size
+	if err := c.client.Patch(context.TODO(), patched, client.MergeFrom(cassdc)); err != nil {
+		return 0, err
+	}
+	return patched.Generation, nil
+}
+
+// WaitForSize waits until the operator has processed the size change, the scaling and decommission conditions are
+// cleared and the datacenter has size started nodes
+func (c *CassManager) WaitForSize(cassdc *cassdcapi.CassandraDatacenter, generation int64, size int32, timeout time.Duration) error {
+	return waitutil.PollImmediate(10*time.Second, timeout, func() (bool, error) {
+		current, err := c.CassandraDatacenter(cassdc.Name, cassdc.Namespace)
+		if err != nil {
+			return false, err
+		}
+
+		pods, err := c.CassandraDatacenterPods(current)
+		if err != nil {
+			return false, err
+		}
+
+		return scaleFinished(current, pods.Items, generation, size), nil
+	})
+}
+
+// scaleFinished returns true when the datacenter has reached the requested size and no longer scales
+func scaleFinished(cassdc *cassdcapi.CassandraDatacenter, pods []corev1.Pod, generation int64, size int32) bool {
+	if cassdc.Status.ObservedGeneration < generation {
+		return false
+	}
+
+	for _, condition := range []cassdcapi.DatacenterConditionType{cassdcapi.DatacenterScalingUp, cassdcapi.DatacenterScalingDown, cassdcapi.DatacenterDecommission} {
+		if cassdc.Status.GetConditionStatus(condition) == corev1.ConditionTrue {
+			return false
+		}
+	}
+
+	if cassdc.Status.GetConditionStatus(cassdcapi.DatacenterReady) != corev1.ConditionTrue {
+		return false
+	}
+
+	started := int32(0)
+	for i := range pods {
+		if pods[i].Labels[NodeStateLabel] == "Started" && podReady(&pods[i]) {
+			started++
+		}
+	}
+	return started == size && int32(len(pods)) == size
+}
+
+// CreateCleanupTask creates a CassandraTask running cleanup on every node of the datacenter, the existing nodes keep
+// the data of the token ranges moved to the new nodes until it is run
+func (c *CassManager) CreateCleanupTask(cassdc *cassdcapi.CassandraDatacenter) (*controlapi.CassandraTask, error) {
+	// Every operator version runs cleanup without arguments
+	support := TaskSupport{}
+	task, err := NewTask(cassdc, controlapi.CommandCleanup, TaskArguments{}, support)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.CreateTask(task, support); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
@@ -0,0 +1,75 @@
+package cassdcutil
+
+import (
+	"testing"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/apis/cassandra/v1beta1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestValidateScale(t *testing.T) {
+	require := require.New(t)
+	dc := datacenter("a", "dc1", true)
+	dc.Spec.Size = 3
+	dc.Spec.Racks = []cassdcapi.Rack{{Name: "r1"}, {Name: "r2"}, {Name: "r3"}}
+
+	require.NoError(ValidateScale(&dc, 6))
+	require.Error(ValidateScale(&dc, 3))
+	require.Error(ValidateScale(&dc, 4))
+	require.Error(ValidateScale(&dc, 0))
+
+	// Without racks the datacenter has a single default rack
+	dc.Spec.Racks = nil
+	require.NoError(ValidateScale(&dc, 4))
+}
+
+func TestRequiredFreeSpace(t *testing.T) {
+	require := require.New(t)
+
+	// 6 nodes with 100 each, 3 are removed and their 300 is spread to the remaining 3 nodes
+	require.InDelta(100*scaleDownHeadroom, requiredFreeSpace(600, 6, 3), 0.001)
+	require.Equal(0.0, requiredFreeSpace(600, 3, 6))
+
+	capacity := NodeCapacity{Free: 110, Required: requiredFreeSpace(600, 6, 3)}
+	require.False(capacity.Sufficient())
+	capacity.Free = 200
+	require.True(capacity.Sufficient())
+}
+
+func TestParseDfAvailable(t *testing.T) {
+	require := require.New(t)
+	output := `Filesystem     1024-blocks     Used Available Capacity Mounted on
+/dev/sdb          10255636  2411588   7827664      24% /var/lib/cassandra
+`
+	available, err := parseDfAvailable(output)
+	require.NoError(err)
+	require.Equal(7827664.0*1024, available)
+
+	_, err = parseDfAvailable("df: /var/lib/cassandra: No such file or directory")
+	require.Error(err)
+}
+
+func TestScaleFinished(t *testing.T) {
+	require := require.New(t)
+	dc := datacenter("a", "dc1", true)
+	dc.Status.ObservedGeneration = 2
+
+	started := func(name string) corev1.Pod {
+		p := pod(name, "", true)
+		p.Labels = map[string]string{NodeStateLabel: "Started"}
+		return p
+	}
+	pods := []corev1.Pod{started("sts-0"), started("sts-1")}
+
+	require.True(scaleFinished(&dc, pods, 2, 2))
+	require.False(scaleFinished(&dc, pods, 3, 2))
+	require.False(scaleFinished(&dc, pods, 2, 3))
+
+	joining := pod("sts-2", "", false)
+	joining.Labels = map[string]string{NodeStateLabel: "Starting"}
+	require.False(scaleFinished(&dc, append(pods, joining), 2, 3))
+
+	dc.Status.SetCondition(*cassdcapi.NewDatacenterCondition(cassdcapi.DatacenterScalingUp, corev1.ConditionTrue))
+	require.False(scaleFinished(&dc, pods, 2, 2))
+}
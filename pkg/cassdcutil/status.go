@@ -0,0 +1,371 @@
+package cassdcutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/apis/cassandra/v1beta1"
+	"github.com/k8ssandra/cass-operator/pkg/httphelper"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/kubectl/pkg/cmd/exec"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// NodeStateLabel is the cass-operator's label for the state of the Cassandra process in the pod
+	NodeStateLabel = "cassandra.datastax.com/node-state"
+
+	// managementAPIURL is the management API address inside the Cassandra container
+	managementAPIURL = "http://localhost:8080"
+)
+
+// StatusConditions are the CassandraDatacenter conditions shown in the status, in this order
+var StatusConditions = []cassdcapi.DatacenterConditionType{
+	cassdcapi.DatacenterReady,
+	cassdcapi.DatacenterInitialized,
+	cassdcapi.DatacenterStopped,
+	cassdcapi.DatacenterResuming,
+	cassdcapi.DatacenterRollingRestart,
+	cassdcapi.DatacenterUpdating,
+	cassdcapi.DatacenterScalingUp,
+	cassdcapi.DatacenterScalingDown,
+	cassdcapi.DatacenterReplacingNodes,
+	cassdcapi.DatacenterDecommission,
+	cassdcapi.DatacenterValid,
+}
+
+// DatacenterStatus combines the state of the CassandraDatacenter, its pods and the Cassandra ring
+type DatacenterStatus struct {
+	Name           string              `json:"name"`
+	Namespace      string              `json:"namespace"`
+	Cluster        string              `json:"cluster"`
+	Size           int32               `json:"size"`
+	Stopped        bool                `json:"stopped"`
+	Progress       string              `json:"progress,omitempty"`
+	Conditions     []ConditionStatus   `json:"conditions"`
+	Pods           []PodStatus         `json:"pods"`
+	Ring           []RingNode          `json:"ring,omitempty"`
+	SchemaVersions map[string][]string `json:"schemaVersions,omitempty"`
+	Events         []EventStatus       `json:"events,omitempty"`
+	Warnings       []string            `json:"warnings,omitempty"`
+}
+
+// ConditionStatus is the status of a single CassandraDatacenter condition
+type ConditionStatus struct {
+	Type   string `json:"type"`
+	Status string `json:"status"`
+}
+
+// PodStatus is the Kubernetes state of a Cassandra pod
+type PodStatus struct {
+	Name      string `json:"name"`
+	Rack      string `json:"rack"`
+	Phase     string `json:"phase"`
+	Ready     bool   `json:"ready"`
+	NodeState string `json:"nodeState"`
+	Restarts  int32  `json:"restarts"`
+}
+
+// RingNode is the state of a Cassandra node as seen by the queried node
+type RingNode struct {
+	Address    string `json:"address"`
+	State      string `json:"state"`
+	Load       string `json:"load"`
+	Owns       string `json:"owns"`
+	HostID     string `json:"hostId"`
+	Datacenter string `json:"datacenter"`
+	Rack       string `json:"rack"`
+	Schema     string `json:"schema,omitempty"`
+}
+
+// EventStatus is a Kubernetes Event of the datacenter or its pods
+type EventStatus struct {
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Object  string    `json:"object"`
+	Reason  string    `json:"reason"`
+	Message string    `json:"message"`
+}
+
+// Datacenters returns the CassandraDatacenters of the namespace
+func (c *CassManager) Datacenters(namespace string) ([]cassdcapi.CassandraDatacenter, error) {
+	dcList := &cassdcapi.CassandraDatacenterList{}
+	if err := c.client.List(context.TODO(), dcList, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+	dcs := dcList.Items
+	sortDatacenters(dcs)
+	return dcs, nil
+}
+
+// DatacenterStatus collects the status of the datacenter. The ring and schema versions are fetched from the management
+// API of a running pod when execOptions is set. At most eventLimit of the latest Events are included. Failures to
+// reach Cassandra are reported as warnings of the status.
+func (c *CassManager) DatacenterStatus(cassdc *cassdcapi.CassandraDatacenter, execOptions *exec.ExecOptions, eventLimit int) (*DatacenterStatus, error) {
+	status := &DatacenterStatus{
+		Name:       cassdc.Name,
+		Namespace:  cassdc.Namespace,
+		Cluster:    cassdc.Spec.ClusterName,
+		Size:       cassdc.Spec.Size,
+		Stopped:    cassdc.Spec.Stopped,
+		Progress:   string(cassdc.Status.CassandraOperatorProgress),
+		Conditions: datacenterConditions(cassdc),
+		Warnings:   make([]string, 0),
+	}
+
+	pods, err := c.CassandraDatacenterPods(cassdc)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(pods.Items, func(i, j int) bool { return pods.Items[i].Name < pods.Items[j].Name })
+
+	var queryPod *corev1.Pod
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		status.Pods = append(status.Pods, podStatus(pod))
+		if queryPod == nil && podReady(pod) {
+			queryPod = pod
+		}
+	}
+
+	if execOptions != nil {
+		if queryPod == nil {
+			status.Warnings = append(status.Warnings, "no ready pods to query the ring state from")
+		} else {
+			c.fetchRing(status, execOptions, queryPod)
+		}
+	}
+
+	if eventLimit > 0 {
+		if status.Events, err = c.datacenterEvents(cassdc, eventLimit); err != nil {
+			return nil, err
+		}
+	}
+
+	return status, nil
+}
+
+func datacenterConditions(cassdc *cassdcapi.CassandraDatacenter) []ConditionStatus {
+	conditions := make([]ConditionStatus, 0, len(StatusConditions))
+	for _, conditionType := range StatusConditions {
+		value := cassdc.Status.GetConditionStatus(conditionType)
+		if value == corev1.ConditionUnknown {
+			continue
+		}
+		conditions = append(conditions, ConditionStatus{Type: string(conditionType), Status: string(value)})
+	}
+	return conditions
+}
+
+func podStatus(pod *corev1.Pod) PodStatus {
+	restarts := int32(0)
+	for _, container := range pod.Status.ContainerStatuses {
+		if container.Name == "cassandra" {
+			restarts = container.RestartCount
+		}
+	}
+	return PodStatus{
+		Name:      pod.Name,
+		Rack:      pod.Labels[cassdcapi.RackLabel],
+		Phase:     string(pod.Status.Phase),
+		Ready:     podReady(pod),
+		NodeState: pod.Labels[NodeStateLabel],
+		Restarts:  restarts,
+	}
+}
+
+// fetchRing adds the ring state, ownership and schema versions seen by the pod to the status
+func (c *CassManager) fetchRing(status *DatacenterStatus, execOptions *exec.ExecOptions, pod *corev1.Pod) {
+	out, err := execInPod(execOptions, pod, "wget", "-q", "-O", "-", managementAPIURL+"/api/v0/metadata/endpoints")
+	if err != nil {
+		status.Warnings = append(status.Warnings, fmt.Sprintf("unable to fetch the ring state from the management API: %v", err))
+		return
+	}
+
+	endpoints := httphelper.CassMetadataEndpoints{}
+	if err := json.Unmarshal(out, &endpoints); err != nil {
+		status.Warnings = append(status.Warnings, fmt.Sprintf("unable to parse the ring state from the management API: %v", err))
+		return
+	}
+
+	// The management API has no token ownership, nodetool status calculates it. The credentials are not passed on the
+	// command line where other processes could read them, the pod's local JMX does not require them.
+	ownership := make(map[string]string)
+	if out, err := execInPod(execOptions, pod, "nodetool", "status"); err != nil {
+		status.Warnings = append(status.Warnings, fmt.Sprintf("unable to fetch the ownership with nodetool status: %v", err))
+	} else {
+		ownership = parseOwnership(string(out))
+	}
+
+	status.Ring = ringNodes(endpoints.Entity, ownership)
+
+	out, err = execInPod(execOptions, pod, "wget", "-q", "-O", "-", managementAPIURL+"/api/v1/ops/node/schema/versions")
+	if err != nil {
+		status.Warnings = append(status.Warnings, fmt.Sprintf("unable to fetch the schema versions from the management API: %v", err))
+		return
+	}
+
+	schemaVersions := make(map[string][]string)
+	if err := json.Unmarshal(out, &schemaVersions); err != nil {
+		status.Warnings = append(status.Warnings, fmt.Sprintf("unable to parse the schema versions from the management API: %v", err))
+		return
+	}
+	status.SchemaVersions = schemaVersions
+}
+
+// ringNodes converts the management API endpoints to ring nodes, sorted by datacenter, rack and address
+func ringNodes(endpoints []httphelper.EndpointState, ownership map[string]string) []RingNode {
+	nodes := make([]RingNode, 0, len(endpoints))
+	for i := range endpoints {
+		endpoint := &endpoints[i]
+		owns, found := ownership[endpoint.HostID]
+		if !found {
+			owns = "?"
+		}
+		nodes = append(nodes, RingNode{
+			Address:    endpoint.EndpointIP,
+			State:      ringState(endpoint),
+			Load:       formatLoad(endpoint.Load),
+			Owns:       owns,
+			HostID:     endpoint.HostID,
+			Datacenter: endpoint.Datacenter,
+			Rack:       endpoint.Rack,
+			Schema:     endpoint.SchemaVersion,
+		})
+	}
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].Datacenter != nodes[j].Datacenter {
+			return nodes[i].Datacenter < nodes[j].Datacenter
+		}
+		if nodes[i].Rack != nodes[j].Rack {
+			return nodes[i].Rack < nodes[j].Rack
+		}
+		return nodes[i].Address < nodes[j].Address
+	})
+	return nodes
+}
+
+// ringState returns the state of the endpoint in the nodetool status format, such as UN or DJ
+func ringState(endpoint *httphelper.EndpointState) string {
+	state := "D"
+	if endpoint.IsAlive == "true" {
+		state = "U"
+	}
+
+	switch {
+	case endpoint.HasStatus(httphelper.StatusLeaving):
+		return state + "L"
+	case endpoint.HasStatus(httphelper.StatusMoving):
+		return state + "M"
+	case endpoint.HasStatus("BOOT"):
+		return state + "J"
+	case endpoint.HasStatus(httphelper.StatusNormal):
+		return state + "N"
+	}
+	return state + "?"
+}
+
+// formatLoad formats the load in bytes reported by the management API like nodetool status does
+func formatLoad(load string) string {
+	value, err := strconv.ParseFloat(load, 64)
+	if err != nil {
+		return load
+	}
+	return FormatBytes(value)
+}
+
+// FormatBytes formats the bytes with binary units
+func FormatBytes(value float64) string {
+	units := []string{"bytes", "KiB", "MiB", "GiB", "TiB"}
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.2f %s", value, units[unit])
+}
+
+// parseOwnership returns the effective or token ownership of the nodes by their host ID from nodetool status output
+func parseOwnership(output string) map[string]string {
+	nodeLine := regexp.MustCompile(`^[UD][NLJM]\s`)
+	hostID := regexp.MustCompile(`^([0-9a-fA-F]+-){4}[0-9a-fA-F]+$`)
+	ownership := make(map[string]string)
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if !nodeLine.MatchString(line) {
+			continue
+		}
+		fields := strings.Fields(line)
+		for i := 1; i < len(fields); i++ {
+			if hostID.MatchString(fields[i]) && i > 1 {
+				ownership[fields[i]] = fields[i-1]
+				break
+			}
+		}
+	}
+	return ownership
+}
+
+// datacenterEvents returns the latest Events of the datacenter and its pods, newest first
+func (c *CassManager) datacenterEvents(cassdc *cassdcapi.CassandraDatacenter, limit int) ([]EventStatus, error) {
+	eventList := &corev1.EventList{}
+	if err := c.client.List(context.TODO(), eventList, client.InNamespace(cassdc.Namespace)); err != nil {
+		return nil, err
+	}
+
+	podPrefix := fmt.Sprintf("%s-%s-", cassdcapi.CleanupForKubernetes(cassdc.Spec.ClusterName), cassdc.Name)
+	events := make([]EventStatus, 0)
+	for _, event := range eventList.Items {
+		object := event.InvolvedObject
+		if !(object.Kind == "CassandraDatacenter" && object.Name == cassdc.Name) && !(object.Kind == "Pod" && strings.HasPrefix(object.Name, podPrefix)) {
+			continue
+		}
+
+		timestamp := event.LastTimestamp.Time
+		if timestamp.IsZero() {
+			timestamp = event.EventTime.Time
+		}
+		events = append(events, EventStatus{
+			Time:    timestamp,
+			Type:    event.Type,
+			Object:  fmt.Sprintf("%s/%s", strings.ToLower(object.Kind), object.Name),
+			Reason:  event.Reason,
+			Message: event.Message,
+		})
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Time.After(events[j].Time) })
+	if len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+// execInPod runs the command in the pod's Cassandra container and returns the standard output
+func execInPod(execOptions *exec.ExecOptions, pod *corev1.Pod, command ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+
+	options := *execOptions
+	options.IOStreams = genericclioptions.IOStreams{In: &bytes.Buffer{}, Out: &stdout, ErrOut: &stderr}
+	options.Namespace = pod.Namespace
+	options.PodName = pod.Name
+	options.Stdin = false
+	options.TTY = false
+	options.Command = command
+
+	if err := options.Run(); err != nil {
+		return nil, fmt.Errorf("unable to run %s in pod %s: %v %s", command[0], pod.Name, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,73 @@
+package cassdcutil
+
+import (
+	"testing"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/apis/cassandra/v1beta1"
+	"github.com/k8ssandra/cass-operator/pkg/httphelper"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestRingState(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal("UN", ringState(&httphelper.EndpointState{IsAlive: "true", Status: "NORMAL,-9223372036854775808"}))
+	require.Equal("DN", ringState(&httphelper.EndpointState{IsAlive: "false", StatusWithPort: "NORMAL,-9223372036854775808"}))
+	require.Equal("UJ", ringState(&httphelper.EndpointState{IsAlive: "true", Status: "BOOT,123"}))
+	require.Equal("UL", ringState(&httphelper.EndpointState{IsAlive: "true", Status: "LEAVING,123"}))
+	require.Equal("UM", ringState(&httphelper.EndpointState{IsAlive: "true", Status: "MOVING,123"}))
+	require.Equal("D?", ringState(&httphelper.EndpointState{}))
+}
+
+func TestFormatLoad(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal("512.00 bytes", formatLoad("512.0"))
+	require.Equal("1.50 KiB", formatLoad("1536"))
+	require.Equal("2.00 GiB", formatLoad("2147483648"))
+	require.Equal("unknown", formatLoad("unknown"))
+}
+
+func TestParseOwnership(t *testing.T) {
+	require := require.New(t)
+	output := `Datacenter: dc1
+===============
+Status=Up/Down
+|/ State=Normal/Leaving/Joining/Moving
+--  Address     Load       Tokens       Owns (effective)  Host ID                               Rack
+UN  10.0.0.1    1.21 MiB   16           66.7%             4ab6bd2e-4e1a-4b5d-9b4e-3e0b6b5a2a01  r1
+DN  10.0.0.2    ?          16           ?                 5bc7ce3f-5f2b-4c6e-8c5f-4f1c7c6b3b02  r2
+UJ  10.0.0.3    103.5 KiB  16           33.3%             6cd8df40-6a3c-4d7f-9d60-5a2d8d7c4c03  r3
+`
+	ownership := parseOwnership(output)
+	require.Equal(map[string]string{
+		"4ab6bd2e-4e1a-4b5d-9b4e-3e0b6b5a2a01": "66.7%",
+		"5bc7ce3f-5f2b-4c6e-8c5f-4f1c7c6b3b02": "?",
+		"6cd8df40-6a3c-4d7f-9d60-5a2d8d7c4c03": "33.3%",
+	}, ownership)
+}
+
+func TestRingNodes(t *testing.T) {
+	require := require.New(t)
+	endpoints := []httphelper.EndpointState{
+		{EndpointIP: "10.0.0.2", HostID: "b", Datacenter: "dc1", Rack: "r2", IsAlive: "true", Status: "NORMAL,1", Load: "1024"},
+		{EndpointIP: "10.0.0.1", HostID: "a", Datacenter: "dc1", Rack: "r1", IsAlive: "true", Status: "NORMAL,2", Load: "2048", SchemaVersion: "s1"},
+	}
+
+	nodes := ringNodes(endpoints, map[string]string{"a": "50.0%"})
+	require.Len(nodes, 2)
+	require.Equal("10.0.0.1", nodes[0].Address)
+	require.Equal("50.0%", nodes[0].Owns)
+	require.Equal("2.00 KiB", nodes[0].Load)
+	require.Equal("s1", nodes[0].Schema)
+	require.Equal("?", nodes[1].Owns)
+}
+
+func TestDatacenterConditions(t *testing.T) {
+	require := require.New(t)
+	dc := datacenter("a", "dc1", true)
+	dc.Status.SetCondition(*cassdcapi.NewDatacenterCondition(cassdcapi.DatacenterScalingUp, corev1.ConditionFalse))
+
+	require.Equal([]ConditionStatus{{Type: "Ready", Status: "True"}, {Type: "ScalingUp", Status: "False"}}, datacenterConditions(&dc))
+}
@@ -0,0 +1,684 @@
+package cassdcutil
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/apis/cassandra/v1beta1"
+	controlapi "github.com/k8ssandra/cass-operator/apis/control/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	waitutil "k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Job commands of the CassandraTask that are not part of the vendored cass-operator API
+const (
+	CommandRestart         controlapi.CassandraCommand = "restart"
+	CommandReplaceNode     controlapi.CassandraCommand = "replacenode"
+	CommandUpgradeSSTables controlapi.CassandraCommand = "upgradesstables"
+	CommandCompaction      controlapi.CassandraCommand = "compaction"
+	CommandScrub           controlapi.CassandraCommand = "scrub"
+	CommandFlush           controlapi.CassandraCommand = "flush"
+)
+
+const (
+	// Job arguments. The task keeps them as strings, typed operators get the tables as a list and jobs as a number.
+	argKeyspace         = "keyspace_name"
+	argTables           = "tables"
+	argSourceDatacenter = "source_datacenter"
+	argPodName          = "pod_name"
+	argRack             = "rack"
+	argJobs             = "jobs"
+
+	// podJobAnnotationPrefix is the prefix of the pod annotation the operator keeps the pod's job status in, the key
+	// ends with the task UID
+	podJobAnnotationPrefix = "control.k8ssandra.io/job"
+
+	// taskTTL keeps the finished task around long enough to inspect it
+	taskTTL = int32(24 * 60 * 60)
+
+	cassandraTaskCRDName = "cassandratasks.control.k8ssandra.io"
+
+	TaskPending   = "Pending"
+	TaskRunning   = "Running"
+	TaskCompleted = "Completed"
+	TaskFailed    = "Failed"
+
+	PodRestarting = "restarting"
+	PodRestarted  = "restarted"
+)
+
+// TaskCommands are the job commands supported by the CassandraTask
+var TaskCommands = []controlapi.CassandraCommand{
+	controlapi.CommandCleanup,
+	controlapi.CommandRebuild,
+	CommandReplaceNode,
+	CommandRestart,
+	CommandUpgradeSSTables,
+	CommandCompaction,
+	CommandScrub,
+	CommandFlush,
+}
+
+// legacyTaskCommands are the only commands run by the operators whose job arguments are a map of strings
+var legacyTaskCommands = []controlapi.CassandraCommand{
+	controlapi.CommandCleanup,
+	controlapi.CommandRebuild,
+}
+
+var crdGVK = schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"}
+
+// TaskSupport describes the CassandraTask jobs the installed cass-operator can run
+type TaskSupport struct {
+	// TypedArguments is set when the operator's job arguments are typed fields instead of a map of strings. The
+	// operators with string arguments only run cleanup and rebuild.
+	TypedArguments bool
+}
+
+// Commands returns the job commands the operator runs
+func (s TaskSupport) Commands() []controlapi.CassandraCommand {
+	if s.TypedArguments {
+		return TaskCommands
+	}
+	return legacyTaskCommands
+}
+
+// Supports returns true if the operator runs the command
+func (s TaskSupport) Supports(command controlapi.CassandraCommand) bool {
+	for _, supported := range s.Commands() {
+		if supported == command {
+			return true
+		}
+	}
+	return false
+}
+
+// TaskSupport detects the jobs supported by the installed cass-operator from the CassandraTask definition
+func (c *CassManager) TaskSupport() (*TaskSupport, error) {
+	crd := &unstructured.Unstructured{}
+	crd.SetGroupVersionKind(crdGVK)
+	if err := c.client.Get(context.TODO(), types.NamespacedName{Name: cassandraTaskCRDName}, crd); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, fmt.Errorf("CassandraTask is not installed, cass-operator is too old to run tasks")
+		}
+		return nil, fmt.Errorf("unable to read the CassandraTask definition of the installed operator: %w", err)
+	}
+
+	return taskSupportFromCRD(crd)
+}
+
+// taskSupportFromCRD checks the schema of the job arguments in the CassandraTask definition
+func taskSupportFromCRD(crd *unstructured.Unstructured) (*TaskSupport, error) {
+	versions, _, err := unstructured.NestedSlice(crd.Object, "spec", "versions")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, v := range versions {
+		version, ok := v.(map[string]interface{})
+		if !ok || version["name"] != controlapi.GroupVersion.Version {
+			continue
+		}
+		args, _, err := unstructured.NestedMap(version, "schema", "openAPIV3Schema", "properties", "spec", "properties", "jobs", "items", "properties", "args")
+		if err != nil {
+			return nil, err
+		}
+		_, typed := args["properties"]
+		return &TaskSupport{TypedArguments: typed}, nil
+	}
+
+	return nil, fmt.Errorf("the installed CassandraTask definition has no version %s", controlapi.GroupVersion.Version)
+}
+
+// TaskArguments are the optional arguments of a task's job, only the ones supported by the command are accepted
+type TaskArguments struct {
+	Keyspace         string
+	Tables           []string
+	SourceDatacenter string
+	Pod              string
+	Rack             string
+	Jobs             int
+}
+
+// TaskPodEvent is a change in the state of a pod targeted by a task, either the pod's job status or its restart
+type TaskPodEvent struct {
+	Pod   string
+	State string
+}
+
+// TaskProgressFunc is called every time the state of a pod targeted by the task changes
+type TaskProgressFunc func(event TaskPodEvent)
+
+// validateTaskArguments ensures the command is known and run by the operator, its required arguments are set and no
+// arguments the operator would ignore are
+func validateTaskArguments(command controlapi.CassandraCommand, args TaskArguments, support TaskSupport) error {
+	supported := map[string]bool{}
+	switch command {
+	case controlapi.CommandCleanup:
+		supported = map[string]bool{argKeyspace: true, argTables: true, argJobs: true}
+	case controlapi.CommandRebuild:
+		if args.SourceDatacenter == "" {
+			return fmt.Errorf("%s requires the source datacenter", command)
+		}
+		supported = map[string]bool{argKeyspace: true, argSourceDatacenter: true}
+	case CommandReplaceNode:
+		if args.Pod == "" {
+			return fmt.Errorf("%s requires the pod to replace", command)
+		}
+		supported = map[string]bool{argPodName: true}
+	case CommandRestart:
+		// The restart job only targets the datacenter or a rack, single pods are restarted by deleting them
+		supported = map[string]bool{argRack: true}
+	case CommandUpgradeSSTables, CommandScrub:
+		supported = map[string]bool{argKeyspace: true, argTables: true, argJobs: true}
+	case CommandCompaction, CommandFlush:
+		supported = map[string]bool{argKeyspace: true, argTables: true}
+	default:
+		return fmt.Errorf("unknown task command %s", command)
+	}
+
+	if !support.Supports(command) {
+		return fmt.Errorf("the installed cass-operator does not run %s tasks, it only runs %s", command, joinCommands(support.Commands()))
+	}
+
+	if !support.TypedArguments {
+		// The operators with string arguments run cleanup on every keyspace and only read the rebuild's source
+		supported = map[string]bool{}
+		if command == controlapi.CommandRebuild {
+			supported[argSourceDatacenter] = true
+		}
+	}
+
+	for arg := range args.toMap() {
+		if !supported[arg] {
+			if !support.TypedArguments {
+				return fmt.Errorf("the installed cass-operator ignores the argument %s of %s", arg, command)
+			}
+			return fmt.Errorf("%s does not support the argument %s", command, arg)
+		}
+	}
+
+	if len(args.Tables) > 0 && args.Keyspace == "" {
+		return fmt.Errorf("tables require the keyspace")
+	}
+
+	return nil
+}
+
+func joinCommands(commands []controlapi.CassandraCommand) string {
+	names := make([]string, 0, len(commands))
+	for _, command := range commands {
+		names = append(names, string(command))
+	}
+	return strings.Join(names, ", ")
+}
+
+func (a TaskArguments) toMap() map[string]string {
+	args := make(map[string]string)
+	if a.Keyspace != "" {
+		args[argKeyspace] = a.Keyspace
+	}
+	if len(a.Tables) > 0 {
+		args[argTables] = strings.Join(a.Tables, ",")
+	}
+	if a.SourceDatacenter != "" {
+		args[argSourceDatacenter] = a.SourceDatacenter
+	}
+	if a.Pod != "" {
+		args[argPodName] = a.Pod
+	}
+	if a.Rack != "" {
+		args[argRack] = a.Rack
+	}
+	if a.Jobs > 0 {
+		args[argJobs] = strconv.Itoa(a.Jobs)
+	}
+	return args
+}
+
+// typedArguments converts the job arguments to the types of the operators with typed arguments
+func typedArguments(args map[string]string) (map[string]interface{}, error) {
+	typed := make(map[string]interface{}, len(args))
+	for name, value := range args {
+		switch name {
+		case argTables:
+			tables := make([]interface{}, 0)
+			for _, table := range strings.Split(value, ",") {
+				tables = append(tables, table)
+			}
+			typed[name] = tables
+		case argJobs:
+			jobs, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s argument %s: %w", name, value, err)
+			}
+			typed[name] = jobs
+		default:
+			typed[name] = value
+		}
+	}
+	return typed, nil
+}
+
+// stringArguments converts the job arguments of any operator version to strings
+func stringArguments(args map[string]interface{}) map[string]string {
+	converted := make(map[string]string, len(args))
+	for name, value := range args {
+		switch v := value.(type) {
+		case string:
+			converted[name] = v
+		case []interface{}:
+			values := make([]string, 0, len(v))
+			for _, item := range v {
+				values = append(values, fmt.Sprint(item))
+			}
+			converted[name] = strings.Join(values, ",")
+		case map[string]interface{}:
+			b, _ := json.Marshal(v)
+			converted[name] = string(b)
+		default:
+			converted[name] = fmt.Sprint(v)
+		}
+	}
+	return converted
+}
+
+// NewTask creates a CassandraTask running the command on the pods of the datacenter. The command and the arguments
+// must be supported by the installed operator.
+func NewTask(cassdc *cassdcapi.CassandraDatacenter, command controlapi.CassandraCommand, args TaskArguments, support TaskSupport) (*controlapi.CassandraTask, error) {
+	if err := validateTaskArguments(command, args, support); err != nil {
+		return nil, err
+	}
+
+	ttl := taskTTL
+	return &controlapi.CassandraTask{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("%s-%s-", cassdc.Name, command),
+			Namespace:    cassdc.Namespace,
+			Labels:       cassdc.GetDatacenterLabels(),
+		},
+		Spec: controlapi.CassandraTaskSpec{
+			Datacenter: corev1.ObjectReference{
+				Name:      cassdc.Name,
+				Namespace: cassdc.Namespace,
+			},
+			Jobs: []controlapi.CassandraJob{
+				{
+					Name:      fmt.Sprintf("%s-%s", command, cassdc.Name),
+					Command:   command,
+					Arguments: args.toMap(),
+				},
+			},
+			TTLSecondsAfterFinished: &ttl,
+		},
+	}, nil
+}
+
+// NewRestartTask creates a CassandraTask restarting the pods of the datacenter's rack. With no rack, every pod of the
+// datacenter is restarted.
+func NewRestartTask(cassdc *cassdcapi.CassandraDatacenter, rack string, support TaskSupport) (*controlapi.CassandraTask, error) {
+	return NewTask(cassdc, CommandRestart, TaskArguments{Rack: rack}, support)
+}
+
+// ValidateRestartTarget ensures the rack is defined in the datacenter and the pod belongs to it
+func (c *CassManager) ValidateRestartTarget(cassdc *cassdcapi.CassandraDatacenter, rack, pod string) error {
+	if rack != "" && pod != "" {
+		return fmt.Errorf("only one of rack or pod can be restarted")
+	}
+
+	if rack != "" {
+		for _, r := range cassdc.GetRacks() {
+			if r.Name == rack {
+				return nil
+			}
+		}
+		return fmt.Errorf("rack %s is not part of datacenter %s", rack, cassdc.Name)
+	}
+
+	if pod != "" {
+		p := &corev1.Pod{}
+		if err := c.client.Get(context.TODO(), types.NamespacedName{Name: pod, Namespace: cassdc.Namespace}, p); err != nil {
+			return err
+		}
+		if p.Labels[cassdcapi.DatacenterLabel] != cassdc.Name {
+			return fmt.Errorf("pod %s is not part of datacenter %s", pod, cassdc.Name)
+		}
+	}
+
+	return nil
+}
+
+// CreateTask creates the CassandraTask with the job arguments typed as the operator expects them, the generated name
+// is set to the task
+func (c *CassManager) CreateTask(task *controlapi.CassandraTask, support TaskSupport) error {
+	u, err := taskToUnstructured(task, support)
+	if err != nil {
+		return err
+	}
+
+	if err := c.client.Create(context.TODO(), u); err != nil {
+		return err
+	}
+
+	created, err := taskFromUnstructured(u)
+	if err != nil {
+		return err
+	}
+	*task = *created
+	return nil
+}
+
+// taskToUnstructured converts the task to the content sent to the operator
+func taskToUnstructured(task *controlapi.CassandraTask, support TaskSupport) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(task)
+	if err != nil {
+		return nil, err
+	}
+	u := &unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(controlapi.GroupVersion.WithKind("CassandraTask"))
+
+	if !support.TypedArguments {
+		return u, nil
+	}
+
+	jobs, _, err := unstructured.NestedSlice(u.Object, "spec", "jobs")
+	if err != nil {
+		return nil, err
+	}
+	for i, job := range task.Spec.Jobs {
+		if len(job.Arguments) == 0 {
+			continue
+		}
+		args, err := typedArguments(job.Arguments)
+		if err != nil {
+			return nil, err
+		}
+		jobs[i].(map[string]interface{})["args"] = args
+	}
+	if err := unstructured.SetNestedSlice(u.Object, jobs, "spec", "jobs"); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// taskFromUnstructured reads a task created for any operator version, the job arguments are converted to strings
+func taskFromUnstructured(u *unstructured.Unstructured) (*controlapi.CassandraTask, error) {
+	content := u.DeepCopy().Object
+	jobs, _, err := unstructured.NestedSlice(content, "spec", "jobs")
+	if err != nil {
+		return nil, err
+	}
+	for _, job := range jobs {
+		jobMap, ok := job.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if args, ok := jobMap["args"].(map[string]interface{}); ok {
+			converted := make(map[string]interface{}, len(args))
+			for name, value := range stringArguments(args) {
+				converted[name] = value
+			}
+			jobMap["args"] = converted
+		}
+	}
+	if len(jobs) > 0 {
+		if err := unstructured.SetNestedSlice(content, jobs, "spec", "jobs"); err != nil {
+			return nil, err
+		}
+	}
+
+	task := &controlapi.CassandraTask{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(content, task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// CancelTask deletes the CassandraTask, which stops the operator from starting the job on more pods. Jobs and restarts
+// already running in the pods are not stopped.
+func (c *CassManager) CancelTask(task *controlapi.CassandraTask) error {
+	if err := c.client.Delete(context.TODO(), task); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// Task fetches the CassandraTask by its name and namespace
+func (c *CassManager) Task(name, namespace string) (*controlapi.CassandraTask, error) {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(controlapi.GroupVersion.WithKind("CassandraTask"))
+	if err := c.client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: namespace}, u); err != nil {
+		return nil, err
+	}
+	return taskFromUnstructured(u)
+}
+
+// Tasks returns the CassandraTasks of the namespace, oldest first. If the datacenter is set, only its tasks are returned.
+func (c *CassManager) Tasks(namespace, datacenter string) ([]controlapi.CassandraTask, error) {
+	taskList := &unstructured.UnstructuredList{}
+	taskList.SetGroupVersionKind(controlapi.GroupVersion.WithKind("CassandraTaskList"))
+	if err := c.client.List(context.TODO(), taskList, client.InNamespace(namespace)); err != nil {
+		return nil, err
+	}
+
+	tasks := make([]controlapi.CassandraTask, 0, len(taskList.Items))
+	for i := range taskList.Items {
+		task, err := taskFromUnstructured(&taskList.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		if datacenter == "" || task.Spec.Datacenter.Name == datacenter {
+			tasks = append(tasks, *task)
+		}
+	}
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].CreationTimestamp.Before(&tasks[j].CreationTimestamp)
+	})
+	return tasks, nil
+}
+
+// TaskState returns the state of the task from its status
+func TaskState(task *controlapi.CassandraTask) string {
+	finished, err := taskFinished(task)
+	switch {
+	case err != nil:
+		return TaskFailed
+	case finished:
+		return TaskCompleted
+	case task.Status.StartTime != nil || task.Status.Active > 0:
+		return TaskRunning
+	}
+	return TaskPending
+}
+
+// TaskPods returns the pods targeted by the task
+func (c *CassManager) TaskPods(task *controlapi.CassandraTask) ([]corev1.Pod, error) {
+	namespace := task.Spec.Datacenter.Namespace
+	if namespace == "" {
+		namespace = task.Namespace
+	}
+
+	labels := map[string]string{cassdcapi.DatacenterLabel: task.Spec.Datacenter.Name}
+	for _, job := range task.Spec.Jobs {
+		if rack, found := job.Arguments[argRack]; found {
+			labels[cassdcapi.RackLabel] = rack
+		}
+	}
+
+	podList := &corev1.PodList{}
+	if err := c.client.List(context.TODO(), podList, client.InNamespace(namespace), client.MatchingLabels(labels)); err != nil {
+		return nil, err
+	}
+
+	pods := podList.Items
+	for _, job := range task.Spec.Jobs {
+		if pod, found := job.Arguments[argPodName]; found {
+			pods = filterPod(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+func filterPod(pods []corev1.Pod, name string) []corev1.Pod {
+	for _, pod := range pods {
+		if pod.Name == name {
+			return []corev1.Pod{pod}
+		}
+	}
+	return []corev1.Pod{}
+}
+
+// PodJobStatus returns the status of the task's job in the pod, empty if the pod has not started the job or the
+// operator has already removed the status after the task finished
+func PodJobStatus(task *controlapi.CassandraTask, pod *corev1.Pod) string {
+	data, found := pod.Annotations[fmt.Sprintf("%s-%s", podJobAnnotationPrefix, task.UID)]
+	if !found {
+		return ""
+	}
+
+	jobStatus := struct {
+		Status string `json:"status,omitempty"`
+	}{}
+	if err := json.Unmarshal([]byte(data), &jobStatus); err != nil {
+		return ""
+	}
+	return strings.ToLower(jobStatus.Status)
+}
+
+// WaitForTask waits until the task has completed or failed, reporting the job status of the targeted pods and the
+// pods as they restart. Cancelling the context stops waiting without modifying the task.
+func (c *CassManager) WaitForTask(ctx context.Context, task *controlapi.CassandraTask, progress TaskProgressFunc) error {
+	tracker := newTaskPodTracker()
+	return waitutil.PollImmediateUntil(5*time.Second, func() (bool, error) {
+		current, err := c.Task(task.Name, task.Namespace)
+		if err != nil {
+			return false, err
+		}
+
+		pods, err := c.TaskPods(current)
+		if err != nil {
+			return false, err
+		}
+
+		for _, event := range tracker.update(current, pods) {
+			if progress != nil {
+				progress(event)
+			}
+		}
+
+		return taskFinished(current)
+	}, ctx.Done())
+}
+
+// taskFinished returns true when the task has completed and an error if it has failed
+func taskFinished(task *controlapi.CassandraTask) (bool, error) {
+	for _, condition := range task.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case controlapi.JobFailed:
+			return false, fmt.Errorf("task %s failed: %s", task.Name, condition.Message)
+		case controlapi.JobComplete:
+			return true, nil
+		}
+	}
+
+	if task.Status.Failed > 0 {
+		return false, fmt.Errorf("task %s failed on %d pods", task.Name, task.Status.Failed)
+	}
+
+	return task.Status.CompletionTime != nil, nil
+}
+
+type podState struct {
+	uid        types.UID
+	ready      bool
+	restarting bool
+	jobStatus  string
+}
+
+// taskPodTracker detects the changes in the pods' job status and the pods going down and coming back up between the
+// snapshots of the pods
+type taskPodTracker struct {
+	pods map[string]*podState
+}
+
+func newTaskPodTracker() *taskPodTracker {
+	return &taskPodTracker{
+		pods: make(map[string]*podState),
+	}
+}
+
+func (t *taskPodTracker) update(task *controlapi.CassandraTask, pods []corev1.Pod) []TaskPodEvent {
+	events := make([]TaskPodEvent, 0)
+	seen := make(map[string]bool, len(pods))
+
+	for _, pod := range pods {
+		seen[pod.Name] = true
+		ready := podReady(&pod)
+		jobStatus := PodJobStatus(task, &pod)
+
+		state, found := t.pods[pod.Name]
+		if !found {
+			state = &podState{uid: pod.UID, ready: ready}
+			t.pods[pod.Name] = state
+		}
+
+		if !state.restarting && (state.uid != pod.UID || (state.ready && !ready)) {
+			state.restarting = true
+			events = append(events, TaskPodEvent{Pod: pod.Name, State: PodRestarting})
+		}
+
+		if state.restarting && ready && (state.uid != pod.UID || !state.ready) {
+			state.restarting = false
+			events = append(events, TaskPodEvent{Pod: pod.Name, State: PodRestarted})
+		}
+
+		// The operator removes the job status once the task has finished
+		if jobStatus != "" && jobStatus != state.jobStatus {
+			events = append(events, TaskPodEvent{Pod: pod.Name, State: jobStatus})
+			state.jobStatus = jobStatus
+		}
+
+		state.uid = pod.UID
+		state.ready = ready
+	}
+
+	// Deleted pods are restarting until they are recreated
+	missing := make([]string, 0)
+	for name, state := range t.pods {
+		if !seen[name] && !state.restarting {
+			state.restarting = true
+			state.ready = false
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	for _, name := range missing {
+		events = append(events, TaskPodEvent{Pod: name, State: PodRestarting})
+	}
+
+	return events
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,254 @@
+package cassdcutil
+
+import (
+	"testing"
+
+	controlapi "github.com/k8ssandra/cass-operator/apis/control/v1alpha1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func pod(name string, uid types.UID, ready bool) corev1.Pod {
+	status := corev1.ConditionFalse
+	if ready {
+		status = corev1.ConditionTrue
+	}
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, UID: uid},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: status}},
+		},
+	}
+}
+
+func TestNewTask(t *testing.T) {
+	require := require.New(t)
+	dc := datacenter("cass", "dc1", true)
+	dc.Spec.ClusterName = "cluster1"
+
+	typed := TaskSupport{TypedArguments: true}
+	task, err := NewRestartTask(&dc, "r1", typed)
+	require.NoError(err)
+	require.Equal("dc1-restart-", task.GenerateName)
+	require.Equal("cass", task.Namespace)
+	require.Equal("dc1", task.Spec.Datacenter.Name)
+	require.Equal("cass", task.Spec.Datacenter.Namespace)
+	require.Len(task.Spec.Jobs, 1)
+	require.Equal(CommandRestart, task.Spec.Jobs[0].Command)
+	require.Equal(map[string]string{"rack": "r1"}, task.Spec.Jobs[0].Arguments)
+
+	task, err = NewRestartTask(&dc, "", typed)
+	require.NoError(err)
+	require.Empty(task.Spec.Jobs[0].Arguments)
+
+	_, err = NewTask(&dc, CommandRestart, TaskArguments{Pod: "cluster1-dc1-r1-sts-0"}, typed)
+	require.Error(err)
+
+	_, err = NewRestartTask(&dc, "r1", TaskSupport{})
+	require.Error(err)
+
+	task, err = NewTask(&dc, controlapi.CommandCleanup, TaskArguments{Keyspace: "ks1", Tables: []string{"t1", "t2"}, Jobs: 2}, typed)
+	require.NoError(err)
+	require.Equal("dc1-cleanup-", task.GenerateName)
+	require.Equal(map[string]string{"keyspace_name": "ks1", "tables": "t1,t2", "jobs": "2"}, task.Spec.Jobs[0].Arguments)
+
+	task, err = NewTask(&dc, controlapi.CommandRebuild, TaskArguments{SourceDatacenter: "dc2"}, TaskSupport{})
+	require.NoError(err)
+	require.Equal(map[string]string{"source_datacenter": "dc2"}, task.Spec.Jobs[0].Arguments)
+}
+
+func TestValidateTaskArguments(t *testing.T) {
+	require := require.New(t)
+
+	typed := TaskSupport{TypedArguments: true}
+	for _, command := range TaskCommands {
+		args := TaskArguments{}
+		switch command {
+		case controlapi.CommandRebuild:
+			args.SourceDatacenter = "dc2"
+		case CommandReplaceNode:
+			args.Pod = "sts-0"
+		}
+		require.NoError(validateTaskArguments(command, args, typed), string(command))
+	}
+
+	require.Error(validateTaskArguments("repair", TaskArguments{}, typed))
+	require.Error(validateTaskArguments(controlapi.CommandRebuild, TaskArguments{}, typed))
+	require.Error(validateTaskArguments(CommandReplaceNode, TaskArguments{}, typed))
+	require.Error(validateTaskArguments(CommandFlush, TaskArguments{Rack: "r1"}, typed))
+	require.Error(validateTaskArguments(CommandCompaction, TaskArguments{Tables: []string{"t1"}}, typed))
+	require.Error(validateTaskArguments(CommandCompaction, TaskArguments{Keyspace: "ks1", Jobs: 2}, typed))
+
+	// The operators with string arguments ignore everything but the rebuild's source datacenter
+	legacy := TaskSupport{}
+	require.NoError(validateTaskArguments(controlapi.CommandCleanup, TaskArguments{}, legacy))
+	require.NoError(validateTaskArguments(controlapi.CommandRebuild, TaskArguments{SourceDatacenter: "dc2"}, legacy))
+	require.Error(validateTaskArguments(controlapi.CommandCleanup, TaskArguments{Keyspace: "ks1"}, legacy))
+	require.Error(validateTaskArguments(controlapi.CommandRebuild, TaskArguments{SourceDatacenter: "dc2", Keyspace: "ks1"}, legacy))
+	require.Error(validateTaskArguments(CommandFlush, TaskArguments{}, legacy))
+}
+
+func TestTypedTaskArguments(t *testing.T) {
+	require := require.New(t)
+	dc := datacenter("cass", "dc1", true)
+	typed := TaskSupport{TypedArguments: true}
+
+	task, err := NewTask(&dc, controlapi.CommandCleanup, TaskArguments{Keyspace: "ks1", Tables: []string{"t1", "t2"}, Jobs: 2}, typed)
+	require.NoError(err)
+
+	u, err := taskToUnstructured(task, typed)
+	require.NoError(err)
+	require.Equal("CassandraTask", u.GetKind())
+	jobs, _, err := unstructured.NestedSlice(u.Object, "spec", "jobs")
+	require.NoError(err)
+	require.Equal(map[string]interface{}{
+		"keyspace_name": "ks1",
+		"tables":        []interface{}{"t1", "t2"},
+		"jobs":          int64(2),
+	}, jobs[0].(map[string]interface{})["args"])
+
+	read, err := taskFromUnstructured(u)
+	require.NoError(err)
+	require.Equal(task.Spec.Jobs[0].Arguments, read.Spec.Jobs[0].Arguments)
+
+	u, err = taskToUnstructured(task, TaskSupport{})
+	require.NoError(err)
+	jobs, _, err = unstructured.NestedSlice(u.Object, "spec", "jobs")
+	require.NoError(err)
+	require.Equal("t1,t2", jobs[0].(map[string]interface{})["args"].(map[string]interface{})["tables"])
+}
+
+func TestTaskSupportFromCRD(t *testing.T) {
+	require := require.New(t)
+
+	crd := func(args map[string]interface{}) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"versions": []interface{}{
+					map[string]interface{}{
+						"name": "v1alpha1",
+						"schema": map[string]interface{}{
+							"openAPIV3Schema": map[string]interface{}{
+								"properties": map[string]interface{}{
+									"spec": map[string]interface{}{
+										"properties": map[string]interface{}{
+											"jobs": map[string]interface{}{
+												"items": map[string]interface{}{
+													"properties": map[string]interface{}{
+														"args": args,
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}}
+	}
+
+	support, err := taskSupportFromCRD(crd(map[string]interface{}{
+		"type":                 "object",
+		"additionalProperties": map[string]interface{}{"type": "string"},
+	}))
+	require.NoError(err)
+	require.False(support.TypedArguments)
+	require.True(support.Supports(controlapi.CommandCleanup))
+	require.False(support.Supports(CommandRestart))
+
+	support, err = taskSupportFromCRD(crd(map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"rack":   map[string]interface{}{"type": "string"},
+			"tables": map[string]interface{}{"type": "array"},
+		},
+	}))
+	require.NoError(err)
+	require.True(support.TypedArguments)
+	require.True(support.Supports(CommandRestart))
+
+	_, err = taskSupportFromCRD(&unstructured.Unstructured{Object: map[string]interface{}{}})
+	require.Error(err)
+}
+
+func TestTaskState(t *testing.T) {
+	require := require.New(t)
+	task := &controlapi.CassandraTask{}
+	require.Equal(TaskPending, TaskState(task))
+
+	task.Status.Active = 1
+	require.Equal(TaskRunning, TaskState(task))
+
+	task.Status.Conditions = []controlapi.JobCondition{{Type: controlapi.JobComplete, Status: corev1.ConditionTrue}}
+	require.Equal(TaskCompleted, TaskState(task))
+
+	task.Status.Conditions = []controlapi.JobCondition{{Type: controlapi.JobFailed, Status: corev1.ConditionTrue}}
+	require.Equal(TaskFailed, TaskState(task))
+}
+
+func TestPodJobStatus(t *testing.T) {
+	require := require.New(t)
+	task := &controlapi.CassandraTask{ObjectMeta: metav1.ObjectMeta{UID: "1234"}}
+	p := pod("sts-0", "a", true)
+	require.Equal("", PodJobStatus(task, &p))
+
+	p.Annotations = map[string]string{"control.k8ssandra.io/job-1234": `{"id":"abc","status":"COMPLETED","handler":"management-api"}`}
+	require.Equal("completed", PodJobStatus(task, &p))
+}
+
+func TestTaskFinished(t *testing.T) {
+	require := require.New(t)
+	task := &controlapi.CassandraTask{}
+
+	finished, err := taskFinished(task)
+	require.NoError(err)
+	require.False(finished)
+
+	task.Status.Conditions = []controlapi.JobCondition{{Type: controlapi.JobRunning, Status: corev1.ConditionTrue}}
+	finished, err = taskFinished(task)
+	require.NoError(err)
+	require.False(finished)
+
+	task.Status.Conditions = append(task.Status.Conditions, controlapi.JobCondition{Type: controlapi.JobComplete, Status: corev1.ConditionTrue})
+	finished, err = taskFinished(task)
+	require.NoError(err)
+	require.True(finished)
+
+	task.Status.Conditions = []controlapi.JobCondition{{Type: controlapi.JobFailed, Status: corev1.ConditionTrue, Message: "drain failed"}}
+	_, err = taskFinished(task)
+	require.Error(err)
+	require.Contains(err.Error(), "drain failed")
+}
+
+func TestTaskPodTracker(t *testing.T) {
+	require := require.New(t)
+	tracker := newTaskPodTracker()
+	task := &controlapi.CassandraTask{ObjectMeta: metav1.ObjectMeta{UID: "1234"}}
+
+	require.Empty(tracker.update(task, []corev1.Pod{pod("sts-0", "a", true), pod("sts-1", "b", true)}))
+
+	// sts-0 is deleted
+	require.Equal([]TaskPodEvent{{Pod: "sts-0", State: PodRestarting}}, tracker.update(task, []corev1.Pod{pod("sts-1", "b", true)}))
+
+	// sts-0 is recreated, not yet ready
+	require.Empty(tracker.update(task, []corev1.Pod{pod("sts-0", "c", false), pod("sts-1", "b", true)}))
+
+	// sts-0 is ready, sts-1 goes down
+	events := tracker.update(task, []corev1.Pod{pod("sts-0", "c", true), pod("sts-1", "b", false)})
+	require.Equal([]TaskPodEvent{{Pod: "sts-0", State: PodRestarted}, {Pod: "sts-1", State: PodRestarting}}, events)
+
+	// sts-1 is replaced between the snapshots
+	require.Equal([]TaskPodEvent{{Pod: "sts-1", State: PodRestarted}}, tracker.update(task, []corev1.Pod{pod("sts-0", "c", true), pod("sts-1", "d", true)}))
+
+	// sts-0 reports its job status once
+	done := pod("sts-0", "c", true)
+	done.Annotations = map[string]string{"control.k8ssandra.io/job-1234": `{"status":"COMPLETED"}`}
+	require.Equal([]TaskPodEvent{{Pod: "sts-0", State: "completed"}}, tracker.update(task, []corev1.Pod{done, pod("sts-1", "d", true)}))
+	require.Empty(tracker.update(task, []corev1.Pod{done, pod("sts-1", "d", true)}))
+}
@@ -0,0 +1,686 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/apis/cassandra/v1beta1"
+	"github.com/pterm/pterm"
+	"gopkg.in/yaml.v3"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	waitutil "k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/kubectl/pkg/cmd/exec"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// adoptDatacenterAnnotation and adoptHostIDsAnnotation are stored to the StatefulSet, an interrupted adoption
+	// continues from them once the pods are gone
+	adoptDatacenterAnnotation = "migrate.k8ssandra.io/datacenter"
+	adoptHostIDsAnnotation    = "migrate.k8ssandra.io/host-ids"
+
+	adoptTimeout = 10 * time.Minute
+
+	// cassandraDataPath is where cass-operator mounts the server-data volume
+	cassandraDataPath = "/var/lib/cassandra"
+)
+
+// StatefulSetAdopter moves the pods of a Cassandra StatefulSet installed by another Helm chart, such as Bitnami's, to
+// the cass-operator layout. The existing PersistentVolumes are bound to claims named the way cass-operator names them,
+// no data is copied. The pods are replaced one at a time, starting from the highest ordinal.
+type StatefulSetAdopter struct {
+	client.Client
+	Namespace   string
+	StatefulSet string
+
+	// ContainerName is the Cassandra container of the StatefulSet's pods
+	ContainerName string
+
+	execOptions *exec.ExecOptions
+
+	sts           *appsv1.StatefulSet
+	claimTemplate string
+	dataMountPath string
+	cluster       *ClusterConfigMap
+}
+
+func NewStatefulSetAdopter(cli client.Client, execOptions *exec.ExecOptions, namespace, statefulSet string) *StatefulSetAdopter {
+	return &StatefulSetAdopter{
+		Client:        cli,
+		Namespace:     namespace,
+		StatefulSet:   statefulSet,
+		ContainerName: CassandraContainerName,
+		execOptions:   execOptions,
+	}
+}
+
+// Adopt initializes the import from a running pod of the StatefulSet and replaces its pods with cass-operator's
+func (s *StatefulSetAdopter) Adopt(p *pterm.SpinnerPrinter) error {
+	p.UpdateText("Reading StatefulSet " + s.StatefulSet)
+	if err := s.readStatefulSet(); err != nil {
+		pterm.Error.Println("StatefulSet can not be adopted")
+		return err
+	}
+	pterm.Success.Printf("StatefulSet %s stores its data in volume %s mounted at %s\n", s.StatefulSet, s.claimTemplate, s.dataMountPath)
+
+	if s.replicas() > 0 {
+		if err := s.initCluster(p); err != nil {
+			return err
+		}
+	}
+
+	datacenter := s.sts.Annotations[adoptDatacenterAnnotation]
+	if datacenter == "" {
+		return fmt.Errorf("StatefulSet %s has no running pods and was not initialized for the import", s.StatefulSet)
+	}
+
+	cluster, err := fetchClusterConfigMap(s.Client, s.Namespace, datacenter)
+	if err != nil {
+		return err
+	}
+	s.cluster = cluster
+
+	hostIDs, err := s.recordedHostIDs()
+	if err != nil {
+		return err
+	}
+
+	ordinals := make([]int, 0, len(hostIDs))
+	for ordinal := range hostIDs {
+		ordinals = append(ordinals, ordinal)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(ordinals)))
+
+	for _, ordinal := range ordinals {
+		if err := s.adoptPod(p, ordinal, hostIDs[ordinal]); err != nil {
+			pterm.Error.Printf("Failed to adopt pod %s\n", s.podName(ordinal))
+			return err
+		}
+	}
+
+	pterm.Info.Printf("All pods of StatefulSet %s were adopted. Use 'import commit %s --namespace %s' to create the CassandraDatacenter, then remove the StatefulSet and its Helm release. The PersistentVolumes are retained\n", s.StatefulSet, datacenter, s.Namespace)
+
+	return nil
+}
+
+// readStatefulSet finds the data volume of the StatefulSet and where the Cassandra container mounts it
+func (s *StatefulSetAdopter) readStatefulSet() error {
+	sts := &appsv1.StatefulSet{}
+	if err := s.Client.Get(context.TODO(), types.NamespacedName{Name: s.StatefulSet, Namespace: s.Namespace}, sts); err != nil {
+		return err
+	}
+
+	if len(sts.Spec.VolumeClaimTemplates) != 1 {
+		return fmt.Errorf("StatefulSet %s has %d volume claim templates, only a single data volume can be adopted", sts.Name, len(sts.Spec.VolumeClaimTemplates))
+	}
+
+	if policy := sts.Spec.PersistentVolumeClaimRetentionPolicy; policy != nil && policy.WhenScaled == appsv1.DeletePersistentVolumeClaimRetentionPolicyType {
+		return fmt.Errorf("StatefulSet %s deletes the claims of removed pods, set persistentVolumeClaimRetentionPolicy.whenScaled to Retain", sts.Name)
+	}
+
+	mountPath, err := dataMountPath(sts.Spec.Template.Spec.Containers, s.ContainerName, sts.Spec.VolumeClaimTemplates[0].Name)
+	if err != nil {
+		return err
+	}
+
+	s.sts = sts
+	s.claimTemplate = sts.Spec.VolumeClaimTemplates[0].Name
+	s.dataMountPath = mountPath
+
+	return nil
+}
+
+// dataMountPath returns the mount path of the volume in the container
+func dataMountPath(containers []corev1.Container, containerName, volume string) (string, error) {
+	for _, container := range containers {
+		if container.Name != containerName {
+			continue
+		}
+		for _, mount := range container.VolumeMounts {
+			if mount.Name != volume {
+				continue
+			}
+			if mount.SubPath != "" || mount.SubPathExpr != "" {
+				return "", fmt.Errorf("volume %s is mounted with a subPath, which cass-operator does not use", volume)
+			}
+			return mount.MountPath, nil
+		}
+		return "", fmt.Errorf("container %s does not mount volume %s", containerName, volume)
+	}
+	return "", fmt.Errorf("pod template has no container %s, set the Cassandra container with --container", containerName)
+}
+
+func (s *StatefulSetAdopter) replicas() int {
+	if s.sts.Spec.Replicas == nil {
+		return 1
+	}
+	return int(*s.sts.Spec.Replicas)
+}
+
+func (s *StatefulSetAdopter) podName(ordinal int) string {
+	return fmt.Sprintf("%s-%d", s.sts.Name, ordinal)
+}
+
+func (s *StatefulSetAdopter) claimName(ordinal int) string {
+	return fmt.Sprintf("%s-%s-%d", s.claimTemplate, s.sts.Name, ordinal)
+}
+
+// initCluster runs the cluster initialization against the pod with ordinal 0, which is replaced last. The stored
+// configuration is moved to the cass-operator data path and the host IDs of the running pods are recorded to the
+// StatefulSet.
+func (s *StatefulSetAdopter) initCluster(p *pterm.SpinnerPrinter) error {
+	pod := &corev1.Pod{}
+	if err := s.Client.Get(context.TODO(), types.NamespacedName{Name: s.podName(0), Namespace: s.Namespace}, pod); err != nil {
+		return err
+	}
+
+	installation, err := NewPodInstallation(s.execOptions, pod, s.ContainerName)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = installation.Cleanup()
+	}()
+
+	migrator, err := NewClusterMigrator(s.Client, s.Namespace, "")
+	if err != nil {
+		return err
+	}
+	migrator.Container = installation
+
+	if err := migrator.InitCluster(p); err != nil {
+		return err
+	}
+
+	p.UpdateText("Moving data directories to the cass-operator layout")
+	if err := s.relocateStoredDirectories(migrator.Datacenter); err != nil {
+		pterm.Error.Println("Stored configuration can not use the adopted data volume")
+		return err
+	}
+
+	cluster, err := fetchClusterConfigMap(s.Client, s.Namespace, migrator.Datacenter)
+	if err != nil {
+		return err
+	}
+
+	p.UpdateText("Matching the StatefulSet's pods to Cassandra nodes")
+	hostIDs, err := s.recordedHostIDs()
+	if err != nil {
+		return err
+	}
+
+	for ordinal := 0; ordinal < s.replicas(); ordinal++ {
+		if _, found := hostIDs[ordinal]; found {
+			continue
+		}
+		pod := &corev1.Pod{}
+		if err := s.Client.Get(context.TODO(), types.NamespacedName{Name: s.podName(ordinal), Namespace: s.Namespace}, pod); err != nil {
+			return err
+		}
+		hostID := hostIDForAddress(cluster.NodeInfos, pod.Status.PodIP)
+		if hostID == "" {
+			return fmt.Errorf("pod %s with address %s is not a node of the cluster", pod.Name, pod.Status.PodIP)
+		}
+		hostIDs[ordinal] = hostID
+	}
+
+	return s.recordAdoption(migrator.Datacenter, hostIDs)
+}
+
+func hostIDForAddress(nodeInfos []NodetoolNodeInfo, address string) string {
+	for _, nodeInfo := range nodeInfos {
+		if address != "" && nodeInfo.Address == address {
+			return nodeInfo.HostId
+		}
+	}
+	return ""
+}
+
+// recordedHostIDs returns the host IDs per pod ordinal recorded to the StatefulSet
+func (s *StatefulSetAdopter) recordedHostIDs() (map[int]string, error) {
+	hostIDs := make(map[int]string)
+	value, found := s.sts.Annotations[adoptHostIDsAnnotation]
+	if !found {
+		return hostIDs, nil
+	}
+
+	recorded := make(map[string]string)
+	if err := json.Unmarshal([]byte(value), &recorded); err != nil {
+		return nil, fmt.Errorf("unable to parse annotation %s of StatefulSet %s: %w", adoptHostIDsAnnotation, s.sts.Name, err)
+	}
+
+	for key, hostID := range recorded {
+		ordinal, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ordinal %s in annotation %s of StatefulSet %s", key, adoptHostIDsAnnotation, s.sts.Name)
+		}
+		hostIDs[ordinal] = hostID
+	}
+
+	return hostIDs, nil
+}
+
+func (s *StatefulSetAdopter) recordAdoption(datacenter string, hostIDs map[int]string) error {
+	recorded := make(map[string]string, len(hostIDs))
+	for ordinal, hostID := range hostIDs {
+		recorded[strconv.Itoa(ordinal)] = hostID
+	}
+
+	value, err := json.Marshal(recorded)
+	if err != nil {
+		return err
+	}
+
+	if s.sts.Annotations == nil {
+		s.sts.Annotations = make(map[string]string)
+	}
+	s.sts.Annotations[adoptDatacenterAnnotation] = datacenter
+	s.sts.Annotations[adoptHostIDsAnnotation] = string(value)
+
+	return s.Client.Update(context.TODO(), s.sts)
+}
+
+// relocateStoredDirectories rewrites the directories of the stored cassandra.yaml to the cass-operator data path
+func (s *StatefulSetAdopter) relocateStoredDirectories(datacenter string) error {
+	configMap, files, err := fetchConfigFiles(s.Client, s.Namespace, datacenter)
+	if err != nil {
+		return err
+	}
+
+	content, found := files[cassYamlKey]
+	if !found {
+		return nil
+	}
+
+	cassYaml := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(content), &cassYaml); err != nil {
+		return err
+	}
+
+	if err := relocateDirectories(cassYaml, s.dataMountPath, cassandraDataPath); err != nil {
+		return err
+	}
+
+	out, err := yaml.Marshal(cassYaml)
+	if err != nil {
+		return err
+	}
+	files[cassYamlKey] = string(out)
+
+	if err := setConfigFiles(configMap, files); err != nil {
+		return err
+	}
+
+	return s.Client.Update(context.TODO(), configMap)
+}
+
+// relocateDirectories moves the directories of cassandra.yaml from the data volume's mount path in the StatefulSet to
+// the same place under the cass-operator mount path. Directories outside of the data volume would be lost with the
+// old pod, they're not accepted. Already moved directories are kept.
+func relocateDirectories(cassYaml map[string]interface{}, from, to string) error {
+	from = filepath.Clean(from)
+	to = filepath.Clean(to)
+
+	relocate := func(key, dir string) (string, error) {
+		dir = filepath.Clean(dir)
+		for _, root := range []string{to, from} {
+			if dir == root || strings.HasPrefix(dir, root+string(filepath.Separator)) {
+				return filepath.Join(to, strings.TrimPrefix(dir, root)), nil
+			}
+		}
+		return "", fmt.Errorf("%s %s is not on the data volume mounted at %s", key, dir, from)
+	}
+
+	if _, found := cassYaml["data_file_directories"]; !found {
+		return fmt.Errorf("cassandra.yaml does not set data_file_directories, the data location can not be verified")
+	}
+
+	for key, val := range cassYaml {
+		if strings.HasSuffix(key, "_directory") {
+			dir, ok := val.(string)
+			if !ok || dir == "" {
+				continue
+			}
+			relocated, err := relocate(key, dir)
+			if err != nil {
+				return err
+			}
+			cassYaml[key] = relocated
+		} else if strings.HasSuffix(key, "_directories") {
+			dirs, ok := val.([]interface{})
+			if !ok {
+				continue
+			}
+			for i, dir := range dirs {
+				relocated, err := relocate(key, fmt.Sprintf("%v", dir))
+				if err != nil {
+					return err
+				}
+				dirs[i] = relocated
+			}
+		}
+	}
+
+	return nil
+}
+
+// nodeMigrator returns a NodeMigrator that creates the cass-operator pod of the node, using the pod template of the
+// StatefulSet for the scheduling
+func (s *StatefulSetAdopter) nodeMigrator(nodeInfo NodetoolNodeInfo) (*NodeMigrator, error) {
+	n := NewNodeMigrator(s.Client, s.Namespace)
+	n.Cluster = s.cluster.Cluster
+	n.Datacenter = s.cluster.Datacenter
+	n.Rack = nodeInfo.Rack
+	n.Ordinal = nodeInfo.Ordinal
+	n.HostID = nodeInfo.HostId
+	n.ServerType = s.cluster.ServerType
+	n.ServerVersion = s.cluster.ServerVersion
+	n.DseWorkloads = s.cluster.DseWorkloads
+	n.PodNetwork = true
+
+	template := s.sts.Spec.Template.Spec
+	n.FSGroupId = 999
+	if template.SecurityContext != nil && template.SecurityContext.FSGroup != nil {
+		// The files on the volume are owned by the StatefulSet's group
+		n.FSGroupId = int(*template.SecurityContext.FSGroup)
+	}
+	n.tolerations = template.Tolerations
+	for _, container := range template.Containers {
+		if container.Name == s.ContainerName {
+			n.resources = container.Resources
+		}
+	}
+
+	secrets, err := existingImportSecrets(s.Client, s.Namespace, n.Datacenter)
+	if err != nil {
+		return nil, err
+	}
+	n.secrets = secrets
+
+	volume, mount, err := existingLogbackConfig(s.Client, s.Namespace, n.Datacenter)
+	if err != nil {
+		return nil, err
+	}
+	n.logbackVolume = volume
+	n.logbackMount = mount
+
+	return n, nil
+}
+
+// adoptPod replaces the pod of the ordinal. Every step checks if it was already done, an interrupted adoption can be
+// run again.
+func (s *StatefulSetAdopter) adoptPod(p *pterm.SpinnerPrinter, ordinal int, hostID string) error {
+	var nodeInfo *NodetoolNodeInfo
+	for i := range s.cluster.NodeInfos {
+		if s.cluster.NodeInfos[i].HostId == hostID {
+			nodeInfo = &s.cluster.NodeInfos[i]
+		}
+	}
+	if nodeInfo == nil {
+		return fmt.Errorf("host %s of pod %s was not part of the init process", hostID, s.podName(ordinal))
+	}
+
+	n, err := s.nodeMigrator(*nodeInfo)
+	if err != nil {
+		return err
+	}
+
+	pod := &corev1.Pod{}
+	err = s.Client.Get(context.TODO(), types.NamespacedName{Name: n.getPodName(), Namespace: s.Namespace}, pod)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	podExists := err == nil
+	if podExists && pod.Labels[cassdcapi.CassNodeState] == "Started" {
+		pterm.Info.Printf("Pod %s was already adopted as %s\n", s.podName(ordinal), pod.Name)
+		return nil
+	}
+
+	claim := &corev1.PersistentVolumeClaim{}
+	err = s.Client.Get(context.TODO(), types.NamespacedName{Name: s.claimName(ordinal), Namespace: s.Namespace}, claim)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	if err == nil {
+		p.UpdateText(fmt.Sprintf("Retaining the volume of claim %s", claim.Name))
+		if err := s.retainVolume(claim); err != nil {
+			return err
+		}
+
+		if ordinal < s.replicas() {
+			p.UpdateText(fmt.Sprintf("Draining and removing pod %s", s.podName(ordinal)))
+			if err := s.removePod(ordinal); err != nil {
+				return err
+			}
+			pterm.Success.Printf("Drained and removed pod %s\n", s.podName(ordinal))
+		}
+
+		p.UpdateText(fmt.Sprintf("Binding the volume of claim %s to claim %s", claim.Name, n.getPVCName(PvcName)))
+		if err := s.rebindVolume(claim, n.getPVCName(PvcName)); err != nil {
+			return err
+		}
+		pterm.Success.Printf("Bound the volume of claim %s to claim %s\n", claim.Name, n.getPVCName(PvcName))
+	} else {
+		// An earlier run might have deleted the original claim and failed before the volume was bound to the new claim
+		p.UpdateText(fmt.Sprintf("Verifying the volume of claim %s", n.getPVCName(PvcName)))
+		if err := s.resumeRebind(s.claimName(ordinal), n.getPVCName(PvcName)); err != nil {
+			return err
+		}
+	}
+
+	if !podExists {
+		p.UpdateText(fmt.Sprintf("Creating pod %s", n.getPodName()))
+		if err := n.CreatePod(); err != nil {
+			return err
+		}
+	}
+
+	p.UpdateText(fmt.Sprintf("Starting Cassandra in pod %s", n.getPodName()))
+	if err := n.StartPod(); err != nil {
+		return err
+	}
+	pterm.Success.Printf("Pod %s was adopted as %s\n", s.podName(ordinal), n.getPodName())
+
+	return nil
+}
+
+// retainVolume prevents the volume from being deleted with the original claim
+func (s *StatefulSetAdopter) retainVolume(claim *corev1.PersistentVolumeClaim) error {
+	if claim.Spec.VolumeName == "" {
+		return fmt.Errorf("claim %s is not bound to a volume", claim.Name)
+	}
+
+	pv := &corev1.PersistentVolume{}
+	if err := s.Client.Get(context.TODO(), types.NamespacedName{Name: claim.Spec.VolumeName}, pv); err != nil {
+		return err
+	}
+
+	if pv.Spec.PersistentVolumeReclaimPolicy == corev1.PersistentVolumeReclaimRetain {
+		return nil
+	}
+
+	pv.Spec.PersistentVolumeReclaimPolicy = corev1.PersistentVolumeReclaimRetain
+	return s.Client.Update(context.TODO(), pv)
+}
+
+// removePod drains the node and scales the StatefulSet down by one, which removes the pod with the highest ordinal
+func (s *StatefulSetAdopter) removePod(ordinal int) error {
+	if ordinal != s.replicas()-1 {
+		return fmt.Errorf("pod %s must be adopted before %s", s.podName(s.replicas()-1), s.podName(ordinal))
+	}
+
+	podKey := types.NamespacedName{Name: s.podName(ordinal), Namespace: s.Namespace}
+	pod := &corev1.Pod{}
+	if err := s.Client.Get(context.TODO(), podKey, pod); err != nil {
+		return err
+	}
+
+	installation, err := NewPodInstallation(s.execOptions, pod, s.ContainerName)
+	if err != nil {
+		return err
+	}
+
+	if _, err := installation.Nodetool("drain"); err != nil {
+		return err
+	}
+
+	replicas := int32(ordinal)
+	s.sts.Spec.Replicas = &replicas
+	if err := s.Client.Update(context.TODO(), s.sts); err != nil {
+		return err
+	}
+
+	return waitutil.PollImmediate(2*time.Second, adoptTimeout, func() (bool, error) {
+		err := s.Client.Get(context.TODO(), podKey, &corev1.Pod{})
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+}
+
+// rebindVolume replaces the original claim with a claim of the given name, bound to the same volume
+func (s *StatefulSetAdopter) rebindVolume(claim *corev1.PersistentVolumeClaim, name string) error {
+	volumeName := claim.Spec.VolumeName
+
+	if err := s.Client.Delete(context.TODO(), claim); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	err := waitutil.PollImmediate(time.Second, adoptTimeout, func() (bool, error) {
+		err := s.Client.Get(context.TODO(), types.NamespacedName{Name: claim.Name, Namespace: claim.Namespace}, &corev1.PersistentVolumeClaim{})
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+	if err != nil {
+		return err
+	}
+
+	pv := &corev1.PersistentVolume{}
+	if err := s.Client.Get(context.TODO(), types.NamespacedName{Name: volumeName}, pv); err != nil {
+		return err
+	}
+
+	return s.bindVolume(pv, adoptedClaim(claim, name))
+}
+
+// resumeRebind finishes a rebind that was interrupted after the original claim was deleted. The released volume still
+// references the original claim, or is already reserved for the new claim.
+func (s *StatefulSetAdopter) resumeRebind(claimName, name string) error {
+	newClaim := &corev1.PersistentVolumeClaim{}
+	err := s.Client.Get(context.TODO(), types.NamespacedName{Name: name, Namespace: s.Namespace}, newClaim)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err == nil && newClaim.Status.Phase == corev1.ClaimBound {
+		return nil
+	}
+
+	pvs := &corev1.PersistentVolumeList{}
+	if err := s.Client.List(context.TODO(), pvs); err != nil {
+		return err
+	}
+
+	pv := claimedVolume(pvs.Items, s.Namespace, claimName, name)
+	if pv == nil {
+		return fmt.Errorf("claim %s does not exist and no volume references claim %s or %s", name, claimName, name)
+	}
+
+	pterm.Info.Printf("Finishing the binding of volume %s to claim %s\n", pv.Name, name)
+	return s.bindVolume(pv, adoptedClaimForVolume(pv, s.Namespace, name))
+}
+
+// claimedVolume returns the volume that references either of the claims
+func claimedVolume(pvs []corev1.PersistentVolume, namespace, claimName, name string) *corev1.PersistentVolume {
+	for i := range pvs {
+		ref := pvs[i].Spec.ClaimRef
+		if ref == nil || ref.Namespace != namespace {
+			continue
+		}
+		if ref.Name == claimName || ref.Name == name {
+			return &pvs[i]
+		}
+	}
+	return nil
+}
+
+// bindVolume reserves the released volume for the new claim, creates the claim and waits until it is bound
+func (s *StatefulSetAdopter) bindVolume(pv *corev1.PersistentVolume, newClaim *corev1.PersistentVolumeClaim) error {
+	if pv.Spec.ClaimRef == nil || pv.Spec.ClaimRef.Name != newClaim.Name {
+		pv.Spec.ClaimRef = &corev1.ObjectReference{
+			Kind:      "PersistentVolumeClaim",
+			Namespace: newClaim.Namespace,
+			Name:      newClaim.Name,
+		}
+		if err := s.Client.Update(context.TODO(), pv); err != nil {
+			return err
+		}
+	}
+
+	if err := s.Client.Create(context.TODO(), newClaim); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	return waitutil.PollImmediate(time.Second, adoptTimeout, func() (bool, error) {
+		if err := s.Client.Get(context.TODO(), types.NamespacedName{Name: newClaim.Name, Namespace: newClaim.Namespace}, newClaim); err != nil {
+			return false, err
+		}
+		return newClaim.Status.Phase == corev1.ClaimBound, nil
+	})
+}
+
+// adoptedClaim returns a claim with the given name for the volume of the original claim
+func adoptedClaim(claim *corev1.PersistentVolumeClaim, name string) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: claim.Namespace,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      claim.Spec.AccessModes,
+			Resources:        claim.Spec.Resources,
+			StorageClassName: claim.Spec.StorageClassName,
+			VolumeMode:       claim.Spec.VolumeMode,
+			VolumeName:       claim.Spec.VolumeName,
+		},
+	}
+}
+
+// adoptedClaimForVolume returns a claim with the given name for the volume when the original claim no longer exists
+func adoptedClaimForVolume(pv *corev1.PersistentVolume, namespace, name string) *corev1.PersistentVolumeClaim {
+	var storageClassName *string
+	if pv.Spec.StorageClassName != "" {
+		storageClassName = &pv.Spec.StorageClassName
+	}
+
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: pv.Spec.AccessModes,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: pv.Spec.Capacity[corev1.ResourceStorage],
+				},
+			},
+			StorageClassName: storageClassName,
+			VolumeMode:       pv.Spec.VolumeMode,
+			VolumeName:       pv.Name,
+		},
+	}
+}
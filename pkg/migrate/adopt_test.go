@@ -0,0 +1,157 @@
+package migrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRelocateDirectories(t *testing.T) {
+	require := require.New(t)
+
+	bitnamiYaml := `
+data_file_directories:
+  - /bitnami/cassandra/data/data
+commitlog_directory: /bitnami/cassandra/data/commitlog
+hints_directory: /bitnami/cassandra/data/hints
+saved_caches_directory: /var/lib/cassandra/saved_caches
+cdc_raw_directory: ""
+`
+	cassYaml := make(map[string]interface{})
+	require.NoError(yaml.Unmarshal([]byte(bitnamiYaml), &cassYaml))
+
+	require.NoError(relocateDirectories(cassYaml, "/bitnami/cassandra", cassandraDataPath))
+	require.Equal([]interface{}{"/var/lib/cassandra/data/data"}, cassYaml["data_file_directories"])
+	require.Equal("/var/lib/cassandra/data/commitlog", cassYaml["commitlog_directory"])
+	require.Equal("/var/lib/cassandra/data/hints", cassYaml["hints_directory"])
+	// Already in the cass-operator data path
+	require.Equal("/var/lib/cassandra/saved_caches", cassYaml["saved_caches_directory"])
+	require.Equal("", cassYaml["cdc_raw_directory"])
+
+	// Running again keeps the relocated directories
+	require.NoError(relocateDirectories(cassYaml, "/bitnami/cassandra", cassandraDataPath))
+	require.Equal("/var/lib/cassandra/data/commitlog", cassYaml["commitlog_directory"])
+
+	cassYaml["commitlog_directory"] = "/commitlog"
+	require.Error(relocateDirectories(cassYaml, "/bitnami/cassandra", cassandraDataPath))
+
+	require.Error(relocateDirectories(map[string]interface{}{"hints_directory": "/bitnami/cassandra/hints"}, "/bitnami/cassandra", cassandraDataPath))
+}
+
+func TestDataMountPath(t *testing.T) {
+	require := require.New(t)
+
+	containers := []corev1.Container{
+		{
+			Name: "metrics",
+		},
+		{
+			Name: "cassandra",
+			VolumeMounts: []corev1.VolumeMount{
+				{Name: "empty-dirs", MountPath: "/tmp"},
+				{Name: "data", MountPath: "/bitnami/cassandra"},
+			},
+		},
+	}
+
+	path, err := dataMountPath(containers, "cassandra", "data")
+	require.NoError(err)
+	require.Equal("/bitnami/cassandra", path)
+
+	_, err = dataMountPath(containers, "server", "data")
+	require.Error(err)
+
+	_, err = dataMountPath(containers, "cassandra", "commitlog")
+	require.Error(err)
+
+	containers[1].VolumeMounts[1].SubPath = "cassandra"
+	_, err = dataMountPath(containers, "cassandra", "data")
+	require.Error(err)
+}
+
+func TestAdoptedClaim(t *testing.T) {
+	require := require.New(t)
+
+	storageClassName := "gp2"
+	claim := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "data-cassandra-2",
+			Namespace: "cassandra",
+			Labels:    map[string]string{"app.kubernetes.io/instance": "cassandra"},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("100Gi")},
+			},
+			StorageClassName: &storageClassName,
+			VolumeName:       "pvc-0c1f",
+		},
+	}
+
+	adopted := adoptedClaim(claim, "server-data-cassandra-datacenter1-rack1-sts-2")
+	require.Equal("server-data-cassandra-datacenter1-rack1-sts-2", adopted.Name)
+	require.Equal("cassandra", adopted.Namespace)
+	require.Empty(adopted.Labels)
+	require.Equal("pvc-0c1f", adopted.Spec.VolumeName)
+	require.Equal(&storageClassName, adopted.Spec.StorageClassName)
+	require.True(resource.MustParse("100Gi").Equal(adopted.Spec.Resources.Requests[corev1.ResourceStorage]))
+}
+
+func TestHostIDForAddress(t *testing.T) {
+	require := require.New(t)
+
+	nodeInfos := []NodetoolNodeInfo{
+		{Address: "10.244.0.12", HostId: "a8f1"},
+		{Address: "10.244.1.7", HostId: "5c2e"},
+	}
+
+	require.Equal("5c2e", hostIDForAddress(nodeInfos, "10.244.1.7"))
+	require.Equal("", hostIDForAddress(nodeInfos, "10.244.2.3"))
+	require.Equal("", hostIDForAddress(nodeInfos, ""))
+}
+
+func TestClaimedVolume(t *testing.T) {
+	require := require.New(t)
+
+	pvs := []corev1.PersistentVolume{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pvc-9a7b"},
+			Spec: corev1.PersistentVolumeSpec{
+				ClaimRef: &corev1.ObjectReference{Namespace: "cassandra", Name: "data-cassandra-1"},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "pvc-0c1f"},
+			Spec: corev1.PersistentVolumeSpec{
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Capacity:         corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("100Gi")},
+				StorageClassName: "gp2",
+				ClaimRef:         &corev1.ObjectReference{Namespace: "cassandra", Name: "data-cassandra-2", UID: "8e2d"},
+			},
+		},
+	}
+
+	// Released volume still references the deleted claim
+	pv := claimedVolume(pvs, "cassandra", "data-cassandra-2", "server-data-cassandra-datacenter1-rack1-sts-2")
+	require.NotNil(pv)
+	require.Equal("pvc-0c1f", pv.Name)
+
+	// Volume already reserved for the new claim
+	pvs[1].Spec.ClaimRef = &corev1.ObjectReference{Namespace: "cassandra", Name: "server-data-cassandra-datacenter1-rack1-sts-2"}
+	pv = claimedVolume(pvs, "cassandra", "data-cassandra-2", "server-data-cassandra-datacenter1-rack1-sts-2")
+	require.NotNil(pv)
+	require.Equal("pvc-0c1f", pv.Name)
+
+	require.Nil(claimedVolume(pvs, "other", "data-cassandra-2", "server-data-cassandra-datacenter1-rack1-sts-2"))
+
+	adopted := adoptedClaimForVolume(pv, "cassandra", "server-data-cassandra-datacenter1-rack1-sts-2")
+	require.Equal("server-data-cassandra-datacenter1-rack1-sts-2", adopted.Name)
+	require.Equal("pvc-0c1f", adopted.Spec.VolumeName)
+	require.Equal("gp2", *adopted.Spec.StorageClassName)
+	require.True(resource.MustParse("100Gi").Equal(adopted.Spec.Resources.Requests[corev1.ResourceStorage]))
+}
@@ -0,0 +1,200 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+
+	"github.com/pterm/pterm"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// auditKey has the audit trail in the datacenter's migrate ConfigMap
+	auditKey = "audit"
+	// auditHistoryLimit is the number of audit records kept, the oldest are removed first
+	auditHistoryLimit = 200
+
+	eventComponent = "k8ssandra-import"
+
+	AuditSucceeded = "succeeded"
+	AuditFailed    = "failed"
+)
+
+// AuditRecord is a migration step run by a user on a host
+type AuditRecord struct {
+	Time     time.Time     `json:"time"`
+	Host     string        `json:"host"`
+	User     string        `json:"user"`
+	HostID   string        `json:"hostId,omitempty"`
+	Pod      string        `json:"pod,omitempty"`
+	Step     string        `json:"step"`
+	Result   string        `json:"result"`
+	Duration time.Duration `json:"duration"`
+	Message  string        `json:"message,omitempty"`
+}
+
+// newAuditRecord returns a record of the step run by the current user on this host
+func newAuditRecord(step, result string, duration time.Duration, message string) AuditRecord {
+	host, _ := os.Hostname()
+	username := ""
+	if current, err := user.Current(); err == nil {
+		username = current.Username
+	}
+
+	return AuditRecord{
+		Time:     time.Now().UTC(),
+		Host:     host,
+		User:     username,
+		Step:     step,
+		Result:   result,
+		Duration: duration,
+		Message:  message,
+	}
+}
+
+// appendAuditRecord adds the record to the history, keeping at most limit records
+func appendAuditRecord(history []AuditRecord, record AuditRecord, limit int) []AuditRecord {
+	history = append(history, record)
+	if len(history) > limit {
+		history = history[len(history)-limit:]
+	}
+	return history
+}
+
+func decodeAuditTrail(configMap *corev1.ConfigMap) ([]AuditRecord, error) {
+	history := make([]AuditRecord, 0)
+	if data, found := configMap.Data[auditKey]; found {
+		if err := json.Unmarshal([]byte(data), &history); err != nil {
+			return nil, fmt.Errorf("unable to parse the audit trail of ConfigMap %s: %w", configMap.Name, err)
+		}
+	}
+	return history, nil
+}
+
+// FetchAuditTrail returns the audit trail of the datacenter's migration, oldest first
+func FetchAuditTrail(cli client.Client, namespace, datacenter string) ([]AuditRecord, error) {
+	configMap := &corev1.ConfigMap{}
+	if err := cli.Get(context.TODO(), types.NamespacedName{Name: configMapName(datacenter), Namespace: namespace}, configMap); err != nil {
+		return nil, err
+	}
+	return decodeAuditTrail(configMap)
+}
+
+// recordAudit adds the record to the audit trail of the datacenter's migrate ConfigMap and emits it as an Event for
+// the ConfigMap and the record's pod. Nodes can be migrated concurrently, so conflicts are retried.
+func recordAudit(cli client.Client, namespace, datacenter string, record AuditRecord) error {
+	configMap := &corev1.ConfigMap{}
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := cli.Get(context.TODO(), types.NamespacedName{Name: configMapName(datacenter), Namespace: namespace}, configMap); err != nil {
+			return err
+		}
+
+		history, err := decodeAuditTrail(configMap)
+		if err != nil {
+			return err
+		}
+
+		b, err := json.Marshal(appendAuditRecord(history, record, auditHistoryLimit))
+		if err != nil {
+			return err
+		}
+
+		if configMap.Data == nil {
+			configMap.Data = make(map[string]string)
+		}
+		configMap.Data[auditKey] = string(b)
+		return cli.Update(context.TODO(), configMap)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := createEvent(cli, configMap, "ConfigMap", record); err != nil {
+		return err
+	}
+
+	if record.Pod == "" {
+		return nil
+	}
+
+	pod := &corev1.Pod{}
+	if err := cli.Get(context.TODO(), types.NamespacedName{Name: record.Pod, Namespace: namespace}, pod); err != nil {
+		return err
+	}
+	return createEvent(cli, pod, "Pod", record)
+}
+
+// createEvent creates the Event directly, the client runs only for the duration of the command and an event
+// broadcaster could lose the last events
+func createEvent(cli client.Client, obj client.Object, kind string, record AuditRecord) error {
+	eventType := corev1.EventTypeNormal
+	reason := "MigrationStep"
+	if record.Result == AuditFailed {
+		eventType = corev1.EventTypeWarning
+		reason = "MigrationFailed"
+	}
+
+	message := fmt.Sprintf("%s %s in %s on host %s by %s", record.Step, record.Result, record.Duration.Round(time.Millisecond), record.Host, record.User)
+	if record.HostID != "" {
+		message = fmt.Sprintf("%s, Cassandra host ID %s", message, record.HostID)
+	}
+	if record.Message != "" {
+		message = fmt.Sprintf("%s: %s", message, record.Message)
+	}
+
+	timestamp := metav1.NewTime(record.Time)
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", obj.GetName(), record.Time.UnixNano()),
+			Namespace: obj.GetNamespace(),
+		},
+		InvolvedObject: corev1.ObjectReference{
+			Kind:            kind,
+			APIVersion:      "v1",
+			Namespace:       obj.GetNamespace(),
+			Name:            obj.GetName(),
+			UID:             obj.GetUID(),
+			ResourceVersion: obj.GetResourceVersion(),
+		},
+		Reason:  reason,
+		Message: message,
+		Type:    eventType,
+		Source: corev1.EventSource{
+			Component: eventComponent,
+			Host:      record.Host,
+		},
+		FirstTimestamp:      timestamp,
+		LastTimestamp:       timestamp,
+		Count:               1,
+		ReportingController: eventComponent,
+		ReportingInstance:   record.Host,
+	}
+
+	return cli.Create(context.TODO(), event)
+}
+
+// audit records the finished step of the node migration. Steps before the datacenter is known are not recorded, and
+// a failure to record is only reported as the migration itself continues.
+func (n *NodeMigrator) audit(step, result string, duration time.Duration, message string) {
+	if n.Datacenter == "" || step == "" {
+		return
+	}
+
+	record := newAuditRecord(step, result, duration, message)
+	record.HostID = n.HostID
+	if n.podCreated {
+		record.Pod = n.getPodName()
+	}
+
+	if err := recordAudit(n.Client, n.Namespace, n.Datacenter, record); err != nil {
+		pterm.Warning.Printf("Unable to record step '%s' to the audit trail: %v\n", step, err)
+	}
+}
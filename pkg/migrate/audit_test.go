@@ -0,0 +1,51 @@
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestAppendAuditRecord(t *testing.T) {
+	require := require.New(t)
+
+	history := make([]AuditRecord, 0)
+	for i := 0; i < 5; i++ {
+		history = appendAuditRecord(history, AuditRecord{Step: fmt.Sprintf("step %d", i)}, 3)
+	}
+
+	require.Len(history, 3)
+	require.Equal("step 2", history[0].Step)
+	require.Equal("step 4", history[2].Step)
+}
+
+func TestDecodeAuditTrail(t *testing.T) {
+	require := require.New(t)
+
+	configMap := &corev1.ConfigMap{}
+	history, err := decodeAuditTrail(configMap)
+	require.NoError(err)
+	require.Empty(history)
+
+	record := newAuditRecord("Draining and shutting down the current node", AuditFailed, 90*time.Second, "unable to execute nodetool against localhost")
+	require.NotEmpty(record.Host)
+	require.False(record.Time.IsZero())
+
+	b, err := json.Marshal([]AuditRecord{record})
+	require.NoError(err)
+	configMap.Data = map[string]string{auditKey: string(b)}
+
+	history, err = decodeAuditTrail(configMap)
+	require.NoError(err)
+	require.Len(history, 1)
+	require.Equal(AuditFailed, history[0].Result)
+	require.Equal(90*time.Second, history[0].Duration)
+
+	configMap.Data[auditKey] = "not json"
+	_, err = decodeAuditTrail(configMap)
+	require.Error(err)
+}
@@ -0,0 +1,297 @@
+package migrate
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/burmanm/k8ssandra-client/pkg/cassdcutil"
+	cassdcapi "github.com/k8ssandra/cass-operator/apis/cassandra/v1beta1"
+	"github.com/pterm/pterm"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/kubectl/pkg/cmd/exec"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	migrationSnapshotPrefix = "k8ssandra-import"
+	configBackupKey         = "config.tar.gz"
+)
+
+var (
+	// serviceFiles are the init scripts and systemd units of the package and tarball installations
+	serviceFiles = []string{
+		"/etc/systemd/system/cassandra.service",
+		"/etc/systemd/system/dse.service",
+		"/lib/systemd/system/cassandra.service",
+		"/lib/systemd/system/dse.service",
+		"/usr/lib/systemd/system/cassandra.service",
+		"/usr/lib/systemd/system/dse.service",
+		"/etc/init.d/cassandra",
+		"/etc/init.d/dse",
+		"/etc/default/cassandra",
+		dseDefaultsFile,
+	}
+)
+
+// NodeBackup is the restore point taken before the node was drained
+type NodeBackup struct {
+	HostID string `json:"hostId"`
+	// SnapshotTag is the tag of the nodetool snapshot, empty if no snapshot was taken or it has been cleared
+	SnapshotTag string `json:"snapshotTag,omitempty"`
+	// ConfigSecret has the archived configuration directories and service files
+	ConfigSecret string    `json:"configSecret"`
+	Created      time.Time `json:"created"`
+	// DisabledServices are the host service entries disabled after the pod started, such as systemd:cassandra
+	DisabledServices []string `json:"disabledServices,omitempty"`
+}
+
+func backupsConfigMapName(datacenter string) string {
+	return getConfigMapName(datacenter, "migrate-backups")
+}
+
+func configBackupSecretName(datacenter, hostID string) string {
+	return importSecretName(datacenter, fmt.Sprintf("backup-%s", hostID))
+}
+
+// backupNode takes a snapshot of the local node and archives its configuration before the node is drained
+func (n *NodeMigrator) backupNode() error {
+	backup := NodeBackup{
+		HostID:       n.HostID,
+		ConfigSecret: configBackupSecretName(n.Datacenter, n.HostID),
+		Created:      time.Now().UTC(),
+	}
+
+	if n.Snapshot {
+		tag := fmt.Sprintf("%s-%s", migrationSnapshotPrefix, backup.Created.Format("20060102150405"))
+		if _, err := n.nodetool("snapshot", "-t", tag); err != nil {
+			return fmt.Errorf("unable to take snapshot: %w", err)
+		}
+		backup.SnapshotTag = tag
+		pterm.Success.Printf("Created snapshot %s\n", tag)
+	}
+
+	paths := []string{n.configs.cassConfigHome}
+	if n.configs.dseConfigHome != "" && n.configs.dseConfigHome != n.configs.cassConfigHome {
+		paths = append(paths, n.configs.dseConfigHome)
+	}
+	paths = append(paths, serviceFiles...)
+
+	archive, err := archiveFiles(paths)
+	if err != nil {
+		return err
+	}
+
+	if len(archive) > configMapSizeLimit {
+		return fmt.Errorf("configuration backup is too large to be stored in a Secret (%d bytes)", len(archive))
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      backup.ConfigSecret,
+			Namespace: n.Namespace,
+		},
+		Data: map[string][]byte{
+			configBackupKey: archive,
+		},
+	}
+
+	if err := n.Client.Create(context.TODO(), secret); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return err
+		}
+		// Previous attempt to migrate this node, keep the newest backup
+		if err := n.Client.Update(context.TODO(), secret); err != nil {
+			return err
+		}
+	}
+
+	n.backup = backup
+
+	return recordBackup(n.Client, n.Namespace, n.Datacenter, backup)
+}
+
+// archiveFiles creates a tar.gz from the given files and directories. Missing paths are skipped.
+func archiveFiles(paths []string) ([]byte, error) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gw)
+
+	for _, root := range paths {
+		if _, err := os.Stat(root); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !d.Type().IsRegular() {
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			header, err := tar.FileInfoHeader(info, "")
+			if err != nil {
+				return err
+			}
+			header.Name = strings.TrimPrefix(filepath.ToSlash(path), "/")
+
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+
+			f, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			_, err = io.Copy(tw, f)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// recordBackup adds the backup to the datacenter's backup ConfigMap. Nodes can be migrated concurrently, so
+// conflicts are retried.
+func recordBackup(cli client.Client, namespace, datacenter string, backup NodeBackup) error {
+	b, err := json.Marshal(backup)
+	if err != nil {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap := &corev1.ConfigMap{}
+		configMapKey := types.NamespacedName{Name: backupsConfigMapName(datacenter), Namespace: namespace}
+		if err := cli.Get(context.TODO(), configMapKey, configMap); err != nil {
+			if !errors.IsNotFound(err) {
+				return err
+			}
+			configMap.ObjectMeta.Name = configMapKey.Name
+			configMap.ObjectMeta.Namespace = configMapKey.Namespace
+			configMap.Data = map[string]string{
+				backup.HostID: string(b),
+			}
+			return cli.Create(context.TODO(), configMap)
+		}
+
+		if configMap.Data == nil {
+			configMap.Data = make(map[string]string)
+		}
+		configMap.Data[backup.HostID] = string(b)
+		return cli.Update(context.TODO(), configMap)
+	})
+}
+
+// FetchBackups returns the restore points taken during the migration of the datacenter, sorted by creation time
+func FetchBackups(cli client.Client, namespace, datacenter string) ([]NodeBackup, error) {
+	configMap := &corev1.ConfigMap{}
+	configMapKey := types.NamespacedName{Name: backupsConfigMapName(datacenter), Namespace: namespace}
+	if err := cli.Get(context.TODO(), configMapKey, configMap); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	backups := make([]NodeBackup, 0, len(configMap.Data))
+	for _, data := range configMap.Data {
+		backup := NodeBackup{}
+		if err := json.Unmarshal([]byte(data), &backup); err != nil {
+			return nil, err
+		}
+		backups = append(backups, backup)
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Created.Before(backups[j].Created)
+	})
+
+	return backups, nil
+}
+
+// ClearSnapshots removes the snapshots taken before the migration from the pods of the datacenter. The datacenter
+// must be Ready, the snapshots are the only restore point until then.
+func (c *MigrateFinisher) ClearSnapshots(execOptions *exec.ExecOptions) error {
+	dc, err := cassdcutil.NewManager(c.Client).CassandraDatacenter(c.datacenter, c.namespace)
+	if err != nil {
+		return err
+	}
+
+	if dc.Status.GetConditionStatus(cassdcapi.DatacenterReady) != corev1.ConditionTrue {
+		return fmt.Errorf("CassandraDatacenter %s is not ready, keeping the snapshots", dc.Name)
+	}
+
+	backups, err := FetchBackups(c.Client, c.namespace, c.datacenter)
+	if err != nil {
+		return err
+	}
+
+	hostPods := make(map[string]string, len(dc.Status.NodeStatuses))
+	for podName, nodeStatus := range dc.Status.NodeStatuses {
+		hostPods[nodeStatus.HostID] = podName
+	}
+
+	for _, backup := range backups {
+		if backup.SnapshotTag == "" {
+			continue
+		}
+
+		podName, found := hostPods[backup.HostID]
+		if !found {
+			pterm.Warning.Printf("No pod found for host %s, snapshot %s was not cleared\n", backup.HostID, backup.SnapshotTag)
+			continue
+		}
+
+		// The pod's local JMX does not require credentials, they are not passed on the command line
+		execOptions.PodName = podName
+		execOptions.Command = []string{"nodetool", "clearsnapshot", "-t", backup.SnapshotTag}
+		if err := execOptions.Run(); err != nil {
+			return fmt.Errorf("unable to clear snapshot %s from pod %s: %w", backup.SnapshotTag, podName, err)
+		}
+
+		pterm.Success.Printf("Cleared snapshot %s from pod %s\n", backup.SnapshotTag, podName)
+
+		backup.SnapshotTag = ""
+		if err := recordBackup(c.Client, c.namespace, c.datacenter, backup); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
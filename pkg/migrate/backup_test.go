@@ -0,0 +1,48 @@
+package migrate
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestArchiveFiles(t *testing.T) {
+	require := require.New(t)
+	confDir := t.TempDir()
+
+	require.NoError(os.MkdirAll(filepath.Join(confDir, "triggers"), 0755))
+	require.NoError(os.WriteFile(filepath.Join(confDir, "cassandra.yaml"), []byte("num_tokens: 16\n"), 0644))
+	require.NoError(os.WriteFile(filepath.Join(confDir, "triggers", "README.txt"), []byte("triggers"), 0644))
+
+	archive, err := archiveFiles([]string{confDir, filepath.Join(confDir, "missing.service")})
+	require.NoError(err)
+
+	gr, err := gzip.NewReader(bytes.NewReader(archive))
+	require.NoError(err)
+	tr := tar.NewReader(gr)
+
+	files := make(map[string]string)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(err)
+		content, err := io.ReadAll(tr)
+		require.NoError(err)
+		files[header.Name] = string(content)
+	}
+
+	root := strings.TrimPrefix(filepath.ToSlash(confDir), "/")
+	require.Equal(map[string]string{
+		root + "/cassandra.yaml":      "num_tokens: 16\n",
+		root + "/triggers/README.txt": "triggers",
+	}, files)
+}
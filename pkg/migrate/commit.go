@@ -29,7 +29,6 @@ type MigrateFinisher struct {
 /*
 	TODO:
 		- DSE_HOME could indicate more to us
-		- We need to remove the startup scripts / disable them (such as systemd start scripts)
 */
 
 func NewMigrateFinisher(cli client.Client, namespace, datacenter string) *MigrateFinisher {
@@ -41,26 +40,32 @@ func NewMigrateFinisher(cli client.Client, namespace, datacenter string) *Migrat
 }
 
 func (c *MigrateFinisher) fetchConfiguration() error {
-	configMap := &corev1.ConfigMap{}
-	configMapKey := types.NamespacedName{Name: configMapName(c.datacenter), Namespace: c.namespace}
-	if err := c.Client.Get(context.TODO(), configMapKey, configMap); err != nil {
-		return err
-	}
-
-	b := configMap.BinaryData["clusterInfo"]
-
-	clusterConfigMap := ClusterConfigMap{}
-	if err := json.Unmarshal(b, &clusterConfigMap); err != nil {
+	clusterConfigMap, err := fetchClusterConfigMap(c.Client, c.namespace, c.datacenter)
+	if err != nil {
 		return err
 	}
 
-	c.clusterConfigMap = clusterConfigMap
+	c.clusterConfigMap = *clusterConfigMap
 
 	return nil
 }
 
 func (c *MigrateFinisher) FinishInstallation(p *pterm.SpinnerPrinter) error {
+	started := time.Now()
+	err := c.finishInstallation(p)
+
+	result, message := AuditSucceeded, ""
+	if err != nil {
+		result, message = AuditFailed, err.Error()
+	}
+	if recordErr := recordAudit(c.Client, c.namespace, c.datacenter, newAuditRecord("Datacenter commit", result, time.Since(started), message)); recordErr != nil {
+		pterm.Warning.Printf("Unable to record the commit to the audit trail: %v\n", recordErr)
+	}
+
+	return err
+}
 
+func (c *MigrateFinisher) finishInstallation(p *pterm.SpinnerPrinter) error {
 	p.UpdateText("Fetching cluster configuration...")
 
 	err := c.fetchConfiguration()
@@ -70,6 +75,14 @@ func (c *MigrateFinisher) FinishInstallation(p *pterm.SpinnerPrinter) error {
 
 	pterm.Success.Println("Cluster configuration fetched")
 
+	installed, err := k8ssandraOperatorInstalled(c.Client, c.namespace)
+	if err != nil {
+		return err
+	}
+	if installed {
+		return c.finishK8ssandraCluster(p)
+	}
+
 	p.UpdateText("Creating CassandraDatacenter")
 
 	err = c.createCassandraDatacenter()
@@ -93,7 +106,7 @@ func (c *MigrateFinisher) FinishInstallation(p *pterm.SpinnerPrinter) error {
 	return nil
 }
 
-func (c *MigrateFinisher) countOfMigratedPods() (int, error) {
+func (c *MigrateFinisher) migratedPods() ([]corev1.Pod, error) {
 	// countOfMigratedPods failed: found 'Cluster', expected: ',' or 'end of string'
 	podList := &corev1.PodList{}
 	datacenterLabels := map[string]string{
@@ -101,20 +114,33 @@ func (c *MigrateFinisher) countOfMigratedPods() (int, error) {
 		cassdcapi.ClusterLabel:    cassdcapi.CleanupForKubernetes(c.clusterConfigMap.Cluster),
 	}
 	if err := c.Client.List(context.TODO(), podList, client.MatchingLabels(datacenterLabels)); err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	return podList.Size(), nil
-
+	return podList.Items, nil
 }
 
 func (c *MigrateFinisher) createCassandraDatacenter() error {
+	dc, err := c.buildCassandraDatacenter()
+	if err != nil {
+		return err
+	}
+
+	if err := c.Client.Create(context.TODO(), dc); err != nil {
+		fmt.Printf("Failed to insert CassDc, CassDc: %v", dc)
+		return err
+	}
+	return nil
+}
+
+func (c *MigrateFinisher) buildCassandraDatacenter() (*cassdcapi.CassandraDatacenter, error) {
 	// Fetch the amount of pods we created to ensure all the pods have been
 	// migrated before we continue
-	datacenterSize, err := c.countOfMigratedPods()
+	pods, err := c.migratedPods()
 	if err != nil {
-		return err
+		return nil, err
 	}
+	datacenterSize := len(pods)
 	// datacenterSize = len(c.clusterConfigMap.NodeInfos)
 
 	if datacenterSize != len(c.clusterConfigMap.NodeInfos) {
@@ -134,33 +160,63 @@ func (c *MigrateFinisher) createCassandraDatacenter() error {
 			Name: rackInfo,
 		})
 	}
-	storageClassName := "local-path"
 	userId := int64(999)
 	userGroup := int64(999)
 
 	// TODO Read from the cluster migration config - it should've been validated value
 	fsGroup := int64(121)
+	hostNetwork := true
+	if len(pods) > 0 {
+		if securityContext := pods[0].Spec.SecurityContext; securityContext != nil && securityContext.FSGroup != nil {
+			fsGroup = *securityContext.FSGroup
+		}
+		// Pods adopted from a StatefulSet are already in the pod network
+		hostNetwork = pods[0].Spec.HostNetwork
+	}
 
-	// TODO Move the configFileGetting to reusable function
-	configFilesMap := &corev1.ConfigMap{}
-	configFilesMapKey := types.NamespacedName{Name: getConfigMapName(c.clusterConfigMap.Datacenter, "cass-config"), Namespace: c.namespace}
-	if err := c.Client.Get(context.TODO(), configFilesMapKey, configFilesMap); err != nil {
-		return err
+	claimSpec, err := c.dataClaimSpec(pods)
+	if err != nil {
+		return nil, err
+	}
+
+	_, files, err := fetchConfigFiles(c.Client, c.namespace, c.clusterConfigMap.Datacenter)
+	if err != nil {
+		return nil, err
 	}
 
 	config := make(map[string]interface{})
 
-	for yamlKey, yamlFile := range configFilesMap.Data {
+	for yamlKey, yamlFile := range files {
 		modelValues := make(map[string]interface{})
 		if err := yaml.Unmarshal([]byte(yamlFile), modelValues); err != nil {
-			return err
+			return nil, err
 		}
 		config[yamlKey] = modelValues
 	}
 
 	modelBytes, err := json.Marshal(config)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	secrets, err := existingImportSecrets(c.Client, c.namespace, c.clusterConfigMap.Datacenter)
+	if err != nil {
+		return nil, err
+	}
+
+	superuserSecret, err := importedSuperuser(c.Client, c.namespace, c.clusterConfigMap.Datacenter)
+	if err != nil {
+		return nil, err
+	}
+
+	logbackVolume, logbackMount, err := existingLogbackConfig(c.Client, c.namespace, c.clusterConfigMap.Datacenter)
+	if err != nil {
+		return nil, err
+	}
+
+	resources, tolerations, err := datacenterScheduling(pods)
+	if err != nil {
+		return nil, err
 	}
 
 	dc := &cassdcapi.CassandraDatacenter{
@@ -174,27 +230,19 @@ func (c *MigrateFinisher) createCassandraDatacenter() error {
 			ClusterName:   c.clusterConfigMap.Cluster,
 			ServerType:    c.clusterConfigMap.ServerType,
 			ServerVersion: c.clusterConfigMap.ServerVersion,
+			DseWorkloads:  c.clusterConfigMap.DseWorkloads,
 			ManagementApiAuth: cassdcapi.ManagementApiAuthConfig{
 				Insecure: &cassdcapi.ManagementApiAuthInsecureConfig{},
 			},
-			Size:  int32(datacenterSize),
-			Racks: racks,
+			Size:      int32(datacenterSize),
+			Racks:     racks,
+			Resources: resources,
+			// Migrated pods use host networking, use "import network" to move to pod networking
 			Networking: &cassdcapi.NetworkingConfig{
-				HostNetwork: true,
+				HostNetwork: hostNetwork,
 			},
 			StorageConfig: cassdcapi.StorageConfig{
-				CassandraDataVolumeClaimSpec: &corev1.PersistentVolumeClaimSpec{
-					AccessModes: []corev1.PersistentVolumeAccessMode{
-						corev1.ReadWriteOnce,
-					},
-					Resources: corev1.ResourceRequirements{
-						Requests: corev1.ResourceList{
-							// TODO Hardcoded not real value
-							corev1.ResourceStorage: resource.MustParse("5Gi"),
-						},
-					},
-					StorageClassName: &storageClassName,
-				},
+				CassandraDataVolumeClaimSpec: claimSpec,
 			},
 			PodTemplateSpec: &corev1.PodTemplateSpec{
 				Spec: corev1.PodSpec{
@@ -208,17 +256,67 @@ func (c *MigrateFinisher) createCassandraDatacenter() error {
 						RunAsGroup: &userGroup,
 						FSGroup:    &fsGroup,
 					},
+					Tolerations: tolerations,
 				},
 			},
 			Config: modelBytes,
+			// Imported with "import credentials", cass-operator generates a new superuser if not set
+			SuperuserSecretName: superuserSecret,
 		},
 	}
 
-	if err := c.Client.Create(context.TODO(), dc); err != nil {
-		fmt.Printf("Failed to insert CassDc, CassDc: %v", dc)
-		return err
+	podSpec := &dc.Spec.PodTemplateSpec.Spec
+	for _, secret := range secrets {
+		podSpec.Volumes = append(podSpec.Volumes, secret.Volume())
+		podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, secret.mounts...)
 	}
-	return nil
+
+	if logbackVolume != nil {
+		podSpec.Volumes = append(podSpec.Volumes, *logbackVolume)
+		podSpec.Containers[0].VolumeMounts = append(podSpec.Containers[0].VolumeMounts, *logbackMount)
+	}
+
+	return dc, nil
+}
+
+// dataClaimSpec returns the claim spec of the datacenter's data volumes. The storage class and size are read from the
+// server-data claim of the first pod, adopted StatefulSets keep their storage class.
+func (c *MigrateFinisher) dataClaimSpec(pods []corev1.Pod) (*corev1.PersistentVolumeClaimSpec, error) {
+	storageClassName := "local-path"
+	spec := &corev1.PersistentVolumeClaimSpec{
+		AccessModes: []corev1.PersistentVolumeAccessMode{
+			corev1.ReadWriteOnce,
+		},
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				// TODO Hardcoded not real value
+				corev1.ResourceStorage: resource.MustParse("5Gi"),
+			},
+		},
+		StorageClassName: &storageClassName,
+	}
+
+	if len(pods) == 0 {
+		return spec, nil
+	}
+
+	for _, volume := range pods[0].Spec.Volumes {
+		if volume.Name != PvcName || volume.PersistentVolumeClaim == nil {
+			continue
+		}
+		claim := &corev1.PersistentVolumeClaim{}
+		if err := c.Client.Get(context.TODO(), types.NamespacedName{Name: volume.PersistentVolumeClaim.ClaimName, Namespace: c.namespace}, claim); err != nil {
+			return nil, err
+		}
+		if claim.Spec.StorageClassName != nil {
+			spec.StorageClassName = claim.Spec.StorageClassName
+		}
+		if size, found := claim.Spec.Resources.Requests[corev1.ResourceStorage]; found {
+			spec.Resources.Requests[corev1.ResourceStorage] = size
+		}
+	}
+
+	return spec, nil
 }
 
 func (c *MigrateFinisher) waitForDatacenter() error {
@@ -1,13 +1,10 @@
 package migrate
 
 import (
-	"bufio"
 	"context"
 	"fmt"
-	"io/fs"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strings"
 
 	"github.com/pterm/pterm"
@@ -15,8 +12,6 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
-
-	definitions "github.com/burmanm/definitions-parser/pkg/types/matcher"
 )
 
 const (
@@ -28,9 +23,32 @@ const (
 type ConfigParser struct {
 	cassConfigHome string
 	dseConfigHome  string
+	cassandraHome  string
+
+	yamls map[string]map[string]interface{}
+
+	// unsupportedJvmOptions are the JVM options per file that could not be carried over
+	unsupportedJvmOptions map[string][]string
+
+	rackDc   map[string]string
+	topology map[string]string
+	logback  []byte
+
+	// container is set if the node runs in a container, the files referenced by the configuration are read from it
+	container *ContainerInstallation
+}
 
-	yamls      map[string]map[string]interface{}
-	jvmOptions map[string]string
+// localFilesystem reads the files referenced by the configuration from the local filesystem
+func localFilesystem(path string) (string, error) {
+	return path, nil
+}
+
+// localFile returns the local path of a file referenced by the configuration
+func (p *ConfigParser) localFile(path string) (string, error) {
+	if p.container == nil {
+		return localFilesystem(path)
+	}
+	return p.container.LocalPath(path)
 }
 
 func (p *ConfigParser) Yamls() map[string]map[string]interface{} {
@@ -41,6 +59,33 @@ func (p *ConfigParser) CassYaml() map[string]interface{} {
 	return p.yamls[cassYamlKey]
 }
 
+// CassandraHome returns the installation directory, which is used to resolve relative paths in the configuration files
+func (p *ConfigParser) CassandraHome() string {
+	// Tarball installations have the configuration files in $CASSANDRA_HOME/conf
+	confDir := filepath.Clean(p.cassConfigHome)
+	if filepath.Base(confDir) == "conf" {
+		return filepath.Dir(confDir)
+	}
+	return p.cassandraHome
+}
+
+// DseHome returns the DSE installation directory, which is used to resolve relative paths in the dse.yaml
+func (p *ConfigParser) DseHome() string {
+	if p.cassandraHome != "" {
+		return p.cassandraHome
+	}
+	// Tarball installations have the configuration files in $DSE_HOME/resources/dse/conf
+	confDir := filepath.Clean(p.dseConfigHome)
+	if strings.HasSuffix(confDir, filepath.Join("resources", "dse", "conf")) {
+		return filepath.Dir(filepath.Dir(filepath.Dir(confDir)))
+	}
+	return installerDefault
+}
+
+func (p *ConfigParser) DseYaml() map[string]interface{} {
+	return p.yamls["dse-yaml"]
+}
+
 func (p *ConfigParser) ParseConfigs() error {
 	if err := p.parseYaml(p.cassConfigHome, cassYamlFilename); err != nil {
 		return err
@@ -54,6 +99,14 @@ func (p *ConfigParser) ParseConfigs() error {
 		return err
 	}
 
+	if err := p.parseTopology(); err != nil {
+		return err
+	}
+
+	if err := p.parseLogback(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -76,7 +129,9 @@ func VerifyFileExists(yamlPath string) (bool, error) {
 // https://docs.datastax.com/en/installing/docs/dsePackageLoc.html
 // https://docs.datastax.com/en/installing/docs/dseTarLoc.html
 func (p *ConfigParser) ParseConfigDirectories(cassConfDir, dseConfDir, cassandraHome string) error {
-	verifier := func(cassConfDir, dseConfDir string) error {
+	p.cassandraHome = cassandraHome
+
+	verifier := func(cassConfDir, dseConfDir string, requireDse bool) error {
 		foundCass, err := VerifyFileExists(filepath.Join(cassConfDir, "cassandra.yaml"))
 		if err != nil {
 			return err
@@ -86,7 +141,7 @@ func (p *ConfigParser) ParseConfigDirectories(cassConfDir, dseConfDir, cassandra
 			return err
 		}
 
-		if foundCass && foundDse {
+		if foundCass && (foundDse || !requireDse) {
 			p.dseConfigHome = dseConfDir
 			p.cassConfigHome = cassConfDir
 		}
@@ -98,7 +153,7 @@ func (p *ConfigParser) ParseConfigDirectories(cassConfDir, dseConfDir, cassandra
 		cassConfDir = filepath.Join(installDir, "resources", "cassandra", "conf")
 		dseConfDir = filepath.Join(installDir, "resources", "dse", "conf")
 
-		if err := verifier(cassConfDir, dseConfDir); err != nil {
+		if err := verifier(cassConfDir, dseConfDir, true); err != nil {
 			return err
 		}
 
@@ -106,8 +161,9 @@ func (p *ConfigParser) ParseConfigDirectories(cassConfDir, dseConfDir, cassandra
 	}
 
 	if cassConfDir != "" && dseConfDir != "" {
-		// If user gives override values, we will not try to detect any other directory
-		return verifier(cassConfDir, dseConfDir)
+		// If user gives override values, we will not try to detect any other directory. Cassandra installations
+		// have no dse.yaml
+		return verifier(cassConfDir, dseConfDir, false)
 	}
 
 	// Detect DSE_HOME / override home value for configs
@@ -123,7 +179,7 @@ func (p *ConfigParser) ParseConfigDirectories(cassConfDir, dseConfDir, cassandra
 		cassConfDir = "/etc/dse/cassandra/"
 		dseConfDir = "/etc/dse/"
 
-		if err := verifier(cassConfDir, dseConfDir); err != nil {
+		if err := verifier(cassConfDir, dseConfDir, true); err != nil {
 			return err
 		}
 	}
@@ -138,21 +194,38 @@ func (p *ConfigParser) ParseConfigDirectories(cassConfDir, dseConfDir, cassandra
 	return nil
 }
 
-var serverOptionName = regexp.MustCompile("^jvm.*-server.options$")
-
 func NewParser() *ConfigParser {
 	p := &ConfigParser{
-		jvmOptions: make(map[string]string),
-		yamls:      make(map[string]map[string]interface{}),
+		yamls:                 make(map[string]map[string]interface{}),
+		unsupportedJvmOptions: make(map[string][]string),
 	}
 
 	return p
 }
 
-func (c *ClusterMigrator) ParseConfigs(p *pterm.SpinnerPrinter) error {
+// newInstallationParser finds the configuration directories of the local node. If the node runs in a container, the
+// directories are copied from it and the overrides are ignored.
+func newInstallationParser(container *ContainerInstallation, cassConfDir, dseConfDir, cassandraHome string) (*ConfigParser, error) {
 	cfgParser := NewParser()
 
-	if err := cfgParser.ParseConfigDirectories(c.CassConfigOverride, c.DseConfigOverride, c.CassandraHome); err != nil {
+	if container != nil {
+		var err error
+		if cassConfDir, dseConfDir, cassandraHome, err = container.CopyConfigs(); err != nil {
+			return nil, err
+		}
+		cfgParser.container = container
+	}
+
+	if err := cfgParser.ParseConfigDirectories(cassConfDir, dseConfDir, cassandraHome); err != nil {
+		return nil, err
+	}
+
+	return cfgParser, nil
+}
+
+func (c *ClusterMigrator) ParseConfigs(p *pterm.SpinnerPrinter) error {
+	cfgParser, err := newInstallationParser(c.Container, c.CassConfigOverride, c.DseConfigOverride, c.CassandraHome)
+	if err != nil {
 		return err
 	}
 
@@ -161,8 +234,13 @@ func (c *ClusterMigrator) ParseConfigs(p *pterm.SpinnerPrinter) error {
 		return err
 	}
 
-	if len(confMap.Data) > 0 {
-		for k, conf := range confMap.Data {
+	storedFiles, err := configFiles(confMap)
+	if err != nil {
+		return err
+	}
+
+	if len(storedFiles) > 0 {
+		for k, conf := range storedFiles {
 			yamlFile := make(map[string]interface{})
 			if err := yaml.Unmarshal([]byte(conf), &yamlFile); err != nil {
 				return err
@@ -170,7 +248,8 @@ func (c *ClusterMigrator) ParseConfigs(p *pterm.SpinnerPrinter) error {
 			cfgParser.yamls[k] = yamlFile
 		}
 
-		return nil
+		p.UpdateText("Validating stored configuration")
+		return reportConfigIssues(validateConfigs(c.ServerType, c.ServerVersion, cfgParser.Yamls()))
 	}
 
 	p.UpdateText("Parsing all Cassandra configuration files")
@@ -179,6 +258,28 @@ func (c *ClusterMigrator) ParseConfigs(p *pterm.SpinnerPrinter) error {
 		return err
 	}
 
+	warnings, err := cfgParser.validateTopology(c.Datacenter, c.Rack, c.clusterConfigMap.NodeInfos)
+	if err != nil {
+		return err
+	}
+	for _, warning := range warnings {
+		pterm.Warning.Println(warning)
+	}
+
+	p.UpdateText("Storing keystores and keys to Kubernetes")
+	if err := storeImportSecrets(c.Client, c.Namespace, c.Datacenter, cfgParser); err != nil {
+		return err
+	}
+
+	p.UpdateText("Storing logging configuration to Kubernetes")
+	if err := storeLogbackConfig(c.Client, c.Namespace, c.Datacenter, cfgParser.logback); err != nil {
+		return err
+	}
+
+	for _, option := range cfgParser.UnsupportedJvmOptions() {
+		pterm.Warning.Printf("JVM option can not be carried over, %s\n", option)
+	}
+
 	p.UpdateText("Storing configs to Kubernetes")
 	_, err = c.storeConfigFiles(confMap, cfgParser.Yamls())
 	if err != nil {
@@ -186,6 +287,12 @@ func (c *ClusterMigrator) ParseConfigs(p *pterm.SpinnerPrinter) error {
 	}
 	pterm.Success.Println("Parsed and stored Cassandra configuration files to Kubernetes")
 
+	// The stored configuration can be fixed before any node is migrated
+	p.UpdateText("Validating stored configuration")
+	if err := reportConfigIssues(validateConfigs(c.ServerType, c.ServerVersion, cfgParser.Yamls())); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -206,66 +313,6 @@ func (c *ClusterMigrator) getOrCreateConfigMap() (*corev1.ConfigMap, error) {
 	return configFilesMap, nil
 }
 
-func (p *ConfigParser) parseJVMOptions() error {
-	// Parse here to the correct final format..
-
-	// Parse through all $CONF_DIRECTORY/jvm*-server.options and write them to a ConfigMap
-	return filepath.WalkDir(p.cassConfigHome, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			// Couldn't access the file for some reason
-			return err
-		}
-
-		if d.IsDir() {
-			// We're not processing subdirs
-			return nil
-		}
-		if serverOptionName.MatchString(d.Name()) {
-			// Parse this file and add it to the ConfigMap
-			f, err := os.Open(path)
-			if err != nil {
-				return err
-			}
-
-			defer f.Close()
-
-			var configData strings.Builder
-			parsedConfig := make(map[string]interface{})
-			additionalJvmOptions := make([]string, 0)
-
-			matcher := definitions.NewMetadataMatcher(d.Name())
-
-			// Remove the comment lines to reduce the ConfigMap size
-			scanner := bufio.NewScanner(f)
-			for scanner.Scan() {
-				line := scanner.Text()
-				if !strings.HasPrefix(line, "#") && (strings.HasPrefix(line, "-X") || strings.HasPrefix(line, "-D")) {
-					key, val, defaultVal := matcher.Parse(line)
-					if key == "" {
-						additionalJvmOptions = append(additionalJvmOptions, line)
-					} else if val != defaultVal {
-						parsedConfig[key] = val
-					}
-					configData.WriteString(line)
-				}
-			}
-
-			if err := scanner.Err(); err != nil {
-				return err
-			}
-
-			keyName := strings.ReplaceAll(d.Name(), ".", "-")
-
-			if len(additionalJvmOptions) > 0 {
-				parsedConfig["additional-jvm-options"] = additionalJvmOptions
-			}
-
-			p.yamls[keyName] = parsedConfig
-		}
-		return nil
-	})
-}
-
 func (p *ConfigParser) getJvmOptionsKey(jdkVersion string) string {
 	return strings.ReplaceAll(fmt.Sprintf("jvm%s-server.options", jdkVersion), ".", "-")
 }
@@ -298,8 +345,9 @@ func (p *ConfigParser) parseYaml(configDir, name string) error {
 }
 
 func (c *ClusterMigrator) storeConfigFiles(configFilesMap *corev1.ConfigMap, yamls map[string]map[string]interface{}) (*corev1.ConfigMap, error) {
-	if configFilesMap.Data == nil {
-		configFilesMap.Data = make(map[string]string)
+	files, err := configFiles(configFilesMap)
+	if err != nil {
+		return nil, err
 	}
 
 	for name, yamlConf := range yamls {
@@ -342,7 +390,11 @@ func (c *ClusterMigrator) storeConfigFiles(configFilesMap *corev1.ConfigMap, yam
 		}
 
 		// cass-config-builder uses "cassandra-yaml" and "dse-yaml"
-		configFilesMap.Data[name] = string(out)
+		files[name] = string(out)
+	}
+
+	if err := setConfigFiles(configFilesMap, files); err != nil {
+		return nil, err
 	}
 
 	if err := c.Client.Update(context.TODO(), configFilesMap); err != nil {
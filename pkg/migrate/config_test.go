@@ -19,9 +19,10 @@ func TestConfigParsing(t *testing.T) {
 	require.Equal(4, len(parser.yamls))
 	require.NoError(err)
 
-	require.Equal(1, len(parser.yamls["jvm11-server-options"]))
+	require.Equal(2, len(parser.yamls["jvm11-server-options"]))
+	require.Equal("CMS", parser.yamls["jvm11-server-options"]["garbage_collector"])
 	addJvmOptions := parser.yamls["jvm11-server-options"]["additional-jvm-options"].([]string)
-	require.Equal(12, len(addJvmOptions))
+	require.Equal(11, len(addJvmOptions))
 }
 
 func TestParseDataPaths(t *testing.T) {
@@ -0,0 +1,486 @@
+package migrate
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	kubeexec "k8s.io/kubectl/pkg/cmd/exec"
+)
+
+const (
+	RuntimeDocker = "docker"
+	RuntimePodman = "podman"
+	// RuntimeKubernetes is a Cassandra container of a pod, nodetool and file copies are run through the Kubernetes API
+	RuntimeKubernetes = "kubernetes"
+
+	// Official Cassandra images set CASSANDRA_CONF, DSE images DSE_HOME
+	defaultContainerConfDir = "/etc/cassandra"
+)
+
+var containerRuntimes = []string{RuntimeDocker, RuntimePodman}
+
+// ContainerMount is a mount of the container as reported by docker inspect and podman inspect
+type ContainerMount struct {
+	Type        string `json:"Type"`
+	Source      string `json:"Source"`
+	Destination string `json:"Destination"`
+}
+
+type containerInspect struct {
+	ID     string `json:"Id"`
+	Name   string `json:"Name"`
+	Config struct {
+		Image string   `json:"Image"`
+		Env   []string `json:"Env"`
+	} `json:"Config"`
+	State struct {
+		Running bool `json:"Running"`
+	} `json:"State"`
+	Mounts []ContainerMount `json:"Mounts"`
+}
+
+// ContainerInstallation is a Cassandra or DSE node running in a Docker or Podman container on the host. Nodetool is run
+// through the container runtime, the configuration is copied from the container and the data directories must be bind
+// mounts, which are mounted to the pod. The Cassandra container of a Kubernetes pod is accessed through the Kubernetes
+// API instead.
+type ContainerInstallation struct {
+	Runtime string
+	ID      string
+	Name    string
+	Image   string
+	Env     map[string]string
+	Mounts  []ContainerMount
+
+	// root has the files copied from the container, in the same paths as inside the container
+	root string
+
+	// execOptions are set for containers of a Kubernetes pod
+	execOptions *kubeexec.ExecOptions
+}
+
+func (c *ContainerInstallation) String() string {
+	return fmt.Sprintf("%s:%s", c.Runtime, c.Name)
+}
+
+// parseContainerInspect parses the output of docker inspect or podman inspect
+func parseContainerInspect(runtime string, output []byte) ([]*ContainerInstallation, error) {
+	inspected := make([]containerInspect, 0)
+	if err := json.Unmarshal(output, &inspected); err != nil {
+		return nil, fmt.Errorf("unable to parse %s inspect output: %w", runtime, err)
+	}
+
+	containers := make([]*ContainerInstallation, 0, len(inspected))
+	for _, i := range inspected {
+		if !i.State.Running {
+			continue
+		}
+		containers = append(containers, &ContainerInstallation{
+			Runtime: runtime,
+			ID:      i.ID,
+			Name:    strings.TrimPrefix(i.Name, "/"),
+			Image:   i.Config.Image,
+			Env:     parseEnv(i.Config.Env),
+			Mounts:  i.Mounts,
+		})
+	}
+
+	return containers, nil
+}
+
+// parseEnv parses KEY=value lines
+func parseEnv(lines []string) map[string]string {
+	env := make(map[string]string, len(lines))
+	for _, e := range lines {
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) == 2 {
+			env[parts[0]] = parts[1]
+		}
+	}
+	return env
+}
+
+// NewPodInstallation returns the Cassandra container of a running pod. The environment is read from the running
+// container, as it includes the variables set by the image.
+func NewPodInstallation(execOptions *kubeexec.ExecOptions, pod *corev1.Pod, containerName string) (*ContainerInstallation, error) {
+	image := ""
+	for _, container := range pod.Spec.Containers {
+		if container.Name == containerName {
+			image = container.Image
+		}
+	}
+	if image == "" {
+		return nil, fmt.Errorf("pod %s has no container %s", pod.Name, containerName)
+	}
+
+	options := *execOptions
+	options.Namespace = pod.Namespace
+	options.PodName = pod.Name
+	options.ContainerName = containerName
+
+	c := &ContainerInstallation{
+		Runtime:     RuntimeKubernetes,
+		ID:          pod.Name,
+		Name:        pod.Name,
+		Image:       image,
+		execOptions: &options,
+	}
+
+	out, err := c.podExec("env")
+	if err != nil {
+		return nil, err
+	}
+	c.Env = parseEnv(strings.Split(string(out), "\n"))
+
+	return c, nil
+}
+
+// podExec runs the command in the pod's container and returns the standard output
+func (c *ContainerInstallation) podExec(command ...string) ([]byte, error) {
+	return c.podExecWithInput(nil, command...)
+}
+
+// podExecWithInput runs the command in the pod's container with the input streamed to its standard input
+func (c *ContainerInstallation) podExecWithInput(input io.Reader, command ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+
+	options := *c.execOptions
+	options.IOStreams = genericclioptions.IOStreams{In: &bytes.Buffer{}, Out: &stdout, ErrOut: &stderr}
+	options.Stdin = input != nil
+	if input != nil {
+		options.IOStreams.In = input
+	}
+	options.TTY = false
+	options.Command = command
+
+	if err := options.Run(); err != nil {
+		return nil, fmt.Errorf("unable to run %s in %s: %v %s", command[0], c, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// isCassandraContainer checks if the container runs Cassandra or DSE
+func isCassandraContainer(c *ContainerInstallation) bool {
+	for _, key := range []string{"CASSANDRA_HOME", "CASSANDRA_CONF", "CASSANDRA_CONF_DIR", "DSE_HOME"} {
+		if _, found := c.Env[key]; found {
+			return true
+		}
+	}
+	image := strings.ToLower(c.Image)
+	return strings.Contains(image, "cassandra") || strings.Contains(image, "dse")
+}
+
+// DetectContainer finds the running Cassandra container. If nameOrID is empty, every running container of the runtimes
+// is inspected and exactly one must run Cassandra. An empty runtime tries Docker and Podman.
+func DetectContainer(runtime, nameOrID string) (*ContainerInstallation, error) {
+	runtimes := containerRuntimes
+	if runtime != "" {
+		runtimes = []string{runtime}
+	}
+
+	candidates := make([]*ContainerInstallation, 0)
+	for _, rt := range runtimes {
+		if _, err := exec.LookPath(rt); err != nil {
+			if runtime != "" {
+				return nil, fmt.Errorf("container runtime %s was not found", runtime)
+			}
+			continue
+		}
+
+		ids := []string{nameOrID}
+		if nameOrID == "" {
+			out, err := exec.Command(rt, "ps", "-q").Output()
+			if err != nil {
+				return nil, fmt.Errorf("unable to list %s containers: %w", rt, err)
+			}
+			ids = strings.Fields(string(out))
+			if len(ids) == 0 {
+				continue
+			}
+		}
+
+		out, err := exec.Command(rt, append([]string{"inspect"}, ids...)...).Output()
+		if err != nil {
+			if nameOrID != "" {
+				// Try the next runtime, the container could be managed by it
+				continue
+			}
+			return nil, fmt.Errorf("unable to inspect %s containers: %w", rt, err)
+		}
+
+		containers, err := parseContainerInspect(rt, out)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range containers {
+			if nameOrID != "" || isCassandraContainer(c) {
+				candidates = append(candidates, c)
+			}
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		if nameOrID != "" {
+			return nil, fmt.Errorf("running container %s was not found", nameOrID)
+		}
+		return nil, fmt.Errorf("no running Cassandra container was found")
+	case 1:
+		return candidates[0], nil
+	default:
+		names := make([]string, 0, len(candidates))
+		for _, c := range candidates {
+			names = append(names, c.String())
+		}
+		return nil, fmt.Errorf("found multiple Cassandra containers %s, select one with --container", strings.Join(names, ", "))
+	}
+}
+
+// Home returns the installation directory inside the container. Bitnami images set CASSANDRA_BASE_DIR.
+func (c *ContainerInstallation) Home() string {
+	for _, key := range []string{"DSE_HOME", "CASSANDRA_HOME", "CASSANDRA_BASE_DIR"} {
+		if home := c.Env[key]; home != "" {
+			return home
+		}
+	}
+	return ""
+}
+
+// NodetoolPath returns the nodetool executable inside the container
+func (c *ContainerInstallation) NodetoolPath() string {
+	if home := c.Home(); home != "" {
+		return filepath.Join(home, "bin", "nodetool")
+	}
+	return "nodetool"
+}
+
+// Nodetool runs nodetool inside the container
+func (c *ContainerInstallation) Nodetool(args ...string) (string, error) {
+	if c.execOptions != nil {
+		out, err := c.podExec(append([]string{c.NodetoolPath()}, args...)...)
+		return string(out), err
+	}
+	return execNodetool(c.Runtime, append([]string{"exec", c.ID, c.NodetoolPath()}, args...)...)
+}
+
+// Cqlsh runs cqlsh inside the container with the cqlshrc passed through the standard input
+func (c *ContainerInstallation) Cqlsh(cqlshrc string, args ...string) (string, error) {
+	cqlshPath := "cqlsh"
+	if home := c.Home(); home != "" {
+		cqlshPath = filepath.Join(home, "bin", "cqlsh")
+	}
+	command := append([]string{"sh", "-c", cqlshWithConfigScript, cqlshPath}, args...)
+	if c.execOptions != nil {
+		out, err := c.podExecWithInput(strings.NewReader(cqlshrc), command...)
+		return string(out), err
+	}
+	return execCqlsh(cqlshrc, c.Runtime, append([]string{"exec", "-i", c.ID}, command...)...)
+}
+
+// configDirectories returns the cassandra.yaml and dse.yaml directories inside the container
+func (c *ContainerInstallation) configDirectories() (string, string) {
+	if dseHome := c.Env["DSE_HOME"]; dseHome != "" {
+		return filepath.Join(dseHome, "resources", "cassandra", "conf"), filepath.Join(dseHome, "resources", "dse", "conf")
+	}
+	for _, key := range []string{"CASSANDRA_CONF", "CASSANDRA_CONF_DIR"} {
+		if confDir := c.Env[key]; confDir != "" {
+			return confDir, confDir
+		}
+	}
+	if home := c.Home(); home != "" {
+		return filepath.Join(home, "conf"), filepath.Join(home, "conf")
+	}
+	return defaultContainerConfDir, defaultContainerConfDir
+}
+
+// CopyConfigs copies the configuration directories from the container. The returned directories and installation
+// directory are used in place of the local ones.
+func (c *ContainerInstallation) CopyConfigs() (string, string, string, error) {
+	cassConfDir, dseConfDir := c.configDirectories()
+
+	localCass, err := c.LocalPath(cassConfDir)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	localDse := localCass
+	if dseConfDir != cassConfDir {
+		if localDse, err = c.LocalPath(dseConfDir); err != nil {
+			return "", "", "", err
+		}
+	}
+
+	home := ""
+	if c.Home() != "" {
+		home = filepath.Join(c.root, c.Home())
+	}
+
+	return localCass, localDse, home, nil
+}
+
+// sortedMounts returns the bind mounts, longest destination first
+func (c *ContainerInstallation) sortedMounts() []ContainerMount {
+	mounts := make([]ContainerMount, 0, len(c.Mounts))
+	for _, mount := range c.Mounts {
+		if mount.Type == "" || mount.Type == "bind" {
+			mounts = append(mounts, mount)
+		}
+	}
+	sort.Slice(mounts, func(i, j int) bool {
+		return len(mounts[i].Destination) > len(mounts[j].Destination)
+	})
+	return mounts
+}
+
+// HostPath returns the host path of a path inside the container. The path must be inside a bind mount.
+func (c *ContainerInstallation) HostPath(path string) (string, error) {
+	path = filepath.Clean(path)
+	for _, mount := range c.sortedMounts() {
+		destination := filepath.Clean(mount.Destination)
+		if path == destination {
+			return mount.Source, nil
+		}
+		if strings.HasPrefix(path, destination+string(filepath.Separator)) {
+			return filepath.Join(mount.Source, strings.TrimPrefix(path, destination)), nil
+		}
+	}
+	return "", fmt.Errorf("%s is not bind mounted from the host in container %s, only bind mounted directories can be migrated", path, c)
+}
+
+// LocalPath returns a path on the host for a file or directory inside the container. Bind mounted paths are read from
+// the host, others are copied from the container. Paths that were already resolved to the copy are accepted as well.
+func (c *ContainerInstallation) LocalPath(path string) (string, error) {
+	if c.root == "" {
+		root, err := os.MkdirTemp("", "k8ssandra-import-")
+		if err != nil {
+			return "", err
+		}
+		c.root = root
+	}
+
+	if strings.HasPrefix(path, c.root+string(filepath.Separator)) {
+		path = strings.TrimPrefix(path, c.root)
+	}
+
+	if hostPath, err := c.HostPath(path); err == nil {
+		return hostPath, nil
+	}
+
+	local := filepath.Join(c.root, path)
+	if _, err := os.Stat(local); err == nil {
+		return local, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(local), 0700); err != nil {
+		return "", err
+	}
+
+	if c.execOptions != nil {
+		return local, c.copyFromPod(path, local)
+	}
+
+	if out, err := runCommand(c.Runtime, "cp", fmt.Sprintf("%s:%s", c.ID, path), local); err != nil {
+		if strings.Contains(strings.ToLower(out), "no such file") || strings.Contains(strings.ToLower(out), "could not find") {
+			return local, os.ErrNotExist
+		}
+		return "", fmt.Errorf("unable to copy %s from container %s: %v %s", path, c, err, out)
+	}
+
+	return local, nil
+}
+
+// copyFromPod copies the file or directory from the pod with tar, the same way kubectl cp does
+func (c *ContainerInstallation) copyFromPod(path, local string) error {
+	out, err := c.podExec("tar", "cf", "-", "-C", filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "no such file") {
+			return os.ErrNotExist
+		}
+		return err
+	}
+	return extractTar(bytes.NewReader(out), filepath.Dir(local))
+}
+
+// extractTar extracts the regular files and directories of the archive to dir. Entries outside of dir are rejected.
+func extractTar(r io.Reader, dir string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dir, header.Name)
+		if target != filepath.Clean(dir) && !strings.HasPrefix(target, filepath.Clean(dir)+string(filepath.Separator)) {
+			return fmt.Errorf("archive entry %s is outside of the target directory", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, tr); err != nil {
+				f.Close()
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// DisableRestart prevents the runtime from restarting the container after Cassandra has been stopped
+func (c *ContainerInstallation) DisableRestart() error {
+	if out, err := runCommand(c.Runtime, "update", "--restart=no", c.ID); err != nil {
+		return fmt.Errorf("unable to disable the restart policy of container %s: %v %s", c, err, out)
+	}
+	return nil
+}
+
+// Stop stops the container after Cassandra has been stopped in it
+func (c *ContainerInstallation) Stop() error {
+	if out, err := runCommand(c.Runtime, "stop", c.ID); err != nil {
+		return fmt.Errorf("unable to stop container %s: %v %s", c, err, out)
+	}
+	return nil
+}
+
+// Cleanup removes the files copied from the container
+func (c *ContainerInstallation) Cleanup() error {
+	if c.root == "" {
+		return nil
+	}
+	return os.RemoveAll(c.root)
+}
+
+// runNodetool runs nodetool in the container if the node runs in one, otherwise from the local installation
+func runNodetool(container *ContainerInstallation, nodetoolPath string, args ...string) (string, error) {
+	if container != nil {
+		return container.Nodetool(args...)
+	}
+	return execNodetool(nodetoolPath, args...)
+}
@@ -0,0 +1,172 @@
+package migrate
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const dockerInspectOutput = `[
+  {
+    "Id": "8f2d1c",
+    "Name": "/cassandra1",
+    "State": {"Running": true},
+    "Config": {
+      "Image": "cassandra:4.0.5",
+      "Env": ["CASSANDRA_HOME=/opt/cassandra", "CASSANDRA_CONF=/etc/cassandra", "PATH=/usr/bin"]
+    },
+    "Mounts": [
+      {"Type": "bind", "Source": "/data/cassandra", "Destination": "/var/lib/cassandra"},
+      {"Type": "bind", "Source": "/data/commitlog", "Destination": "/var/lib/cassandra/commitlog"},
+      {"Type": "volume", "Source": "/var/lib/docker/volumes/logs/_data", "Destination": "/var/log/cassandra"}
+    ]
+  },
+  {
+    "Id": "a41b07",
+    "Name": "/nginx",
+    "State": {"Running": true},
+    "Config": {"Image": "nginx:1.21", "Env": ["PATH=/usr/bin"]}
+  },
+  {
+    "Id": "c93e55",
+    "Name": "/cassandra-old",
+    "State": {"Running": false},
+    "Config": {"Image": "cassandra:3.11", "Env": []}
+  }
+]`
+
+func TestParseContainerInspect(t *testing.T) {
+	require := require.New(t)
+
+	containers, err := parseContainerInspect(RuntimeDocker, []byte(dockerInspectOutput))
+	require.NoError(err)
+	require.Len(containers, 2)
+
+	cassandra := containers[0]
+	require.Equal("cassandra1", cassandra.Name)
+	require.Equal("docker:cassandra1", cassandra.String())
+	require.True(isCassandraContainer(cassandra))
+	require.False(isCassandraContainer(containers[1]))
+
+	require.Equal("/opt/cassandra", cassandra.Home())
+	require.Equal("/opt/cassandra/bin/nodetool", cassandra.NodetoolPath())
+
+	cassConf, dseConf := cassandra.configDirectories()
+	require.Equal("/etc/cassandra", cassConf)
+	require.Equal("/etc/cassandra", dseConf)
+
+	dse := &ContainerInstallation{Env: map[string]string{"DSE_HOME": "/opt/dse"}}
+	cassConf, dseConf = dse.configDirectories()
+	require.Equal("/opt/dse/resources/cassandra/conf", cassConf)
+	require.Equal("/opt/dse/resources/dse/conf", dseConf)
+}
+
+func TestContainerHostPath(t *testing.T) {
+	require := require.New(t)
+
+	containers, err := parseContainerInspect(RuntimeDocker, []byte(dockerInspectOutput))
+	require.NoError(err)
+	cassandra := containers[0]
+
+	path, err := cassandra.HostPath("/var/lib/cassandra/data")
+	require.NoError(err)
+	require.Equal("/data/cassandra/data", path)
+
+	// The longest mount wins
+	path, err = cassandra.HostPath("/var/lib/cassandra/commitlog")
+	require.NoError(err)
+	require.Equal("/data/commitlog", path)
+
+	// Named volumes are not bind mounts
+	_, err = cassandra.HostPath("/var/log/cassandra")
+	require.Error(err)
+
+	_, err = cassandra.HostPath("/var/lib/cassandra2")
+	require.Error(err)
+}
+
+func TestContainerLocalPath(t *testing.T) {
+	require := require.New(t)
+
+	tempDir := t.TempDir()
+	c := &ContainerInstallation{
+		Runtime: RuntimeDocker,
+		Name:    "cassandra1",
+		Mounts:  []ContainerMount{{Type: "bind", Source: tempDir, Destination: "/etc/cassandra"}},
+		root:    filepath.Join(tempDir, "root"),
+	}
+	require.NoError(os.MkdirAll(filepath.Join(c.root, "opt", "cassandra"), 0755))
+	require.NoError(os.WriteFile(filepath.Join(c.root, "opt", "cassandra", ".keystore"), []byte("keystore"), 0644))
+
+	// Bind mounted files are read from the host
+	path, err := c.LocalPath("/etc/cassandra/cassandra.yaml")
+	require.NoError(err)
+	require.Equal(filepath.Join(tempDir, "cassandra.yaml"), path)
+
+	// Already copied files are not copied again, also when resolved from the copied installation directory
+	path, err = c.LocalPath("/opt/cassandra/.keystore")
+	require.NoError(err)
+	require.Equal(filepath.Join(c.root, "opt", "cassandra", ".keystore"), path)
+
+	path, err = c.LocalPath(filepath.Join(c.root, "opt", "cassandra", ".keystore"))
+	require.NoError(err)
+	require.Equal(filepath.Join(c.root, "opt", "cassandra", ".keystore"), path)
+}
+
+func TestBitnamiContainerDirectories(t *testing.T) {
+	require := require.New(t)
+
+	c := &ContainerInstallation{
+		Runtime: RuntimeKubernetes,
+		Name:    "cassandra-0",
+		Env: parseEnv([]string{
+			"CASSANDRA_BASE_DIR=/opt/bitnami/cassandra",
+			"CASSANDRA_CONF_DIR=/opt/bitnami/cassandra/conf",
+			"BITNAMI_APP_NAME=cassandra",
+			"MALFORMED",
+		}),
+	}
+
+	require.Len(c.Env, 3)
+	require.Equal("/opt/bitnami/cassandra", c.Home())
+	require.Equal("/opt/bitnami/cassandra/bin/nodetool", c.NodetoolPath())
+
+	cassConf, dseConf := c.configDirectories()
+	require.Equal("/opt/bitnami/cassandra/conf", cassConf)
+	require.Equal(cassConf, dseConf)
+	require.True(isCassandraContainer(c))
+}
+
+func TestExtractTar(t *testing.T) {
+	require := require.New(t)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	require.NoError(tw.WriteHeader(&tar.Header{Name: "conf/", Typeflag: tar.TypeDir, Mode: 0755}))
+	content := []byte("cluster_name: Test Cluster\n")
+	require.NoError(tw.WriteHeader(&tar.Header{Name: "conf/cassandra.yaml", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))}))
+	_, err := tw.Write(content)
+	require.NoError(err)
+	require.NoError(tw.WriteHeader(&tar.Header{Name: "conf/link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"}))
+	require.NoError(tw.Close())
+
+	dir := t.TempDir()
+	require.NoError(extractTar(&buf, dir))
+
+	extracted, err := os.ReadFile(filepath.Join(dir, "conf", "cassandra.yaml"))
+	require.NoError(err)
+	require.Equal(content, extracted)
+
+	_, err = os.Lstat(filepath.Join(dir, "conf", "link"))
+	require.True(os.IsNotExist(err))
+
+	buf.Reset()
+	tw = tar.NewWriter(&buf)
+	require.NoError(tw.WriteHeader(&tar.Header{Name: "../escape", Typeflag: tar.TypeReg, Mode: 0644}))
+	require.NoError(tw.Close())
+	require.Error(extractTar(&buf, dir))
+}
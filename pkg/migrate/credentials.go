@@ -0,0 +1,234 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/apis/cassandra/v1beta1"
+	"github.com/pterm/pterm"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// credentialsLabel marks the Secrets created from the imported credentials, the value is the role type
+	credentialsLabel           = "migrate.k8ssandra.io/credentials"
+	credentialsDatacenterLabel = "migrate.k8ssandra.io/datacenter"
+
+	credentialsSuperuser = "superuser"
+
+	// cqlshWithConfigScript writes the cqlshrc from stdin to a private temporary file and runs cqlsh ($0) with it, so
+	// that the credentials are not visible in the process list, the container runtime or the pod exec request
+	cqlshWithConfigScript = `f=$(mktemp) && trap 'rm -f "$f"' EXIT && cat > "$f" && "$0" --cqlshrc "$f" "$@"`
+)
+
+func superuserSecretName(datacenter string) string {
+	return importSecretName(datacenter, "superuser")
+}
+
+// CredentialsImporter verifies the existing superuser of the cluster against the local node and stores it as the
+// Secret used by the CassandraDatacenter. cass-operator would otherwise generate a new superuser.
+type CredentialsImporter struct {
+	client.Client
+	Namespace  string
+	Datacenter string
+
+	NodetoolPath  string
+	CassandraHome string
+	Container     *ContainerInstallation
+
+	// Host is the address cqlsh connects to, read from the cassandra.yaml if not set
+	Host string
+}
+
+func NewCredentialsImporter(cli client.Client, namespace, datacenter string) *CredentialsImporter {
+	return &CredentialsImporter{
+		Client:     cli,
+		Namespace:  namespace,
+		Datacenter: datacenter,
+	}
+}
+
+// Import verifies the superuser over CQL and stores it to the superuser Secret
+func (i *CredentialsImporter) Import(p *pterm.SpinnerPrinter, superuser, password string) error {
+	_, files, err := fetchConfigFiles(i.Client, i.Namespace, i.Datacenter)
+	if err != nil {
+		return err
+	}
+
+	cassYaml := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(files[cassYamlKey]), cassYaml); err != nil {
+		return err
+	}
+
+	if !usesAuthenticator(cassYaml) {
+		return fmt.Errorf("authentication is not enabled in datacenter %s, there are no credentials to import", i.Datacenter)
+	}
+
+	if i.Host == "" {
+		i.Host = cqlshHost(cassYaml)
+	}
+	connection := cqlshConnection(i.Host, cassYaml)
+
+	config, err := cqlshrc(superuser, password)
+	if err != nil {
+		return err
+	}
+
+	p.UpdateText(fmt.Sprintf("Verifying superuser %s", superuser))
+	query := fmt.Sprintf("SELECT is_superuser FROM system_auth.roles WHERE role = '%s'", strings.ReplaceAll(superuser, "'", "''"))
+	output, err := i.cqlsh(config, append(connection, "-e", query)...)
+	if err != nil {
+		return fmt.Errorf("unable to log in as %s: %w", superuser, err)
+	}
+	if !parseSuperuserOutput(output) {
+		return fmt.Errorf("role %s is not a superuser", superuser)
+	}
+	pterm.Success.Printf("Verified superuser %s\n", superuser)
+
+	p.UpdateText("Storing credentials to Secret")
+	return i.storeCredentials(superuserSecretName(i.Datacenter), credentialsSuperuser, superuser, password)
+}
+
+// cqlshrc returns a cqlshrc with the credentials. cqlsh reads it without interpolation, only line breaks can not be
+// represented.
+func cqlshrc(username, password string) (string, error) {
+	if strings.ContainsAny(username+password, "\r\n") {
+		return "", fmt.Errorf("credentials of %s can not contain line breaks", username)
+	}
+	return fmt.Sprintf("[authentication]\nusername = %s\npassword = %s\n", username, password), nil
+}
+
+// storeCredentials creates the Secret in the format cass-operator uses, an existing Secret is replaced with the
+// verified credentials
+func (i *CredentialsImporter) storeCredentials(name, roleType, username, password string) error {
+	secret := &corev1.Secret{}
+	secretKey := types.NamespacedName{Name: name, Namespace: i.Namespace}
+	err := i.Client.Get(context.TODO(), secretKey, secret)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	found := err == nil
+
+	secret.Name = secretKey.Name
+	secret.Namespace = secretKey.Namespace
+	if secret.Labels == nil {
+		secret.Labels = make(map[string]string)
+	}
+	secret.Labels[credentialsLabel] = roleType
+	secret.Labels[credentialsDatacenterLabel] = cassdcapi.CleanupForKubernetes(i.Datacenter)
+	secret.Data = map[string][]byte{
+		"username": []byte(username),
+		"password": []byte(password),
+	}
+
+	if found {
+		return i.Client.Update(context.TODO(), secret)
+	}
+	return i.Client.Create(context.TODO(), secret)
+}
+
+// cqlsh runs cqlsh with the cqlshrc in the container if the node runs in one, otherwise from the local installation
+func (i *CredentialsImporter) cqlsh(cqlshrc string, args ...string) (string, error) {
+	if i.Container != nil {
+		return i.Container.Cqlsh(cqlshrc, args...)
+	}
+	return execCqlsh(cqlshrc, "sh", append([]string{"-c", cqlshWithConfigScript, cqlshPath(i.CassandraHome, i.NodetoolPath)}, args...)...)
+}
+
+// execCqlsh runs the command with the cqlshrc in stdin
+func execCqlsh(cqlshrc, command string, args ...string) (string, error) {
+	cmd := exec.Command(command, args...)
+	cmd.Stdin = strings.NewReader(cqlshrc)
+	out, err := cmd.Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("%s", strings.TrimSpace(string(ee.Stderr)))
+		}
+		return "", err
+	}
+	return string(out), nil
+}
+
+// cqlshPath finds cqlsh next to nodetool or in the installation directory, $PATH is used otherwise
+func cqlshPath(cassandraHome, nodetoolPath string) string {
+	candidates := make([]string, 0, 2)
+	if nodetoolPath != "" {
+		candidates = append(candidates, filepath.Join(filepath.Dir(nodetoolPath), "cqlsh"))
+	}
+	if cassandraHome != "" {
+		candidates = append(candidates, filepath.Join(cassandraHome, "bin", "cqlsh"))
+	}
+
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return "cqlsh"
+}
+
+// usesAuthenticator checks if the cassandra.yaml enables an authenticator
+func usesAuthenticator(cassYaml map[string]interface{}) bool {
+	authenticator, _ := cassYaml["authenticator"].(string)
+	return authenticator != "" && !strings.HasSuffix(authenticator, "AllowAllAuthenticator")
+}
+
+// cqlshHost returns the address the node accepts CQL connections from
+func cqlshHost(cassYaml map[string]interface{}) string {
+	for _, key := range []string{"rpc_address", "native_transport_address", "broadcast_rpc_address"} {
+		if address, ok := cassYaml[key].(string); ok && address != "" && address != "0.0.0.0" {
+			return address
+		}
+	}
+	return "127.0.0.1"
+}
+
+// cqlshConnection returns the cqlsh arguments to connect to the host with the node's native transport settings
+func cqlshConnection(host string, cassYaml map[string]interface{}) []string {
+	args := make([]string, 0, 3)
+	if options, ok := cassYaml["client_encryption_options"].(map[string]interface{}); ok && encryptionEnabled(options) {
+		args = append(args, "--ssl")
+	}
+	args = append(args, host)
+	if port, found := cassYaml["native_transport_port"]; found {
+		args = append(args, fmt.Sprintf("%v", port))
+	}
+	return args
+}
+
+// parseSuperuserOutput parses the is_superuser column from cqlsh's tabular output
+func parseSuperuserOutput(output string) bool {
+	separator := false
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "---") {
+			separator = true
+			continue
+		}
+		if separator && line != "" {
+			return strings.EqualFold(line, "true")
+		}
+	}
+	return false
+}
+
+// importedSuperuser returns the superuser Secret imported for the datacenter, empty if the credentials were not imported
+func importedSuperuser(cli client.Client, namespace, datacenter string) (string, error) {
+	secrets := &corev1.SecretList{}
+	if err := cli.List(context.TODO(), secrets, client.InNamespace(namespace), client.MatchingLabels{credentialsDatacenterLabel: cassdcapi.CleanupForKubernetes(datacenter), credentialsLabel: credentialsSuperuser}); err != nil {
+		return "", err
+	}
+
+	for _, secret := range secrets.Items {
+		return secret.Name, nil
+	}
+	return "", nil
+}
@@ -0,0 +1,81 @@
+package migrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSuperuserOutput(t *testing.T) {
+	require := require.New(t)
+
+	output := `
+ is_superuser
+--------------
+         True
+
+(1 rows)
+`
+	require.True(parseSuperuserOutput(output))
+
+	output = `
+ is_superuser
+--------------
+        False
+
+(1 rows)
+`
+	require.False(parseSuperuserOutput(output))
+
+	output = `
+ is_superuser
+--------------
+
+(0 rows)
+`
+	require.False(parseSuperuserOutput(output))
+}
+
+func TestCqlshConnection(t *testing.T) {
+	require := require.New(t)
+
+	cassYaml := map[string]interface{}{
+		"authenticator":         "PasswordAuthenticator",
+		"rpc_address":           "0.0.0.0",
+		"broadcast_rpc_address": "10.0.1.10",
+		"native_transport_port": 9142,
+		"client_encryption_options": map[string]interface{}{
+			"enabled": true,
+		},
+	}
+
+	require.True(usesAuthenticator(cassYaml))
+	require.Equal("10.0.1.10", cqlshHost(cassYaml))
+	require.Equal([]string{"--ssl", "10.0.1.10", "9142"}, cqlshConnection(cqlshHost(cassYaml), cassYaml))
+
+	cassYaml = map[string]interface{}{
+		"authenticator": "org.apache.cassandra.auth.AllowAllAuthenticator",
+	}
+	require.False(usesAuthenticator(cassYaml))
+	require.Equal("127.0.0.1", cqlshHost(cassYaml))
+	require.Equal([]string{"127.0.0.1"}, cqlshConnection(cqlshHost(cassYaml), cassYaml))
+}
+
+func TestCqlshrc(t *testing.T) {
+	require := require.New(t)
+
+	config, err := cqlshrc("admin", "p%ss=word")
+	require.NoError(err)
+	require.Equal("[authentication]\nusername = admin\npassword = p%ss=word\n", config)
+
+	_, err = cqlshrc("admin", "pass\nword")
+	require.Error(err)
+}
+
+func TestImportSecretNames(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal("dc1-superuser", superuserSecretName("dc1"))
+	require.Equal("east-dc-superuser", superuserSecretName("East_DC 1"))
+	require.Equal("east-dc-keystore", importSecretName("East_DC 1", "keystore"))
+}
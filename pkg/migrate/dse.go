@@ -0,0 +1,247 @@
+package migrate
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/apis/cassandra/v1beta1"
+)
+
+const (
+	dseDefaultsFile = "/etc/default/dse"
+
+	SystemKeyVolumeName = "dse-system-keys"
+	SystemKeyMountPath  = "/etc/dse/system-keys"
+	KerberosVolumeName  = "dse-kerberos"
+	KerberosMountPath   = "/etc/dse/kerberos"
+
+	krb5ConfPath = "/etc/krb5.conf"
+	krb5ConfKey  = "krb5.conf"
+)
+
+func systemKeySecretName(datacenter string) string {
+	return importSecretName(datacenter, "system-keys")
+}
+
+func kerberosSecretName(datacenter string) string {
+	return importSecretName(datacenter, "kerberos")
+}
+
+// parseDseDefaults reads the workload flags from the /etc/default/dse used by the package installations
+func parseDseDefaults(path string) (*cassdcapi.DseWorkloads, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	defer f.Close()
+
+	workloads := &cassdcapi.DseWorkloads{}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		setting := strings.SplitN(line, "=", 2)
+		if len(setting) < 2 {
+			continue
+		}
+		enabled := strings.Trim(setting[1], `"' `) == "1"
+		switch strings.TrimSpace(setting[0]) {
+		case "SPARK_ENABLED":
+			workloads.AnalyticsEnabled = enabled
+		case "SOLR_ENABLED":
+			workloads.SearchEnabled = enabled
+		case "GRAPH_ENABLED":
+			workloads.GraphEnabled = enabled
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return workloads, nil
+}
+
+// parseGossipWorkloads reads the workloads from the DSE information in the gossip's X_11_PADDING field
+func parseGossipWorkloads(dseInfo map[string]interface{}) *cassdcapi.DseWorkloads {
+	workloads := &cassdcapi.DseWorkloads{}
+
+	for _, key := range []string{"workload", "workloads"} {
+		if value, ok := dseInfo[key].(string); ok {
+			workloads.AnalyticsEnabled = workloads.AnalyticsEnabled || strings.Contains(value, "Analytics")
+			workloads.SearchEnabled = workloads.SearchEnabled || strings.Contains(value, "Search")
+			workloads.GraphEnabled = workloads.GraphEnabled || strings.Contains(value, "Graph")
+		}
+	}
+
+	if graph, ok := dseInfo["graph"].(bool); ok && graph {
+		workloads.GraphEnabled = true
+	}
+
+	return workloads
+}
+
+// parseDseYamlWorkloads detects the workloads from the dse.yaml settings. DSEFS is only started on analytics nodes.
+func parseDseYamlWorkloads(dseYaml map[string]interface{}) *cassdcapi.DseWorkloads {
+	workloads := &cassdcapi.DseWorkloads{}
+
+	if dsefs, ok := dseYaml["dsefs_options"].(map[string]interface{}); ok {
+		if enabled, ok := dsefs["enabled"].(bool); ok && enabled {
+			workloads.AnalyticsEnabled = true
+		}
+	}
+
+	return workloads
+}
+
+// mergeDseWorkloads combines the workloads detected from different sources. nil is returned if no workload is enabled.
+func mergeDseWorkloads(sources ...*cassdcapi.DseWorkloads) *cassdcapi.DseWorkloads {
+	workloads := &cassdcapi.DseWorkloads{}
+	for _, source := range sources {
+		if source == nil {
+			continue
+		}
+		workloads.AnalyticsEnabled = workloads.AnalyticsEnabled || source.AnalyticsEnabled
+		workloads.SearchEnabled = workloads.SearchEnabled || source.SearchEnabled
+		workloads.GraphEnabled = workloads.GraphEnabled || source.GraphEnabled
+	}
+
+	if !workloads.AnalyticsEnabled && !workloads.SearchEnabled && !workloads.GraphEnabled {
+		return nil
+	}
+
+	return workloads
+}
+
+// jvmExtraOpts returns the system properties that enable the workloads, same as in cass-operator
+func jvmExtraOpts(workloads *cassdcapi.DseWorkloads) string {
+	flags := ""
+
+	if workloads.AnalyticsEnabled {
+		flags += "-Dspark-trackers=true "
+	}
+	if workloads.GraphEnabled {
+		flags += "-Dgraph-enabled=true "
+	}
+	if workloads.SearchEnabled {
+		flags += "-Dsearch-service=true"
+	}
+	return flags
+}
+
+// dseSecretFiles contains the local files referenced by dse.yaml that need to be stored in Secrets
+type dseSecretFiles struct {
+	systemKeys  map[string][]byte
+	kerberos    map[string][]byte
+	truststores map[string][]byte
+}
+
+// parseDseSecretFiles reads the system keys, Kerberos keytab and LDAP truststore referenced by the dse.yaml and
+// rewrites their paths to point to the Secrets mounted in the pod
+func parseDseSecretFiles(dseYaml map[string]interface{}, cassandraHome string, localFile func(string) (string, error)) (*dseSecretFiles, error) {
+	files := &dseSecretFiles{
+		systemKeys:  make(map[string][]byte),
+		kerberos:    make(map[string][]byte),
+		truststores: make(map[string][]byte),
+	}
+
+	resolve := func(path string) string {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(cassandraHome, path)
+		}
+		if local, err := localFile(path); err == nil {
+			return local
+		}
+		// Missing files are reported by the read
+		return path
+	}
+
+	if keyDir, ok := dseYaml["system_key_directory"].(string); ok && keyDir != "" && keyDir != SystemKeyMountPath {
+		keyDir = resolve(keyDir)
+		entries, err := os.ReadDir(keyDir)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() {
+				continue
+			}
+			content, err := os.ReadFile(filepath.Join(keyDir, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			files.systemKeys[entry.Name()] = content
+		}
+		if len(files.systemKeys) > 0 {
+			dseYaml["system_key_directory"] = SystemKeyMountPath
+		}
+	}
+
+	if kerberos, ok := dseYaml["kerberos_options"].(map[string]interface{}); ok && kerberosEnabled(dseYaml) {
+		if keytab, ok := kerberos["keytab"].(string); ok && keytab != "" && filepath.Dir(keytab) != KerberosMountPath {
+			content, err := os.ReadFile(resolve(keytab))
+			if err != nil {
+				return nil, fmt.Errorf("unable to read Kerberos keytab %s: %w", keytab, err)
+			}
+			files.kerberos[filepath.Base(keytab)] = content
+			kerberos["keytab"] = filepath.Join(KerberosMountPath, filepath.Base(keytab))
+		}
+
+		content, err := os.ReadFile(resolve(krb5ConfPath))
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		} else if err == nil {
+			files.kerberos[krb5ConfKey] = content
+		}
+	}
+
+	if ldap, ok := dseYaml["ldap_options"].(map[string]interface{}); ok {
+		if truststore, ok := ldap["truststore_path"].(string); ok && truststore != "" && filepath.Dir(truststore) != EncryptionMountPath {
+			content, err := os.ReadFile(resolve(truststore))
+			if err != nil {
+				return nil, fmt.Errorf("unable to read LDAP truststore %s: %w", truststore, err)
+			}
+			key := fmt.Sprintf("ldap-%s", filepath.Base(truststore))
+			files.truststores[key] = content
+			ldap["truststore_path"] = filepath.Join(EncryptionMountPath, key)
+		}
+	}
+
+	return files, nil
+}
+
+// kerberosEnabled checks if the DseAuthenticator uses Kerberos as one of its schemes
+func kerberosEnabled(dseYaml map[string]interface{}) bool {
+	authOptions, ok := dseYaml["authentication_options"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+
+	if enabled, ok := authOptions["enabled"].(bool); !ok || !enabled {
+		return false
+	}
+
+	if scheme, ok := authOptions["default_scheme"].(string); ok && scheme == "kerberos" {
+		return true
+	}
+
+	if schemes, ok := authOptions["other_schemes"].([]interface{}); ok {
+		for _, scheme := range schemes {
+			if scheme == "kerberos" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,75 @@
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDseWorkloadDetection(t *testing.T) {
+	require := require.New(t)
+	tempDir, err := os.MkdirTemp("", "test-")
+	require.NoError(err)
+	defer os.RemoveAll(tempDir)
+
+	defaultsFile := filepath.Join(tempDir, "dse")
+	require.NoError(os.WriteFile(defaultsFile, []byte("# Start as Search node\nSOLR_ENABLED=1\nSPARK_ENABLED=0\n"), 0644))
+
+	defaultsWorkloads, err := parseDseDefaults(defaultsFile)
+	require.NoError(err)
+	require.True(defaultsWorkloads.SearchEnabled)
+	require.False(defaultsWorkloads.AnalyticsEnabled)
+
+	missing, err := parseDseDefaults(filepath.Join(tempDir, "missing"))
+	require.NoError(err)
+	require.Nil(missing)
+
+	gossipWorkloads := parseGossipWorkloads(map[string]interface{}{
+		"dse_version": "6.8.25",
+		"workloads":   "Cassandra",
+		"graph":       true,
+	})
+	require.True(gossipWorkloads.GraphEnabled)
+
+	workloads := mergeDseWorkloads(gossipWorkloads, defaultsWorkloads, nil)
+	require.True(workloads.GraphEnabled)
+	require.True(workloads.SearchEnabled)
+	require.False(workloads.AnalyticsEnabled)
+
+	require.Nil(mergeDseWorkloads(parseGossipWorkloads(map[string]interface{}{"workload": "Cassandra"})))
+}
+
+func TestParseDseSecretFiles(t *testing.T) {
+	require := require.New(t)
+	tempDir, err := os.MkdirTemp("", "test-")
+	require.NoError(err)
+	defer os.RemoveAll(tempDir)
+
+	keyDir := filepath.Join(tempDir, "keys")
+	require.NoError(os.Mkdir(keyDir, 0755))
+	require.NoError(os.WriteFile(filepath.Join(keyDir, "system_key"), []byte("key"), 0600))
+	require.NoError(os.WriteFile(filepath.Join(tempDir, "dse.keytab"), []byte("keytab"), 0600))
+
+	dseYaml := map[string]interface{}{
+		"system_key_directory": keyDir,
+		"authentication_options": map[string]interface{}{
+			"enabled":        true,
+			"default_scheme": "internal",
+			"other_schemes":  []interface{}{"kerberos"},
+		},
+		"kerberos_options": map[string]interface{}{
+			"keytab": "dse.keytab",
+		},
+	}
+
+	files, err := parseDseSecretFiles(dseYaml, tempDir, localFilesystem)
+	require.NoError(err)
+	require.Equal([]byte("key"), files.systemKeys["system_key"])
+	require.Equal([]byte("keytab"), files.kerberos["dse.keytab"])
+	require.Equal(0, len(files.truststores))
+
+	require.Equal(SystemKeyMountPath, dseYaml["system_key_directory"])
+	require.Equal("/etc/dse/kerberos/dse.keytab", dseYaml["kerberos_options"].(map[string]interface{})["keytab"])
+}
@@ -0,0 +1,105 @@
+package migrate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	EncryptionVolumeName = "encryption-cred-storage"
+	EncryptionMountPath  = "/etc/encryption"
+)
+
+var (
+	encryptionSections = map[string]string{
+		"server_encryption_options": "server",
+		"client_encryption_options": "client",
+	}
+	encryptionStoreFields = []string{"keystore", "truststore"}
+)
+
+// encryptionStore is a keystore or truststore file referenced from cassandra.yaml
+type encryptionStore struct {
+	// Path is the location in the Cassandra node as written in the cassandra.yaml
+	Path string
+	// Key is the key in the Secret and the filename inside the pod
+	Key string
+}
+
+func encryptionSecretName(datacenter string) string {
+	return importSecretName(datacenter, "keystore")
+}
+
+// encryptionEnabled checks if server_encryption_options or client_encryption_options enable encryption
+func encryptionEnabled(options map[string]interface{}) bool {
+	if enabled, found := options["enabled"].(bool); found && enabled {
+		return true
+	}
+
+	if internode, found := options["internode_encryption"].(string); found && internode != "none" {
+		return true
+	}
+
+	return false
+}
+
+// parseEncryptionStores returns the keystores and truststores that are used by the enabled encryption options and
+// rewrites their paths in the cassandra.yaml to point to the Secret mounted in the pod
+func parseEncryptionStores(cassandraYaml map[string]interface{}) []encryptionStore {
+	stores := make([]encryptionStore, 0)
+
+	for section, prefix := range encryptionSections {
+		options, ok := cassandraYaml[section].(map[string]interface{})
+		if !ok || !encryptionEnabled(options) {
+			continue
+		}
+
+		for _, field := range encryptionStoreFields {
+			path, ok := options[field].(string)
+			if !ok || path == "" {
+				continue
+			}
+
+			// Already rewritten, the store was processed by earlier run
+			if filepath.Dir(path) == EncryptionMountPath {
+				continue
+			}
+
+			key := fmt.Sprintf("%s-%s", prefix, filepath.Base(path))
+			stores = append(stores, encryptionStore{
+				Path: path,
+				Key:  key,
+			})
+
+			options[field] = filepath.Join(EncryptionMountPath, key)
+		}
+	}
+
+	return stores
+}
+
+// readEncryptionStores reads the given stores from the local filesystem. Relative paths are resolved from the
+// Cassandra installation directory, the same way Cassandra does.
+func readEncryptionStores(cassandraHome string, stores []encryptionStore, localFile func(string) (string, error)) (map[string][]byte, error) {
+	data := make(map[string][]byte, len(stores))
+	for _, store := range stores {
+		path := store.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(cassandraHome, path)
+		}
+
+		local, err := localFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %w", path, err)
+		}
+
+		content, err := os.ReadFile(local)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %w", path, err)
+		}
+		data[store.Key] = content
+	}
+
+	return data, nil
+}
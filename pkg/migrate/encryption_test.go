@@ -0,0 +1,51 @@
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEncryptionStores(t *testing.T) {
+	require := require.New(t)
+	tempDir, err := os.MkdirTemp("", "test-")
+	require.NoError(err)
+	defer os.RemoveAll(tempDir)
+
+	require.NoError(os.Mkdir(filepath.Join(tempDir, "conf"), 0755))
+	require.NoError(os.WriteFile(filepath.Join(tempDir, "conf", ".keystore"), []byte("keystore"), 0644))
+	require.NoError(os.WriteFile(filepath.Join(tempDir, "conf", ".truststore"), []byte("truststore"), 0644))
+
+	cassYaml := map[string]interface{}{
+		"server_encryption_options": map[string]interface{}{
+			"internode_encryption": "all",
+			"keystore":             "conf/.keystore",
+			"truststore":           "conf/.truststore",
+		},
+		"client_encryption_options": map[string]interface{}{
+			"enabled":  false,
+			"keystore": "conf/.keystore",
+		},
+	}
+
+	stores := parseEncryptionStores(cassYaml)
+	require.Equal(2, len(stores))
+
+	serverOptions := cassYaml["server_encryption_options"].(map[string]interface{})
+	require.Equal("/etc/encryption/server-.keystore", serverOptions["keystore"])
+	require.Equal("/etc/encryption/server-.truststore", serverOptions["truststore"])
+
+	// Disabled options are not modified
+	clientOptions := cassYaml["client_encryption_options"].(map[string]interface{})
+	require.Equal("conf/.keystore", clientOptions["keystore"])
+
+	data, err := readEncryptionStores(tempDir, stores, localFilesystem)
+	require.NoError(err)
+	require.Equal([]byte("keystore"), data["server-.keystore"])
+	require.Equal([]byte("truststore"), data["server-.truststore"])
+
+	// Second parse does not find the already rewritten paths
+	require.Equal(0, len(parseEncryptionStores(cassYaml)))
+}
@@ -0,0 +1,474 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/apis/cassandra/v1beta1"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+var (
+	// exportedMetadata are the metadata fields kept in the exported manifests, the rest is set by the cluster
+	exportedMetadata = []string{"name", "namespace", "labels", "annotations"}
+
+	// runtimeAnnotations are set by the API server or controllers and are not exported
+	runtimeAnnotations = []string{
+		"kubectl.kubernetes.io/last-applied-configuration",
+		"pv.kubernetes.io/bound-by-controller",
+		"pv.kubernetes.io/bind-completed",
+	}
+)
+
+// ExportedFile is a manifest file written by the export
+type ExportedFile struct {
+	Name    string
+	Objects []map[string]interface{}
+}
+
+// ClusterExporter writes the imported datacenter as manifests without the status and the fields set by the cluster,
+// ready to be stored in Git
+type ClusterExporter struct {
+	client.Client
+	namespace  string
+	datacenter string
+}
+
+func NewClusterExporter(cli client.Client, namespace, datacenter string) *ClusterExporter {
+	return &ClusterExporter{
+		Client:     cli,
+		namespace:  namespace,
+		datacenter: datacenter,
+	}
+}
+
+// Export returns the datacenter's manifest files. Secrets are not exported, their names are returned as warnings
+// to be managed outside Git.
+func (e *ClusterExporter) Export(helmValues bool) ([]ExportedFile, []string, error) {
+	dc := &cassdcapi.CassandraDatacenter{}
+	if err := e.Client.Get(context.TODO(), types.NamespacedName{Name: e.datacenter, Namespace: e.namespace}, dc); err != nil {
+		return nil, nil, err
+	}
+
+	files := make([]ExportedFile, 0)
+	warnings := make([]string, 0)
+
+	kc, err := e.k8ssandraCluster()
+	if err != nil {
+		return nil, nil, err
+	}
+	if kc != nil {
+		files = append(files, ExportedFile{Name: fmt.Sprintf("k8ssandracluster-%s.yaml", kc.GetName()), Objects: []map[string]interface{}{cleanManifest(kc.Object)}})
+	} else {
+		manifest, err := e.manifest(dc)
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, ExportedFile{Name: fmt.Sprintf("cassandradatacenter-%s.yaml", dc.Name), Objects: []map[string]interface{}{manifest}})
+	}
+
+	config, err := e.config()
+	if err != nil {
+		return nil, nil, err
+	}
+	if config != nil {
+		files = append(files, ExportedFile{Name: fmt.Sprintf("config-%s.yaml", dc.Name), Objects: []map[string]interface{}{config}})
+	}
+
+	volumes, err := e.persistentVolumes()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(volumes) > 0 {
+		files = append(files, ExportedFile{Name: fmt.Sprintf("persistentvolumes-%s.yaml", dc.Name), Objects: volumes})
+	}
+
+	services, err := e.services(dc)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(services) > 0 {
+		files = append(files, ExportedFile{Name: fmt.Sprintf("services-%s.yaml", dc.Name), Objects: services})
+	}
+
+	if helmValues {
+		values, valueWarnings, err := k8ssandraHelmValues(dc)
+		if err != nil {
+			return nil, nil, err
+		}
+		warnings = append(warnings, valueWarnings...)
+		// Not a manifest, kept out of the applied directory
+		files = append(files, ExportedFile{Name: filepath.Join("helm", fmt.Sprintf("values-%s.yaml", dc.Name)), Objects: []map[string]interface{}{values}})
+	}
+
+	for _, secret := range datacenterSecrets(dc) {
+		warnings = append(warnings, fmt.Sprintf("Secret %s is used by the datacenter but not exported, manage it outside Git", secret))
+	}
+
+	return files, warnings, nil
+}
+
+// manifest converts the object to unstructured content with the kind set and the cluster managed fields removed
+func (e *ClusterExporter) manifest(obj client.Object) (map[string]interface{}, error) {
+	gvk, err := apiutil.GVKForObject(obj, e.Client.Scheme())
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	u := &unstructured.Unstructured{Object: content}
+	u.SetGroupVersionKind(gvk)
+
+	return cleanManifest(u.Object), nil
+}
+
+// k8ssandraCluster returns the K8ssandraCluster with the datacenter, nil if the datacenter is not part of one
+func (e *ClusterExporter) k8ssandraCluster() (*unstructured.Unstructured, error) {
+	installed, err := k8ssandraOperatorInstalled(e.Client, e.namespace)
+	if err != nil || !installed {
+		return nil, err
+	}
+
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(k8ssandraClusterListGVK)
+	if err := e.Client.List(context.TODO(), list, client.InNamespace(e.namespace)); err != nil {
+		return nil, err
+	}
+
+	for i := range list.Items {
+		datacenters, _, err := unstructured.NestedSlice(list.Items[i].Object, "spec", "cassandra", "datacenters")
+		if err != nil {
+			return nil, err
+		}
+		for _, dc := range datacenters {
+			name, _, _ := unstructured.NestedString(dc.(map[string]interface{}), "metadata", "name")
+			if name == e.datacenter {
+				return &list.Items[i], nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// config returns the stored configuration of the datacenter, the source of the CassandraDatacenter's config
+func (e *ClusterExporter) config() (map[string]interface{}, error) {
+	configMap, files, err := fetchConfigFiles(e.Client, e.namespace, e.datacenter)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return e.manifest(exportedConfigMap(configMap, files))
+}
+
+// exportedConfigMap replaces the compressed files of the ConfigMap with the decoded files, so the exported manifest
+// can be read and edited in Git
+func exportedConfigMap(configMap *corev1.ConfigMap, files map[string]string) *corev1.ConfigMap {
+	exported := configMap.DeepCopy()
+	exported.Data = files
+	exported.BinaryData = nil
+	return exported
+}
+
+// persistentVolumes returns the PersistentVolumes bound to the claims of the datacenter's pods
+func (e *ClusterExporter) persistentVolumes() ([]map[string]interface{}, error) {
+	pods := &corev1.PodList{}
+	if err := e.Client.List(context.TODO(), pods, client.InNamespace(e.namespace), client.MatchingLabels{cassdcapi.DatacenterLabel: e.datacenter}); err != nil {
+		return nil, err
+	}
+
+	claims := make([]string, 0)
+	for _, pod := range pods.Items {
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil {
+				claims = append(claims, volume.PersistentVolumeClaim.ClaimName)
+			}
+		}
+	}
+	sort.Strings(claims)
+
+	volumes := make([]map[string]interface{}, 0, len(claims))
+	for _, claimName := range claims {
+		claim := &corev1.PersistentVolumeClaim{}
+		if err := e.Client.Get(context.TODO(), types.NamespacedName{Name: claimName, Namespace: e.namespace}, claim); err != nil {
+			return nil, err
+		}
+		if claim.Spec.VolumeName == "" {
+			continue
+		}
+
+		pv := &corev1.PersistentVolume{}
+		if err := e.Client.Get(context.TODO(), types.NamespacedName{Name: claim.Spec.VolumeName}, pv); err != nil {
+			return nil, err
+		}
+
+		// The claim is recreated from the StatefulSet, only its name binds the volume
+		if pv.Spec.ClaimRef != nil {
+			pv.Spec.ClaimRef = &corev1.ObjectReference{
+				Kind:       "PersistentVolumeClaim",
+				APIVersion: "v1",
+				Namespace:  pv.Spec.ClaimRef.Namespace,
+				Name:       pv.Spec.ClaimRef.Name,
+			}
+		}
+
+		manifest, err := e.manifest(pv)
+		if err != nil {
+			return nil, err
+		}
+		volumes = append(volumes, manifest)
+	}
+
+	return volumes, nil
+}
+
+// services returns the cluster's Services not managed by cass-operator, such as the additional seed service created
+// by the import, with the Endpoints of the Services without a selector
+func (e *ClusterExporter) services(dc *cassdcapi.CassandraDatacenter) ([]map[string]interface{}, error) {
+	services := &corev1.ServiceList{}
+	if err := e.Client.List(context.TODO(), services, client.InNamespace(e.namespace)); err != nil {
+		return nil, err
+	}
+
+	prefix := cassdcapi.CleanupForKubernetes(dc.Spec.ClusterName) + "-"
+	manifests := make([]map[string]interface{}, 0)
+	for i := range services.Items {
+		svc := &services.Items[i]
+		if len(svc.OwnerReferences) > 0 || !strings.HasPrefix(svc.Name, prefix) {
+			continue
+		}
+
+		// The cluster allocates the IPs of non-headless services
+		if svc.Spec.ClusterIP != corev1.ClusterIPNone {
+			svc.Spec.ClusterIP = ""
+			svc.Spec.ClusterIPs = nil
+		}
+
+		manifest, err := e.manifest(svc)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, manifest)
+
+		if len(svc.Spec.Selector) > 0 {
+			continue
+		}
+
+		endpoints := &corev1.Endpoints{}
+		if err := e.Client.Get(context.TODO(), types.NamespacedName{Name: svc.Name, Namespace: svc.Namespace}, endpoints); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		manifest, err = e.manifest(endpoints)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, manifest)
+	}
+
+	return manifests, nil
+}
+
+// cleanManifest removes the status and the metadata set by the cluster
+func cleanManifest(obj map[string]interface{}) map[string]interface{} {
+	delete(obj, "status")
+
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	cleaned := make(map[string]interface{}, len(exportedMetadata))
+	for _, key := range exportedMetadata {
+		if value, found := metadata[key]; found {
+			cleaned[key] = value
+		}
+	}
+
+	if annotations, ok := cleaned["annotations"].(map[string]interface{}); ok {
+		for _, annotation := range runtimeAnnotations {
+			delete(annotations, annotation)
+		}
+		if len(annotations) == 0 {
+			delete(cleaned, "annotations")
+		}
+	}
+
+	obj["metadata"] = cleaned
+	removeNullValues(obj)
+	return obj
+}
+
+// removeNullValues removes the empty fields of the unstructured content, such as creationTimestamp of the templates
+func removeNullValues(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if field == nil {
+				delete(v, key)
+				continue
+			}
+			removeNullValues(field)
+		}
+	case []interface{}:
+		for _, item := range v {
+			removeNullValues(item)
+		}
+	}
+}
+
+// datacenterSecrets returns the Secrets referenced by the datacenter
+func datacenterSecrets(dc *cassdcapi.CassandraDatacenter) []string {
+	secrets := make([]string, 0)
+	if dc.Spec.SuperuserSecretName != "" {
+		secrets = append(secrets, dc.Spec.SuperuserSecretName)
+	}
+	for _, user := range dc.Spec.Users {
+		secrets = append(secrets, user.SecretName)
+	}
+	if dc.Spec.PodTemplateSpec != nil {
+		for _, volume := range dc.Spec.PodTemplateSpec.Spec.Volumes {
+			if volume.Secret != nil {
+				secrets = append(secrets, volume.Secret.SecretName)
+			}
+		}
+	}
+	sort.Strings(secrets)
+	return secrets
+}
+
+// k8ssandraHelmValues converts the CassandraDatacenter to values of the k8ssandra Helm chart. Only the settings the
+// chart supports are converted, the returned warnings list the rest.
+func k8ssandraHelmValues(dc *cassdcapi.CassandraDatacenter) (map[string]interface{}, []string, error) {
+	warnings := make([]string, 0)
+
+	if dc.Spec.ServerType != "cassandra" {
+		warnings = append(warnings, fmt.Sprintf("The k8ssandra Helm chart does not support server type %s", dc.Spec.ServerType))
+	}
+
+	racks := make([]interface{}, 0, len(dc.Spec.Racks))
+	for _, rack := range dc.Spec.Racks {
+		racks = append(racks, map[string]interface{}{"name": rack.Name})
+	}
+
+	cassandra := map[string]interface{}{
+		"enabled":     true,
+		"version":     dc.Spec.ServerVersion,
+		"clusterName": dc.Spec.ClusterName,
+		"auth": map[string]interface{}{
+			"enabled": authenticationEnabled(dc),
+		},
+		"datacenters": []interface{}{
+			map[string]interface{}{
+				"name":  dc.Name,
+				"size":  int64(dc.Spec.Size),
+				"racks": racks,
+			},
+		},
+	}
+
+	if dc.Spec.SuperuserSecretName != "" {
+		cassandra["auth"].(map[string]interface{})["superuser"] = map[string]interface{}{
+			"secret": dc.Spec.SuperuserSecretName,
+		}
+	}
+
+	if claim := dc.Spec.StorageConfig.CassandraDataVolumeClaimSpec; claim != nil {
+		volume := make(map[string]interface{})
+		if claim.StorageClassName != nil {
+			volume["storageClass"] = *claim.StorageClassName
+		}
+		if size, found := claim.Resources.Requests[corev1.ResourceStorage]; found {
+			volume["size"] = size.String()
+		}
+		cassandra["cassandraLibDirVolume"] = volume
+	}
+
+	template, templateWarnings, err := k8ssandraDatacenter(dc)
+	if err != nil {
+		return nil, nil, err
+	}
+	warnings = append(warnings, templateWarnings...)
+
+	if config, ok := template["config"].(map[string]interface{}); ok {
+		if jvmOptions, ok := config["jvmOptions"].(map[string]interface{}); ok {
+			heap := make(map[string]interface{})
+			if size, found := jvmOptions["heapSize"]; found {
+				heap["size"] = size
+			}
+			if size, found := jvmOptions["heapNewGenSize"]; found {
+				heap["newGenSize"] = size
+			}
+			cassandra["heap"] = heap
+		}
+		if _, found := config["cassandraYaml"]; found {
+			warnings = append(warnings, "The k8ssandra Helm chart does not take the cassandra.yaml settings, they are kept in the exported config file")
+		}
+	}
+
+	for _, field := range []string{"resources", "tolerations"} {
+		if value, found := template[field]; found && value != nil {
+			cassandra[field] = value
+		}
+	}
+	if _, found := template["extraVolumes"]; found {
+		warnings = append(warnings, "The k8ssandra Helm chart does not mount the keystores and logging configuration of the datacenter")
+	}
+
+	values := map[string]interface{}{
+		"cassandra": cassandra,
+		// The imported cluster had none of the other components
+		"stargate":              map[string]interface{}{"enabled": false},
+		"reaper":                map[string]interface{}{"enabled": false},
+		"medusa":                map[string]interface{}{"enabled": false},
+		"kube-prometheus-stack": map[string]interface{}{"enabled": false},
+	}
+
+	return values, warnings, nil
+}
+
+// WriteExportedFiles writes the files to the directory as YAML documents
+func WriteExportedFiles(dir string, files []ExportedFile) error {
+	for _, file := range files {
+		path := filepath.Join(dir, file.Name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		for _, obj := range file.Objects {
+			if err := encoder.Encode(obj); err != nil {
+				return err
+			}
+		}
+		if err := encoder.Close(); err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,125 @@
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/apis/cassandra/v1beta1"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCleanManifest(t *testing.T) {
+	require := require.New(t)
+
+	obj := map[string]interface{}{
+		"apiVersion": "cassandra.datastax.com/v1beta1",
+		"kind":       "CassandraDatacenter",
+		"metadata": map[string]interface{}{
+			"name":              "dc1",
+			"namespace":         "migrate",
+			"uid":               "3b1f",
+			"resourceVersion":   "1234",
+			"creationTimestamp": "2022-05-01T10:00:00Z",
+			"finalizers":        []interface{}{"finalizer.cassandra.datastax.com"},
+			"annotations": map[string]interface{}{
+				"kubectl.kubernetes.io/last-applied-configuration": "{}",
+			},
+			"labels": map[string]interface{}{"app": "cassandra"},
+		},
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{"creationTimestamp": nil},
+			},
+		},
+		"status": map[string]interface{}{"cassandraOperatorProgress": "Ready"},
+	}
+
+	cleaned := cleanManifest(obj)
+	require.NotContains(cleaned, "status")
+	require.Equal(map[string]interface{}{
+		"name":      "dc1",
+		"namespace": "migrate",
+		"labels":    map[string]interface{}{"app": "cassandra"},
+	}, cleaned["metadata"])
+	require.Equal(map[string]interface{}{"metadata": map[string]interface{}{}}, cleaned["spec"].(map[string]interface{})["template"])
+}
+
+func TestExportedConfigMap(t *testing.T) {
+	require := require.New(t)
+
+	compressed, err := compress([]byte("num_tokens: 16\n"))
+	require.NoError(err)
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "dc1-cass-config", Namespace: "migrate"},
+		BinaryData: map[string][]byte{"cassandra.yaml" + compressedSuffix: compressed},
+	}
+	files, err := configFiles(configMap)
+	require.NoError(err)
+
+	exported := exportedConfigMap(configMap, files)
+	require.Equal(files, exported.Data)
+	require.Nil(exported.BinaryData)
+	require.Equal("dc1-cass-config", exported.Name)
+	require.NotNil(configMap.BinaryData)
+}
+
+func TestK8ssandraHelmValues(t *testing.T) {
+	require := require.New(t)
+
+	storageClass := "local-path"
+	dc := &cassdcapi.CassandraDatacenter{
+		ObjectMeta: metav1.ObjectMeta{Name: "dc1"},
+		Spec: cassdcapi.CassandraDatacenterSpec{
+			ClusterName:         "Test Cluster",
+			ServerType:          "cassandra",
+			ServerVersion:       "4.0.5",
+			Size:                3,
+			SuperuserSecretName: "dc1-superuser",
+			Users:               []cassdcapi.CassandraUser{{SecretName: "dc1-role-app"}},
+			Racks:               []cassdcapi.Rack{{Name: "r1"}},
+			StorageConfig: cassdcapi.StorageConfig{
+				CassandraDataVolumeClaimSpec: &corev1.PersistentVolumeClaimSpec{
+					StorageClassName: &storageClass,
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("100Gi")},
+					},
+				},
+			},
+			PodTemplateSpec: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Volumes: []corev1.Volume{
+						{Name: EncryptionVolumeName, VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "dc1-keystore"}}},
+					},
+				},
+			},
+			Config: []byte(`{"cassandra-yaml": {"authenticator": "PasswordAuthenticator"}, "jvm-options": {"max_heap_size": "4G"}}`),
+		},
+	}
+
+	values, warnings, err := k8ssandraHelmValues(dc)
+	require.NoError(err)
+	require.NotEmpty(warnings)
+
+	cassandra := values["cassandra"].(map[string]interface{})
+	require.Equal("4.0.5", cassandra["version"])
+	require.Equal("Test Cluster", cassandra["clusterName"])
+	require.Equal(map[string]interface{}{"enabled": true, "superuser": map[string]interface{}{"secret": "dc1-superuser"}}, cassandra["auth"])
+	require.Equal(map[string]interface{}{"storageClass": "local-path", "size": "100Gi"}, cassandra["cassandraLibDirVolume"])
+	require.Equal(map[string]interface{}{"size": "4Gi"}, cassandra["heap"])
+
+	require.Equal([]string{"dc1-keystore", "dc1-role-app", "dc1-superuser"}, datacenterSecrets(dc))
+
+	dir := t.TempDir()
+	require.NoError(WriteExportedFiles(dir, []ExportedFile{{Name: filepath.Join("helm", "values-dc1.yaml"), Objects: []map[string]interface{}{values}}}))
+
+	content, err := os.ReadFile(filepath.Join(dir, "helm", "values-dc1.yaml"))
+	require.NoError(err)
+	written := make(map[string]interface{})
+	require.NoError(yaml.Unmarshal(content, written))
+	require.Equal(false, written["stargate"].(map[string]interface{})["enabled"])
+}
@@ -45,6 +45,9 @@ type ClusterMigrator struct {
 	CassConfigOverride string
 	CassandraHome      string
 
+	// Container is set if the local node runs in a Docker or Podman container
+	Container *ContainerInstallation
+
 	Cluster    string
 	Datacenter string
 	Rack       string
@@ -55,9 +58,13 @@ type ClusterMigrator struct {
 
 	ServerType    string
 	ServerVersion string
+	DseWorkloads  *cassdcapi.DseWorkloads
 
 	seeds []string
 
+	// nodes are the nodes of every datacenter of the cluster
+	nodes []NodetoolNodeInfo
+
 	// TODO Move these away..?
 	clusterConfigMap ClusterConfigMap
 }
@@ -99,8 +106,7 @@ func (c *ClusterMigrator) InitCluster(p *pterm.SpinnerPrinter) error {
 	pterm.Success.Println("Created seed services")
 
 	pterm.Info.Println("Initialized and parsed current Cassandra configuration. You may now review configuration before proceeding with node migration")
-
-	// TODO Create tools to review the configuration ConfigMap
+	pterm.Info.Printf("Use 'import config show|diff|edit %s' to review the configuration\n", c.Datacenter)
 
 	return nil
 }
@@ -111,7 +117,7 @@ func (c *ClusterMigrator) getSeeds() ([]string, error) {
 	}
 
 	// nodetool getseeds returns seeds other than the current one (seed labeling can't be done here)
-	seedsOutput, err := execNodetool(c.getNodetoolPath(), "getseeds")
+	seedsOutput, err := c.nodetool("getseeds")
 	if err != nil {
 		return nil, err
 	}
@@ -138,6 +144,55 @@ func (c *ClusterMigrator) getSeeds() ([]string, error) {
 	return seeds, nil
 }
 
+// resolveSeeds returns the IP addresses of the seeds, Endpoints only accept IPs. Loopback addresses are not allowed in
+// Kubernetes and are skipped.
+func resolveSeeds(seeds []string, resolve func(host string) (string, error)) ([]string, error) {
+	resolved := make([]string, 0, len(seeds))
+	for _, seed := range seeds {
+		ip := seed
+		if net.ParseIP(seed) == nil {
+			var err error
+			if ip, err = resolve(seed); err != nil {
+				return nil, fmt.Errorf("unable to resolve the address of seed %s: %w", seed, err)
+			}
+		}
+		if parsed := net.ParseIP(ip); parsed == nil || parsed.IsLoopback() {
+			continue
+		}
+		resolved = append(resolved, ip)
+	}
+	sort.Strings(resolved)
+	return resolved, nil
+}
+
+// resolveSeed returns the IP of a seed hostname. Helm charts, such as Bitnami's, use the DNS names of the pods as seeds,
+// which only resolve inside the cluster, so the pod is looked up first.
+func (c *ClusterMigrator) resolveSeed(host string) (string, error) {
+	podName := strings.SplitN(host, ".", 2)[0]
+	pod := &corev1.Pod{}
+	err := c.Client.Get(context.TODO(), types.NamespacedName{Name: podName, Namespace: c.Namespace}, pod)
+	if err == nil && pod.Status.PodIP != "" {
+		return pod.Status.PodIP, nil
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+
+	addrs, err := net.LookupIP(host)
+	if err != nil {
+		return "", err
+	}
+	for _, addr := range addrs {
+		if addr.To4() != nil {
+			return addr.String(), nil
+		}
+	}
+	if len(addrs) > 0 {
+		return addrs[0].String(), nil
+	}
+	return "", fmt.Errorf("no addresses found")
+}
+
 func (c *ClusterMigrator) CreateSeedServices() error {
 	// TODO Additional seeds service list must be cleaned up after the migration has completed
 	additionalSeedService := &corev1.Service{}
@@ -172,6 +227,11 @@ func (c *ClusterMigrator) CreateSeedServices() error {
 	if err != nil {
 		return err
 	}
+	seeds, err = resolveSeeds(seeds, c.resolveSeed)
+	if err != nil {
+		return err
+	}
+	seeds = crossDatacenterSeeds(seeds, c.nodes, c.Datacenter)
 
 	// TODO Verify endpoints is updated with all the possible seeds (if some nodes have different seeds catalog) ?
 	if len(seeds) > 0 {
@@ -185,22 +245,34 @@ func (c *ClusterMigrator) CreateSeedServices() error {
 }
 
 type ClusterConfigMap struct {
+	SchemaVersion int                `json:"schemaVersion,omitempty"`
 	Cluster       string             `json:"cluster"`
 	ServerType    string             `json:"serverType"`
 	ServerVersion string             `json:"serverVersion"`
 	Datacenter    string             `json:"datacenter"`
 	NodeInfos     []NodetoolNodeInfo `json:"nodeinfos"`
+
+	// Datacenters are all the datacenters of the cluster, each is imported with its own ClusterConfigMap
+	Datacenters []string `json:"datacenters,omitempty"`
+
+	DseWorkloads *cassdcapi.DseWorkloads `json:"dseWorkloads,omitempty"`
+
+	// LockScope and LockConcurrency are stored by the first node migration, every migrator of the datacenter uses the
+	// same Leases
+	LockScope       string `json:"lockScope,omitempty"`
+	LockConcurrency int    `json:"lockConcurrency,omitempty"`
 }
 
 func (c *ClusterMigrator) CreateClusterConfigMap() error {
 	// TODO Or should we use nodetool info first and then just find the correct one?
-	output, err := execNodetool(c.getNodetoolPath(), "gossipinfo")
+	output, err := c.nodetool("gossipinfo")
 	if err != nil {
 		return err
 	}
 
 	lines := strings.Split(output, "\n")
 
+	var gossipWorkloads *cassdcapi.DseWorkloads
 	detailsStarted := false
 	for _, line := range lines {
 		if strings.HasPrefix(line, "  ") {
@@ -223,14 +295,14 @@ func (c *ClusterMigrator) CreateClusterConfigMap() error {
 						}
 					case "X_11_PADDING":
 						// DSE 6.8
-						dseInfo := make(map[string]string)
+						dseInfo := make(map[string]interface{})
 						err = json.Unmarshal([]byte(fieldValue), &dseInfo)
 						if err != nil {
 							return err
 						}
 						c.ServerType = "dse"
-						c.ServerVersion = dseInfo["dse_version"]
-						// We could parse graph / search / etc settings here also for DSE
+						c.ServerVersion, _ = dseInfo["dse_version"].(string)
+						gossipWorkloads = parseGossipWorkloads(dseInfo)
 					}
 				}
 			}
@@ -245,8 +317,14 @@ func (c *ClusterMigrator) CreateClusterConfigMap() error {
 		}
 	}
 
+	if c.ServerType == "dse" {
+		if err := c.detectDseWorkloads(gossipWorkloads); err != nil {
+			return err
+		}
+	}
+
 	// ClusterName
-	clusterInfo, err := execNodetool(c.getNodetoolPath(), "describecluster")
+	clusterInfo, err := c.nodetool("describecluster")
 	if err != nil {
 		return err
 	}
@@ -255,16 +333,25 @@ func (c *ClusterMigrator) CreateClusterConfigMap() error {
 	fields := strings.Split(lines[1], ":")
 	c.Cluster = fields[1][1:]
 
+	// The seeds of the other datacenters are always taken from the current status of the cluster
+	nodeInfos, err := c.retrieveStatusFromNodetool()
+	if err != nil {
+		return err
+	}
+	c.nodes = nodeInfos
+
 	configMap := &corev1.ConfigMap{}
 	configMapKey := types.NamespacedName{Name: configMapName(c.Datacenter), Namespace: c.Namespace}
 	if err := c.Client.Get(context.TODO(), configMapKey, configMap); err != nil && !errors.IsNotFound(err) {
 		return err
-	} else if errors.IsNotFound(err) {
-		nodeInfos, err := c.retrieveStatusFromNodetool()
+	} else if err == nil {
+		// Created by an earlier run, keep the ordinals that were stored then
+		clusterConfigMap, err := decodeClusterInfo(configMap)
 		if err != nil {
 			return err
 		}
-
+		c.clusterConfigMap = *clusterConfigMap
+	} else {
 		configMap.ObjectMeta.Name = configMapName(c.Datacenter)
 		configMap.ObjectMeta.Namespace = c.Namespace
 		clusterConfigMap := ClusterConfigMap{
@@ -272,7 +359,9 @@ func (c *ClusterMigrator) CreateClusterConfigMap() error {
 			ServerVersion: c.ServerVersion,
 			ServerType:    c.ServerType,
 			Datacenter:    c.Datacenter,
-			NodeInfos:     nodeInfos,
+			NodeInfos:     datacenterNodes(nodeInfos, c.Datacenter),
+			Datacenters:   clusterDatacenters(nodeInfos, c.Datacenter),
+			DseWorkloads:  c.DseWorkloads,
 		}
 		/*
 			infoMap := map[string]interface{}{
@@ -291,15 +380,12 @@ func (c *ClusterMigrator) CreateClusterConfigMap() error {
 			configMap.Data = infoMap
 		*/
 
-		c.clusterConfigMap = clusterConfigMap
-
-		b, err := json.Marshal(clusterConfigMap)
-		configMap.BinaryData = make(map[string][]byte)
-		configMap.BinaryData["clusterInfo"] = b
-		if err != nil {
+		if err := encodeClusterInfo(configMap, &clusterConfigMap); err != nil {
 			return err
 		}
 
+		c.clusterConfigMap = clusterConfigMap
+
 		if err := c.Client.Create(context.TODO(), configMap); err != nil {
 			return err
 		}
@@ -308,6 +394,27 @@ func (c *ClusterMigrator) CreateClusterConfigMap() error {
 	return nil
 }
 
+// detectDseWorkloads combines the workloads from gossip, /etc/default/dse and the dse.yaml of the local node
+func (c *ClusterMigrator) detectDseWorkloads(gossipWorkloads *cassdcapi.DseWorkloads) error {
+	defaultsWorkloads, err := parseDseDefaults(dseDefaultsFile)
+	if err != nil {
+		return err
+	}
+
+	cfgParser, err := newInstallationParser(c.Container, c.CassConfigOverride, c.DseConfigOverride, c.CassandraHome)
+	if err != nil {
+		return err
+	}
+
+	if err := cfgParser.parseYaml(cfgParser.dseConfigHome, dseYamlFilename); err != nil {
+		return err
+	}
+
+	c.DseWorkloads = mergeDseWorkloads(gossipWorkloads, defaultsWorkloads, parseDseYamlWorkloads(cfgParser.DseYaml()))
+
+	return nil
+}
+
 func configMapName(datacenter string) string {
 	return fmt.Sprintf("%s-migrate-config", cassdcapi.CleanupForKubernetes(datacenter))
 }
@@ -327,8 +434,12 @@ func (c *ClusterMigrator) getNodetoolPath() string {
 	return fmt.Sprintf("%s/bin", c.CassandraHome)
 }
 
-func execNodetool(nodetoolLocation, command string) (string, error) {
-	out, err := exec.Command(nodetoolLocation, command).Output()
+func (c *ClusterMigrator) nodetool(args ...string) (string, error) {
+	return runNodetool(c.Container, c.getNodetoolPath(), args...)
+}
+
+func execNodetool(nodetoolLocation string, args ...string) (string, error) {
+	out, err := exec.Command(nodetoolLocation, args...).Output()
 	if err != nil {
 		if ee, ok := err.(*exec.ExitError); ok {
 			if ee.ExitCode() == 1 {
@@ -415,21 +526,90 @@ func (c *ClusterMigrator) endpointsForAdditionalSeeds(seeds []string) (*corev1.E
 }
 
 type NodetoolNodeInfo struct {
-	Status  string `json:"status"`
-	State   string `json:"state"`
-	Address string `json:"address"`
-	HostId  string `json:"hostId"`
-	Rack    string `json:"rack"`
-	Ordinal string `json:"ordinal"`
+	Status     string `json:"status"`
+	State      string `json:"state"`
+	Address    string `json:"address"`
+	HostId     string `json:"hostId"`
+	Rack       string `json:"rack"`
+	Ordinal    string `json:"ordinal"`
+	Datacenter string `json:"datacenter,omitempty"`
+}
+
+// remoteSeedsPerDatacenter is the amount of nodes added as seeds from each other datacenter that has none
+const remoteSeedsPerDatacenter = 2
+
+// datacenterNodes returns the nodes of the datacenter
+func datacenterNodes(nodeInfos []NodetoolNodeInfo, datacenter string) []NodetoolNodeInfo {
+	nodes := make([]NodetoolNodeInfo, 0, len(nodeInfos))
+	for _, nodeInfo := range nodeInfos {
+		if nodeInfo.Datacenter == "" || nodeInfo.Datacenter == datacenter {
+			nodes = append(nodes, nodeInfo)
+		}
+	}
+	return nodes
+}
+
+// clusterDatacenters returns every datacenter of the cluster, sorted
+func clusterDatacenters(nodeInfos []NodetoolNodeInfo, datacenter string) []string {
+	found := map[string]bool{datacenter: true}
+	for _, nodeInfo := range nodeInfos {
+		if nodeInfo.Datacenter != "" {
+			found[nodeInfo.Datacenter] = true
+		}
+	}
+
+	datacenters := make([]string, 0, len(found))
+	for name := range found {
+		datacenters = append(datacenters, name)
+	}
+	sort.Strings(datacenters)
+	return datacenters
+}
+
+// crossDatacenterSeeds adds nodes of the other datacenters to the seeds if none of their nodes is a seed, so that the
+// migrated nodes can always reach every datacenter
+func crossDatacenterSeeds(seeds []string, nodeInfos []NodetoolNodeInfo, datacenter string) []string {
+	isSeed := make(map[string]bool, len(seeds))
+	for _, seed := range seeds {
+		isSeed[seed] = true
+	}
+
+	covered := make(map[string]bool)
+	for _, nodeInfo := range nodeInfos {
+		if isSeed[nodeInfo.Address] {
+			covered[nodeInfo.Datacenter] = true
+		}
+	}
+
+	result := append([]string{}, seeds...)
+	added := make(map[string]int)
+	for _, nodeInfo := range nodeInfos {
+		if nodeInfo.Datacenter == "" || nodeInfo.Datacenter == datacenter || covered[nodeInfo.Datacenter] {
+			continue
+		}
+		if nodeInfo.Status != "up" || added[nodeInfo.Datacenter] >= remoteSeedsPerDatacenter {
+			continue
+		}
+		result = append(result, nodeInfo.Address)
+		added[nodeInfo.Datacenter]++
+	}
+
+	sort.Strings(result)
+	return result
 }
 
 // From cass-operator tests
 func (c *ClusterMigrator) retrieveStatusFromNodetool() ([]NodetoolNodeInfo, error) {
-	output, err := execNodetool(c.getNodetoolPath(), "status")
+	output, err := c.nodetool("status")
 	if err != nil {
 		return nil, err
 	}
 
+	return parseNodetoolStatus(output), nil
+}
+
+// parseNodetoolStatus parses the nodes of every datacenter. The ordinals are calculated per rack of each datacenter.
+func parseNodetoolStatus(output string) []NodetoolNodeInfo {
 	getFullName := func(s string) string {
 		status, ok := map[string]string{
 			"U": "up",
@@ -447,34 +627,45 @@ func (c *ClusterMigrator) retrieveStatusFromNodetool() ([]NodetoolNodeInfo, erro
 		return status
 	}
 
-	nodeTexts := regexp.MustCompile(`(?m)^.*(([0-9a-fA-F]+-){4}([0-9a-fA-F]+)).*$`).FindAllString(output, -1)
+	nodeLine := regexp.MustCompile(`^.*(([0-9a-fA-F]+-){4}([0-9a-fA-F]+)).*$`)
 	nodeInfo := []NodetoolNodeInfo{}
 
 	// Ordinal must be per rack calculation
 	ordinals := make(map[string]int)
 
+	datacenter := ""
 	// ordinal := 0
-	for _, nodeText := range nodeTexts {
+	for _, nodeText := range strings.Split(output, "\n") {
+		if strings.HasPrefix(nodeText, "Datacenter:") {
+			datacenter = strings.TrimSpace(strings.TrimPrefix(nodeText, "Datacenter:"))
+			continue
+		}
+		if !nodeLine.MatchString(nodeText) {
+			continue
+		}
+
 		comps := regexp.MustCompile(`[[:space:]]+`).Split(strings.TrimSpace(nodeText), -1)
 		rack := comps[len(comps)-1]
-		ordinal, found := ordinals[rack]
+		rackKey := datacenter + "/" + rack
+		ordinal, found := ordinals[rackKey]
 		if !found {
 			ordinal = 0
 		} else {
 			ordinal++
 		}
-		ordinals[rack] = ordinal
+		ordinals[rackKey] = ordinal
 
 		nodeInfo = append(nodeInfo,
 			NodetoolNodeInfo{
-				Status:  getFullName(string(comps[0][0])),
-				State:   getFullName(string(comps[0][1])),
-				Address: comps[1],
-				HostId:  comps[len(comps)-2],
-				Rack:    rack,
-				Ordinal: strconv.Itoa(ordinal),
+				Status:     getFullName(string(comps[0][0])),
+				State:      getFullName(string(comps[0][1])),
+				Address:    comps[1],
+				HostId:     comps[len(comps)-2],
+				Rack:       rack,
+				Ordinal:    strconv.Itoa(ordinal),
+				Datacenter: datacenter,
 			})
 		ordinal++
 	}
-	return nodeInfo, nil
+	return nodeInfo
 }
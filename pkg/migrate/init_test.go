@@ -0,0 +1,99 @@
+package migrate
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const multiDatacenterStatus = `Datacenter: dc1
+===============
+Status=Up/Down
+|/ State=Normal/Leaving/Joining/Moving
+--  Address     Load       Tokens  Owns (effective)  Host ID                               Rack
+UN  10.0.1.10   1.2 GiB    16      100.0%            0a6b1a3e-5f3c-4c59-9bd4-7c1a0b0c1d01  r1
+UN  10.0.1.11   1.1 GiB    16      100.0%            1b7c2b4f-6a4d-4d6a-8ce5-8d2b1c1d2e02  r1
+UN  10.0.1.12   1.3 GiB    16      100.0%            2c8d3c5a-7b5e-4e7b-9df6-9e3c2d2e3f03  r2
+
+Datacenter: dc2
+===============
+Status=Up/Down
+|/ State=Normal/Leaving/Joining/Moving
+--  Address     Load       Tokens  Owns (effective)  Host ID                               Rack
+UN  10.0.2.10   1.2 GiB    16      100.0%            3d9e4d6b-8c6f-4f8c-aea7-af4d3e3f4a04  r1
+DN  10.0.2.11   1.0 GiB    16      100.0%            4eaf5e7c-9d7a-4a9d-bfb8-ba5e4f4a5b05  r1
+UN  10.0.2.12   1.0 GiB    16      100.0%            5fba6f8d-ae8b-4bae-8ac9-cb6f5a5b6c06  r1
+`
+
+func TestParseNodetoolStatusMultipleDatacenters(t *testing.T) {
+	require := require.New(t)
+
+	nodes := parseNodetoolStatus(multiDatacenterStatus)
+	require.Len(nodes, 6)
+
+	require.Equal("dc1", nodes[0].Datacenter)
+	require.Equal("0", nodes[0].Ordinal)
+	require.Equal("1", nodes[1].Ordinal)
+	require.Equal("r2", nodes[2].Rack)
+	require.Equal("0", nodes[2].Ordinal)
+
+	// Ordinals are per rack of each datacenter
+	require.Equal("dc2", nodes[3].Datacenter)
+	require.Equal("r1", nodes[3].Rack)
+	require.Equal("0", nodes[3].Ordinal)
+	require.Equal("down", nodes[4].Status)
+	require.Equal("2", nodes[5].Ordinal)
+
+	local := datacenterNodes(nodes, "dc2")
+	require.Len(local, 3)
+	require.Equal("3d9e4d6b-8c6f-4f8c-aea7-af4d3e3f4a04", local[0].HostId)
+
+	require.Equal([]string{"dc1", "dc2"}, clusterDatacenters(nodes, "dc2"))
+	require.Equal([]string{"dc1"}, clusterDatacenters(nil, "dc1"))
+}
+
+func TestCrossDatacenterSeeds(t *testing.T) {
+	require := require.New(t)
+
+	nodes := parseNodetoolStatus(multiDatacenterStatus)
+
+	// dc1 has no seed in the seed list, two of its nodes are added
+	seeds := crossDatacenterSeeds([]string{"10.0.2.10"}, nodes, "dc2")
+	require.Equal([]string{"10.0.1.10", "10.0.1.11", "10.0.2.10"}, seeds)
+
+	// Down nodes are skipped
+	seeds = crossDatacenterSeeds([]string{"10.0.1.10"}, nodes, "dc1")
+	require.Equal([]string{"10.0.1.10", "10.0.2.10", "10.0.2.12"}, seeds)
+
+	// Every datacenter has a seed
+	seeds = crossDatacenterSeeds([]string{"10.0.1.10", "10.0.2.12"}, nodes, "dc1")
+	require.Equal([]string{"10.0.1.10", "10.0.2.12"}, seeds)
+}
+
+func TestResolveSeeds(t *testing.T) {
+	require := require.New(t)
+
+	pods := map[string]string{
+		"cassandra-0.cassandra-headless.cassandra.svc.cluster.local": "10.244.0.12",
+		"cassandra-1.cassandra-headless.cassandra.svc.cluster.local": "10.244.1.7",
+	}
+	resolve := func(host string) (string, error) {
+		if ip, found := pods[host]; found {
+			return ip, nil
+		}
+		return "", fmt.Errorf("not found")
+	}
+
+	seeds, err := resolveSeeds([]string{
+		"cassandra-1.cassandra-headless.cassandra.svc.cluster.local",
+		"cassandra-0.cassandra-headless.cassandra.svc.cluster.local",
+		"10.0.0.5",
+		"127.0.0.1",
+	}, resolve)
+	require.NoError(err)
+	require.Equal([]string{"10.0.0.5", "10.244.0.12", "10.244.1.7"}, seeds)
+
+	_, err = resolveSeeds([]string{"cassandra-2.cassandra-headless"}, resolve)
+	require.Error(err)
+}
@@ -0,0 +1,297 @@
+package migrate
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/burmanm/definitions-parser/pkg/types/generated"
+	definitions "github.com/burmanm/definitions-parser/pkg/types/matcher"
+)
+
+const (
+	cassandraEnvFilename = "cassandra-env.sh"
+
+	jvmOptionsKey       = "jvm-options"
+	jvmServerOptionsKey = "jvm-server-options"
+
+	additionalJvmOptionsKey = "additional-jvm-options"
+)
+
+var (
+	// jvm.options (Cassandra 3.11 and DSE), jvm-server.options and jvm8-server.options / jvm11-server.options. The
+	// jvm*-clients.options are only used by the tools.
+	jvmOptionsName = regexp.MustCompile(`^jvm([0-9]+)?(-server)?\.options$`)
+
+	heapOptions = map[string]string{
+		"-Xms": "initial_heap_size",
+		"-Xmx": "max_heap_size",
+		"-Xmn": "heap_size_young_generation",
+	}
+
+	garbageCollectors = map[string]string{
+		"-XX:+UseG1GC":            "G1GC",
+		"-XX:+UseConcMarkSweepGC": "CMS",
+	}
+
+	// envHeapSettings are the cassandra-env.sh variables and the cass-config-builder keys they're translated to
+	envHeapSettings = map[string][]string{
+		"MAX_HEAP_SIZE": {"initial_heap_size", "max_heap_size"},
+		"HEAP_NEWSIZE":  {"heap_size_young_generation"},
+	}
+
+	// imageDefaultOptions are set by the default jvm*.options files in the images and do not need to be carried over
+	imageDefaultOptions = []string{"--add-exports", "--add-opens", "-ea", "-da"}
+)
+
+// parseJVMOptions parses the JVM options files and heap settings from cassandra-env.sh to cass-config-builder format
+func (p *ConfigParser) parseJVMOptions() error {
+	err := filepath.WalkDir(p.cassConfigHome, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Couldn't access the file for some reason
+			return err
+		}
+
+		if d.IsDir() {
+			// We're not processing subdirs
+			if path != p.cassConfigHome {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if jvmOptionsName.MatchString(d.Name()) {
+			return p.parseJVMOptionsFile(path, d.Name())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return p.parseCassandraEnv()
+}
+
+func (p *ConfigParser) parseJVMOptionsFile(path, name string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	parsedConfig := make(map[string]interface{})
+	additionalJvmOptions := make([]string, 0)
+	unsupported := make([]string, 0)
+
+	matcher := definitions.NewMetadataMatcher(name)
+
+	// Comment lines are not carried over to reduce the ConfigMap size
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if key, value, found := parseHeapOption(line); found {
+			parsedConfig[key] = value
+			continue
+		}
+
+		if gc, found := garbageCollectors[line]; found {
+			parsedConfig["garbage_collector"] = gc
+			continue
+		}
+
+		if strings.HasPrefix(line, "-XX:+Use") && strings.HasSuffix(line, "GC") {
+			// Other collectors would conflict with the garbage_collector setting
+			unsupported = append(unsupported, line)
+			continue
+		}
+
+		if !strings.HasPrefix(line, "-X") && !strings.HasPrefix(line, "-D") {
+			if !isImageDefaultOption(line) {
+				unsupported = append(unsupported, line)
+			}
+			continue
+		}
+
+		key, val, defaultVal := matcher.Parse(line)
+		if key == "" {
+			additionalJvmOptions = append(additionalJvmOptions, line)
+		} else if val != defaultVal {
+			parsedConfig[key] = val
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	keyName := strings.ReplaceAll(name, ".", "-")
+
+	if len(additionalJvmOptions) > 0 {
+		parsedConfig[additionalJvmOptionsKey] = additionalJvmOptions
+	}
+
+	if len(unsupported) > 0 {
+		p.unsupportedJvmOptions[name] = unsupported
+	}
+
+	p.yamls[keyName] = parsedConfig
+	return nil
+}
+
+// parseHeapOption returns the cass-config-builder key and the value for -Xms, -Xmx and -Xmn options
+func parseHeapOption(line string) (string, string, bool) {
+	for prefix, key := range heapOptions {
+		if strings.HasPrefix(line, prefix) && len(line) > len(prefix) {
+			return key, line[len(prefix):], true
+		}
+	}
+	return "", "", false
+}
+
+// jvmOptionFlag renders a stored option back to the JVM command line option of the definitions. Disabled boolean
+// options are rendered as -XX:-Option, or as an empty option if they can't be disabled. The definitions of
+// jvm-server.options are used for the files that have none, such as the jvm.options of Cassandra 3.11.
+func jvmOptionFlag(name, key string, value interface{}) (string, bool) {
+	for _, optionsKey := range []string{name, jvmServerOptionsKey} {
+		prefixes := generated.RegexpFinder(optionsKey)
+		for option, meta := range generated.MapFinder(optionsKey) {
+			if meta.Key != key {
+				continue
+			}
+
+			val := fmt.Sprintf("%v", value)
+			switch {
+			case meta.BuilderType == "boolean" && strings.EqualFold(val, "true"):
+				return option, true
+			case meta.BuilderType == "boolean" && strings.HasPrefix(option, "-XX:+"):
+				return "-XX:-" + strings.TrimPrefix(option, "-XX:+"), true
+			case meta.BuilderType == "boolean":
+				return "", true
+			case prefixes != nil && prefixes.String() != "" && prefixes.MatchString(option):
+				// -Xss and the other options without an equal sign
+				return option + val, true
+			default:
+				return option + "=" + val, true
+			}
+		}
+	}
+	return "", false
+}
+
+func isImageDefaultOption(line string) bool {
+	for _, prefix := range imageDefaultOptions {
+		if line == prefix || strings.HasPrefix(line, prefix+" ") || strings.HasPrefix(line, prefix+":") || strings.HasPrefix(line, prefix+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCassandraEnv reads the MAX_HEAP_SIZE and HEAP_NEWSIZE from the cassandra-env.sh. The jvm options files take
+// precedence, same as in Cassandra, which only calculates the heap sizes if they're not set in the options files.
+func (p *ConfigParser) parseCassandraEnv() error {
+	heapSettings, err := parseCassandraEnvFile(filepath.Join(p.cassConfigHome, cassandraEnvFilename))
+	if err != nil {
+		return err
+	}
+
+	if len(heapSettings) == 0 {
+		return nil
+	}
+
+	// Cassandra 3.11 and DSE have the heap settings in jvm.options, 4.x in jvm-server.options
+	keyName := jvmServerOptionsKey
+	if _, found := p.yamls[jvmOptionsKey]; found {
+		keyName = jvmOptionsKey
+	}
+
+	options, found := p.yamls[keyName]
+	if !found {
+		options = make(map[string]interface{})
+		p.yamls[keyName] = options
+	}
+
+	for key, value := range heapSettings {
+		if _, found := options[key]; !found {
+			options[key] = value
+		}
+	}
+
+	return nil
+}
+
+// parseCassandraEnvFile returns the heap settings set in the cassandra-env.sh as cass-config-builder keys
+func parseCassandraEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	defer f.Close()
+
+	heapSettings := make(map[string]string)
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		setting := strings.SplitN(line, "=", 2)
+		if len(setting) < 2 {
+			continue
+		}
+
+		keys, found := envHeapSettings[strings.TrimSpace(setting[0])]
+		if !found {
+			continue
+		}
+
+		value := strings.Trim(setting[1], `"' `)
+		if value == "" || strings.Contains(value, "$") {
+			// Calculated by the script itself, leave it to the defaults
+			continue
+		}
+
+		for _, key := range keys {
+			heapSettings[key] = value
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return heapSettings, nil
+}
+
+// UnsupportedJvmOptions returns the JVM options that could not be translated, formatted as "<file>: <option>"
+func (p *ConfigParser) UnsupportedJvmOptions() []string {
+	files := make([]string, 0, len(p.unsupportedJvmOptions))
+	for file := range p.unsupportedJvmOptions {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	options := make([]string, 0)
+	for _, file := range files {
+		for _, option := range p.unsupportedJvmOptions[file] {
+			options = append(options, file+": "+option)
+		}
+	}
+	return options
+}
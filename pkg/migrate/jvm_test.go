@@ -0,0 +1,44 @@
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLegacyJvmOptions(t *testing.T) {
+	require := require.New(t)
+	confDir := t.TempDir()
+
+	jvmOptions := `# Cassandra 3.11 style jvm.options
+-Xms8G
+-XX:+UseG1GC
+-XX:+UseShenandoahGC
+-Dcassandra.disable_auth_caches_remote_configuration=false
+-javaagent:/opt/agent.jar
+-ea
+`
+	cassandraEnv := `#MAX_HEAP_SIZE="4G"
+MAX_HEAP_SIZE="16G"
+HEAP_NEWSIZE="$MAX_HEAP_SIZE"
+`
+	require.NoError(os.WriteFile(filepath.Join(confDir, "jvm.options"), []byte(jvmOptions), 0644))
+	require.NoError(os.WriteFile(filepath.Join(confDir, "jvm-clients.options"), []byte("-Xmx1G\n"), 0644))
+	require.NoError(os.WriteFile(filepath.Join(confDir, cassandraEnvFilename), []byte(cassandraEnv), 0644))
+
+	parser := NewParser()
+	parser.cassConfigHome = confDir
+	require.NoError(parser.parseJVMOptions())
+
+	require.Equal(1, len(parser.yamls))
+	options := parser.yamls[jvmOptionsKey]
+	require.Equal("8G", options["initial_heap_size"])
+	require.Equal("16G", options["max_heap_size"])
+	require.NotContains(options, "heap_size_young_generation")
+	require.Equal("G1GC", options["garbage_collector"])
+	require.Equal([]string{"-Dcassandra.disable_auth_caches_remote_configuration=false"}, options[additionalJvmOptionsKey])
+
+	require.Equal([]string{"jvm.options: -XX:+UseShenandoahGC", "jvm.options: -javaagent:/opt/agent.jar"}, parser.UnsupportedJvmOptions())
+}
@@ -0,0 +1,361 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/apis/cassandra/v1beta1"
+	"github.com/pterm/pterm"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	waitutil "k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// The k8ssandra-operator API is not a dependency of this module, K8ssandraCluster is handled as unstructured
+var (
+	k8ssandraClusterGVK     = schema.GroupVersionKind{Group: "k8ssandra.io", Version: "v1alpha1", Kind: "K8ssandraCluster"}
+	k8ssandraClusterListGVK = schema.GroupVersionKind{Group: "k8ssandra.io", Version: "v1alpha1", Kind: "K8ssandraClusterList"}
+)
+
+// jvmHeapOptions are the stored JVM options with a K8ssandraCluster field
+var jvmHeapOptions = map[string]string{
+	"max_heap_size":              "heapSize",
+	"heap_size_young_generation": "heapNewGenSize",
+}
+
+const (
+	k8ssandraGcField                = "gc"
+	k8ssandraAdditionalOptionsField = "additionalOptions"
+)
+
+// k8ssandraOperatorInstalled checks if the K8ssandraCluster CRD of k8ssandra-operator is installed
+func k8ssandraOperatorInstalled(cli client.Client, namespace string) (bool, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(k8ssandraClusterListGVK)
+	if err := cli.List(context.TODO(), list, client.InNamespace(namespace), client.Limit(1)); err != nil {
+		if meta.IsNoMatchError(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// finishK8ssandraCluster creates a K8ssandraCluster with every datacenter of the cluster. The datacenters are committed
+// together, k8ssandra-operator would otherwise add the later ones as new datacenters and rebuild them.
+func (c *MigrateFinisher) finishK8ssandraCluster(p *pterm.SpinnerPrinter) error {
+	datacenters := c.clusterConfigMap.Datacenters
+	if len(datacenters) == 0 {
+		datacenters = []string{c.clusterConfigMap.Datacenter}
+	}
+
+	p.UpdateText("Verifying every datacenter has been migrated")
+
+	templates := make([]map[string]interface{}, 0, len(datacenters))
+	pending := make([]string, 0)
+	authentication := false
+	superuserSecret := ""
+	for _, datacenter := range datacenters {
+		finisher := c
+		if datacenter != c.clusterConfigMap.Datacenter {
+			finisher = NewMigrateFinisher(c.Client, c.namespace, datacenter)
+			if err := finisher.fetchConfiguration(); err != nil {
+				if errors.IsNotFound(err) {
+					pending = append(pending, datacenter)
+					continue
+				}
+				return err
+			}
+		}
+
+		pods, err := finisher.migratedPods()
+		if err != nil {
+			return err
+		}
+		if len(pods) < len(finisher.clusterConfigMap.NodeInfos) {
+			pending = append(pending, datacenter)
+			continue
+		}
+
+		dc, err := finisher.buildCassandraDatacenter()
+		if err != nil {
+			return err
+		}
+
+		template, warnings, err := k8ssandraDatacenter(dc)
+		if err != nil {
+			return err
+		}
+		for _, warning := range warnings {
+			pterm.Warning.Println(warning)
+		}
+		templates = append(templates, template)
+
+		if authenticationEnabled(dc) {
+			authentication = true
+		}
+		if superuserSecret == "" {
+			superuserSecret = dc.Spec.SuperuserSecretName
+		}
+	}
+
+	if len(pending) > 0 {
+		pterm.Info.Printf("Datacenter %s is ready, the K8ssandraCluster is created once datacenters %s have been migrated. Run 'import commit' again after that\n", c.clusterConfigMap.Datacenter, strings.Join(pending, ", "))
+		return nil
+	}
+
+	p.UpdateText("Creating K8ssandraCluster")
+	kc, err := buildK8ssandraCluster(c.namespace, c.clusterConfigMap, templates, authentication, superuserSecret)
+	if err != nil {
+		return err
+	}
+	if err := c.Client.Create(context.TODO(), kc); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return err
+		}
+		pterm.Info.Printf("K8ssandraCluster %s already exists\n", kc.GetName())
+	} else {
+		pterm.Success.Printf("K8ssandraCluster %s created with datacenters %s\n", kc.GetName(), strings.Join(datacenters, ", "))
+	}
+
+	for _, datacenter := range datacenters {
+		p.UpdateText(fmt.Sprintf("Waiting for datacenter %s to finish reconciliation...", datacenter))
+		finisher := NewMigrateFinisher(c.Client, c.namespace, datacenter)
+		finisher.clusterConfigMap.Datacenter = datacenter
+		if err := finisher.waitForCreatedDatacenter(); err != nil {
+			return err
+		}
+		pterm.Success.Printf("CassandraDatacenter %s status is Ready\n", datacenter)
+	}
+
+	pterm.Info.Println("Cluster is fully managed by k8ssandra-operator now, welcome to k8ssandra")
+
+	return nil
+}
+
+// waitForCreatedDatacenter waits until k8ssandra-operator has created the CassandraDatacenter and it is ready
+func (c *MigrateFinisher) waitForCreatedDatacenter() error {
+	err := waitutil.PollImmediate(5*time.Second, 10*time.Minute, func() (bool, error) {
+		err := c.Client.Get(context.TODO(), types.NamespacedName{Name: c.clusterConfigMap.Datacenter, Namespace: c.namespace}, &cassdcapi.CassandraDatacenter{})
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return err == nil, err
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.waitForDatacenter()
+}
+
+// authenticationEnabled checks if the datacenter's cassandra.yaml uses an authenticator
+func authenticationEnabled(dc *cassdcapi.CassandraDatacenter) bool {
+	config := make(map[string]map[string]interface{})
+	if err := json.Unmarshal(dc.Spec.Config, &config); err != nil {
+		return false
+	}
+	return usesAuthenticator(config[cassYamlKey])
+}
+
+// toUnstructuredValue converts a typed value to the maps and slices of unstructured content
+func toUnstructuredValue(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var converted interface{}
+	if err := json.Unmarshal(data, &converted); err != nil {
+		return nil, err
+	}
+	return converted, nil
+}
+
+// k8ssandraDatacenter converts the CassandraDatacenter to a datacenter template of K8ssandraCluster. The stored
+// configuration files are split into the cassandraYaml, dseYaml and jvmOptions fields, the returned warnings list the
+// settings that could not be converted.
+func k8ssandraDatacenter(dc *cassdcapi.CassandraDatacenter) (map[string]interface{}, []string, error) {
+	warnings := make([]string, 0)
+
+	files := make(map[string]map[string]interface{})
+	if err := json.Unmarshal(dc.Spec.Config, &files); err != nil {
+		return nil, nil, err
+	}
+
+	config := make(map[string]interface{})
+	jvmOptions := make(map[string]interface{})
+	fileNames := make([]string, 0, len(files))
+	for name := range files {
+		fileNames = append(fileNames, name)
+	}
+	sort.Strings(fileNames)
+
+	for _, name := range fileNames {
+		content := files[name]
+		switch {
+		case name == cassYamlKey:
+			config["cassandraYaml"] = content
+		case name == "dse-yaml":
+			config["dseYaml"] = content
+		case strings.HasPrefix(name, "jvm"):
+			unconverted, err := k8ssandraJvmOptions(name, content, jvmOptions)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, key := range unconverted {
+				warnings = append(warnings, fmt.Sprintf("JVM option %s of %s in datacenter %s is not carried over to the K8ssandraCluster", key, name, dc.Name))
+			}
+		default:
+			warnings = append(warnings, fmt.Sprintf("%s of datacenter %s is not carried over to the K8ssandraCluster", name, dc.Name))
+		}
+	}
+	if len(jvmOptions) > 0 {
+		config["jvmOptions"] = jvmOptions
+	}
+
+	template := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"name": dc.Name,
+		},
+		"size":   int64(dc.Spec.Size),
+		"config": config,
+	}
+
+	fields := map[string]interface{}{
+		"racks":         dc.Spec.Racks,
+		"storageConfig": dc.Spec.StorageConfig,
+		"networking":    dc.Spec.Networking,
+		"resources":     dc.Spec.Resources,
+	}
+	if dc.Spec.DseWorkloads != nil {
+		fields["dseWorkloads"] = dc.Spec.DseWorkloads
+	}
+
+	if podTemplate := dc.Spec.PodTemplateSpec; podTemplate != nil {
+		if len(podTemplate.Spec.Tolerations) > 0 {
+			fields["tolerations"] = podTemplate.Spec.Tolerations
+		}
+		if podTemplate.Spec.SecurityContext != nil {
+			fields["podSecurityContext"] = podTemplate.Spec.SecurityContext
+		}
+		if len(podTemplate.Spec.Volumes) > 0 {
+			// Keystores and logback.xml
+			fields["extraVolumes"] = map[string]interface{}{"volumes": podTemplate.Spec.Volumes}
+			fields["containers"] = podTemplate.Spec.Containers
+		}
+	}
+
+	for name, value := range fields {
+		converted, err := toUnstructuredValue(value)
+		if err != nil {
+			return nil, nil, err
+		}
+		template[name] = converted
+	}
+
+	return template, warnings, nil
+}
+
+// k8ssandraJvmOptions adds the stored JVM options of one file to the jvmOptions of the K8ssandraCluster. The heap sizes
+// and the garbage collector have their own fields, the other options are rendered back to command line options and
+// added to the additionalOptions. Returns the keys of the options that could not be converted.
+func k8ssandraJvmOptions(name string, content map[string]interface{}, jvmOptions map[string]interface{}) ([]string, error) {
+	unconverted := make([]string, 0)
+
+	additionalOptions, _ := jvmOptions[k8ssandraAdditionalOptionsField].([]interface{})
+
+	keys := make([]string, 0, len(content))
+	for key := range content {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := content[key]
+		if field, found := jvmHeapOptions[key]; found {
+			size, err := parseHeapSize(fmt.Sprintf("%v", value))
+			if err != nil {
+				return nil, err
+			}
+			jvmOptions[field] = resource.NewQuantity(size, resource.BinarySI).String()
+			continue
+		}
+
+		switch key {
+		case "initial_heap_size":
+			// heapSize sets both the initial and the maximum heap size
+		case "garbage_collector":
+			jvmOptions[k8ssandraGcField] = fmt.Sprintf("%v", value)
+		case additionalJvmOptionsKey:
+			options, ok := value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s of %s is not a list of options", additionalJvmOptionsKey, name)
+			}
+			additionalOptions = append(additionalOptions, options...)
+		default:
+			option, found := jvmOptionFlag(name, key, value)
+			if !found {
+				unconverted = append(unconverted, key)
+				continue
+			}
+			if option != "" {
+				additionalOptions = append(additionalOptions, option)
+			}
+		}
+	}
+
+	if len(additionalOptions) > 0 {
+		jvmOptions[k8ssandraAdditionalOptionsField] = additionalOptions
+	}
+
+	return unconverted, nil
+}
+
+// buildK8ssandraCluster returns a K8ssandraCluster named after the cluster with the given datacenter templates. With
+// authentication, the imported superuser is required. k8ssandra-operator would otherwise generate a new superuser and
+// try to apply it to the existing cluster.
+func buildK8ssandraCluster(namespace string, cluster ClusterConfigMap, datacenters []map[string]interface{}, authentication bool, superuserSecret string) (*unstructured.Unstructured, error) {
+	if authentication && superuserSecret == "" {
+		return nil, fmt.Errorf("authentication is enabled in cluster %s, but no superuser credentials were imported. Import them with 'import credentials <datacenter> --username=<superuser>' and run 'import commit' again", cluster.Cluster)
+	}
+
+	dcs := make([]interface{}, 0, len(datacenters))
+	for _, dc := range datacenters {
+		dcs = append(dcs, dc)
+	}
+
+	cassandra := map[string]interface{}{
+		"clusterName":   cluster.Cluster,
+		"serverType":    cluster.ServerType,
+		"serverVersion": cluster.ServerVersion,
+		"datacenters":   dcs,
+	}
+	if superuserSecret != "" {
+		// The imported superuser is shared by every datacenter
+		cassandra["superuserSecretRef"] = map[string]interface{}{"name": superuserSecret}
+	}
+
+	kc := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				// The existing authentication settings are kept
+				"auth":      authentication,
+				"cassandra": cassandra,
+			},
+		},
+	}
+	kc.SetGroupVersionKind(k8ssandraClusterGVK)
+	kc.SetName(cassdcapi.CleanupForKubernetes(cluster.Cluster))
+	kc.SetNamespace(namespace)
+
+	return kc, nil
+}
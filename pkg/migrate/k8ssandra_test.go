@@ -0,0 +1,100 @@
+package migrate
+
+import (
+	"testing"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/apis/cassandra/v1beta1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestK8ssandraDatacenter(t *testing.T) {
+	require := require.New(t)
+
+	fsGroup := int64(999)
+	dc := &cassdcapi.CassandraDatacenter{
+		ObjectMeta: metav1.ObjectMeta{Name: "dc2"},
+		Spec: cassdcapi.CassandraDatacenterSpec{
+			Size: 3,
+			DseWorkloads: &cassdcapi.DseWorkloads{
+				SearchEnabled: true,
+			},
+			Racks: []cassdcapi.Rack{{Name: "r1"}},
+			Networking: &cassdcapi.NetworkingConfig{
+				HostNetwork: true,
+			},
+			PodTemplateSpec: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					SecurityContext: &corev1.PodSecurityContext{FSGroup: &fsGroup},
+				},
+			},
+			Config: []byte(`{
+				"cassandra-yaml": {"num_tokens": 16, "authenticator": "PasswordAuthenticator"},
+				"jvm-server-options": {"initial_heap_size": "8G", "max_heap_size": "8G", "heap_size_young_generation": "800M", "log_gc": true},
+				"jvm11-server-options": {"garbage_collector": "G1GC", "max_gc_pause_millis": 300, "print_gc_details": false, "thread_stack_size": "512k", "additional-jvm-options": ["-XX:+AlwaysPreTouch"]},
+				"cassandra-env-sh": {"additional-jvm-opts": ["-Dfoo=bar"]}
+			}`),
+		},
+	}
+
+	template, warnings, err := k8ssandraDatacenter(dc)
+	require.NoError(err)
+	require.Len(warnings, 2)
+	require.Contains(warnings[0], "cassandra-env-sh")
+	require.Contains(warnings[1], "log_gc")
+
+	require.Equal("dc2", template["metadata"].(map[string]interface{})["name"])
+	require.Equal(int64(3), template["size"])
+	require.Equal(true, template["networking"].(map[string]interface{})["hostNetwork"])
+	require.Equal(float64(999), template["podSecurityContext"].(map[string]interface{})["fsGroup"])
+	require.NotContains(template, "extraVolumes")
+
+	config := template["config"].(map[string]interface{})
+	require.Equal(float64(16), config["cassandraYaml"].(map[string]interface{})["num_tokens"])
+	require.Equal(map[string]interface{}{
+		"heapSize":       "8Gi",
+		"heapNewGenSize": "800Mi",
+		"gc":             "G1GC",
+		"additionalOptions": []interface{}{
+			"-XX:+AlwaysPreTouch",
+			"-XX:MaxGCPauseMillis=300",
+			"-XX:-PrintGCDetails",
+			"-Xss512k",
+		},
+	}, config["jvmOptions"])
+	require.Equal(map[string]interface{}{"searchEnabled": true}, template["dseWorkloads"])
+	require.True(authenticationEnabled(dc))
+
+	cluster := ClusterConfigMap{Cluster: "Test Cluster", ServerType: "cassandra", ServerVersion: "4.0.5"}
+
+	// Authentication requires the imported superuser
+	_, err = buildK8ssandraCluster("migrate", cluster, []map[string]interface{}{template}, true, "")
+	require.Error(err)
+
+	kc, err := buildK8ssandraCluster("migrate", cluster, []map[string]interface{}{template}, true, "testcluster-superuser")
+	require.NoError(err)
+	require.Equal("testcluster", kc.GetName())
+	require.Equal("K8ssandraCluster", kc.GetKind())
+
+	clusterName, _, err := unstructured.NestedString(kc.Object, "spec", "cassandra", "clusterName")
+	require.NoError(err)
+	require.Equal("Test Cluster", clusterName)
+
+	datacenters, _, err := unstructured.NestedSlice(kc.Object, "spec", "cassandra", "datacenters")
+	require.NoError(err)
+	require.Len(datacenters, 1)
+
+	auth, _, err := unstructured.NestedBool(kc.Object, "spec", "auth")
+	require.NoError(err)
+	require.True(auth)
+
+	superuserSecret, _, err := unstructured.NestedString(kc.Object, "spec", "cassandra", "superuserSecretRef", "name")
+	require.NoError(err)
+	require.Equal("testcluster-superuser", superuserSecret)
+
+	kc, err = buildK8ssandraCluster("migrate", cluster, []map[string]interface{}{template}, false, "")
+	require.NoError(err)
+	require.NotContains(kc.Object["spec"].(map[string]interface{})["cassandra"], "superuserSecretRef")
+}
@@ -2,39 +2,180 @@ package migrate
 
 import (
 	"context"
+	"fmt"
 	"os"
-	"sync"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 	coordinationv1client "k8s.io/client-go/kubernetes/typed/coordination/v1"
 	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/leaderelection"
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
 	LeaderElectionID = "migrator.k8ssandra.io"
+
+	// LockScopeNamespace allows one migration per namespace at a time
+	LockScopeNamespace = "namespace"
+	// LockScopeDatacenter allows concurrent migrations in different datacenters
+	LockScopeDatacenter = "datacenter"
+	// LockScopeRack allows concurrent migrations in different racks
+	LockScopeRack = "rack"
+
+	lockWaitReportInterval = 5 * time.Second
 )
 
-func NewResourceLock(namespace string) (resourcelock.Interface, error) {
-	config, err := ctrl.GetConfig()
-	if err != nil {
-		return nil, err
+var invalidLeaseNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// ErrLockLost is returned when the migrator lock was lost during the migration
+var ErrLockLost = fmt.Errorf("migrator lock was lost")
+
+// LockConfig defines which migrations are allowed to run at the same time
+type LockConfig struct {
+	// Scope is one of namespace, datacenter or rack. Empty uses the scope stored for the datacenter, or namespace.
+	Scope string
+	// Concurrency is the amount of nodes that can be migrated at the same time in the scope. Zero uses the concurrency
+	// stored for the datacenter, or 1.
+	Concurrency int
+	// Timeout is the maximum time to wait for the lock, zero waits forever
+	Timeout time.Duration
+}
+
+// Validate verifies the scope and concurrency
+func (l LockConfig) Validate() error {
+	switch l.Scope {
+	case "", LockScopeNamespace, LockScopeDatacenter, LockScopeRack:
+	default:
+		return fmt.Errorf("unknown lock scope %s, must be one of %s, %s or %s", l.Scope, LockScopeNamespace, LockScopeDatacenter, LockScopeRack)
+	}
+	if l.Concurrency < 0 {
+		return fmt.Errorf("lock concurrency can not be negative")
 	}
+	if l.Timeout < 0 {
+		return fmt.Errorf("lock timeout can not be negative")
+	}
+	return nil
+}
+
+// resolve returns the lock configuration stored for the datacenter. The first migration stores its scope and
+// concurrency, the later ones must not request different values as they would acquire different Leases and not exclude
+// each other. The returned bool is true if the stored values need to be written.
+func (l LockConfig) resolve(cluster *ClusterConfigMap) (LockConfig, bool, error) {
+	resolved := l
+	if cluster.LockScope == "" {
+		if resolved.Scope == "" {
+			resolved.Scope = LockScopeNamespace
+		}
+		if resolved.Concurrency < 1 {
+			resolved.Concurrency = 1
+		}
+		cluster.LockScope = resolved.Scope
+		cluster.LockConcurrency = resolved.Concurrency
+		return resolved, true, nil
+	}
+
+	if l.Scope != "" && l.Scope != cluster.LockScope {
+		return l, false, fmt.Errorf("datacenter %s is migrated with lock scope %s, requested scope %s would not exclude the other migrators", cluster.Datacenter, cluster.LockScope, l.Scope)
+	}
+	if l.Concurrency > 0 && l.Concurrency != cluster.LockConcurrency {
+		return l, false, fmt.Errorf("datacenter %s is migrated with lock concurrency %d, requested concurrency %d would not exclude the other migrators", cluster.Datacenter, cluster.LockConcurrency, l.Concurrency)
+	}
+
+	resolved.Scope = cluster.LockScope
+	resolved.Concurrency = cluster.LockConcurrency
+	return resolved, false, nil
+}
+
+// storedLockConfig resolves the lock configuration of the datacenter, storing it to the datacenter's ConfigMap if this
+// is the first migration. Concurrent first migrations are serialized by the ConfigMap's resourceVersion.
+func storedLockConfig(cli client.Client, namespace, datacenter string, requested LockConfig) (LockConfig, error) {
+	var resolved LockConfig
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap := &corev1.ConfigMap{}
+		configMapKey := types.NamespacedName{Name: configMapName(datacenter), Namespace: namespace}
+		if err := cli.Get(context.TODO(), configMapKey, configMap); err != nil {
+			return err
+		}
 
-	// Leader id, needs to be unique
+		clusterConfigMap, err := decodeClusterInfo(configMap)
+		if err != nil {
+			return err
+		}
+
+		lockConfig, store, err := requested.resolve(clusterConfigMap)
+		if err != nil {
+			return err
+		}
+		resolved = lockConfig
+
+		if !store {
+			return nil
+		}
+
+		if err := encodeClusterInfo(configMap, clusterConfigMap); err != nil {
+			return err
+		}
+		return cli.Update(context.TODO(), configMap)
+	})
+
+	return resolved, err
+}
+
+// lockNames returns a Lease name per concurrent slot of the scope. The first slot of the namespace scope is the Lease used
+// by the earlier versions, so that older clients are kept out as well.
+func (l LockConfig) lockNames(datacenter, rack string) []string {
+	base := LeaderElectionID
+	switch l.Scope {
+	case LockScopeDatacenter:
+		base = fmt.Sprintf("%s-%s", base, leaseNameSegment(datacenter))
+	case LockScopeRack:
+		base = fmt.Sprintf("%s-%s-%s", base, leaseNameSegment(datacenter), leaseNameSegment(rack))
+	}
+
+	concurrency := l.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	names := make([]string, 0, concurrency)
+	names = append(names, base)
+	for i := 1; i < concurrency; i++ {
+		names = append(names, fmt.Sprintf("%s-%d", base, i))
+	}
+	return names
+}
+
+func leaseNameSegment(name string) string {
+	return strings.Trim(invalidLeaseNameChars.ReplaceAllString(strings.ToLower(name), "-"), "-.")
+}
+
+// lockIdentity is unique per migrator process
+func lockIdentity() (string, error) {
 	id, err := os.Hostname()
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 	padder, err := uuid.NewUUID()
+	if err != nil {
+		return "", err
+	}
+	return id + "_" + padder.String(), nil
+}
+
+func NewResourceLock(namespace, name, identity string) (resourcelock.Interface, error) {
+	config, err := ctrl.GetConfig()
 	if err != nil {
 		return nil, err
 	}
-	id = id + "_" + padder.String()
 
 	// Construct clients for leader election
 	rest.AddUserAgent(config, "leader-election")
@@ -50,28 +191,161 @@ func NewResourceLock(namespace string) (resourcelock.Interface, error) {
 
 	return resourcelock.New(resourcelock.LeasesResourceLock,
 		namespace,
-		LeaderElectionID,
+		name,
 		corev1Client,
 		coordinationClient,
 		resourcelock.ResourceLockConfig{
-			Identity: id,
+			Identity: identity,
 		})
 }
 
-func RunLeaderElection(ctx context.Context, wg *sync.WaitGroup, lock resourcelock.Interface) {
+// MigrationLock is a held migrator lock
+type MigrationLock struct {
+	// Name is the Lease that was acquired
+	Name string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Lost returns ErrLockLost if the lock is no longer held
+func (m *MigrationLock) Lost() error {
+	if m.ctx.Err() != nil {
+		return ErrLockLost
+	}
+	return nil
+}
+
+// Release gives up the lock and waits until the Lease has been released
+func (m *MigrationLock) Release() {
+	m.cancel()
+	<-m.done
+}
+
+type lockSlot struct {
+	name   string
+	lock   resourcelock.Interface
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type acquiredSlot struct {
+	slot int
+	ctx  context.Context
+}
+
+// AcquireMigrationLock waits until one of the concurrent slots of the scope is free. The waiting function is called
+// periodically with the holders of the slots.
+func AcquireMigrationLock(ctx context.Context, namespace string, config LockConfig, datacenter, rack string, waiting func(holders []string)) (*MigrationLock, error) {
+	identity, err := lockIdentity()
+	if err != nil {
+		return nil, err
+	}
+
+	names := config.lockNames(datacenter, rack)
+	locks := make([]resourcelock.Interface, 0, len(names))
+	for _, name := range names {
+		lock, err := NewResourceLock(namespace, name, identity)
+		if err != nil {
+			return nil, err
+		}
+		locks = append(locks, lock)
+	}
+
+	slots := make([]*lockSlot, 0, len(names))
+	acquired := make(chan acquiredSlot, len(names))
+
+	for i, name := range names {
+		slotCtx, cancel := context.WithCancel(ctx)
+		slot := &lockSlot{name: name, lock: locks[i], cancel: cancel, done: make(chan struct{})}
+		slots = append(slots, slot)
+
+		slotIndex := i
+		go runLeaderElection(slotCtx, slot, func(leaderCtx context.Context) {
+			acquired <- acquiredSlot{slot: slotIndex, ctx: leaderCtx}
+		})
+	}
+
+	releaseAll := func() {
+		for _, slot := range slots {
+			slot.cancel()
+		}
+		for _, slot := range slots {
+			<-slot.done
+		}
+	}
+
+	var timeout <-chan time.Time
+	if config.Timeout > 0 {
+		timer := time.NewTimer(config.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	ticker := time.NewTicker(lockWaitReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case a := <-acquired:
+			// Give up the other slots, another one might have been acquired at the same time
+			for i, slot := range slots {
+				if i != a.slot {
+					slot.cancel()
+				}
+			}
+			for i, slot := range slots {
+				if i != a.slot {
+					<-slot.done
+				}
+			}
+			won := slots[a.slot]
+			return &MigrationLock{Name: won.name, ctx: a.ctx, cancel: won.cancel, done: won.done}, nil
+		case <-ticker.C:
+			if waiting != nil {
+				waiting(lockHolders(ctx, slots))
+			}
+		case <-timeout:
+			holders := lockHolders(ctx, slots)
+			releaseAll()
+			return nil, fmt.Errorf("timed out after %s waiting for migrator lock %s, held by %s", config.Timeout, strings.Join(names, ", "), strings.Join(holders, ", "))
+		case <-ctx.Done():
+			releaseAll()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// lockHolders returns the current holders of the slots
+func lockHolders(ctx context.Context, slots []*lockSlot) []string {
+	holders := make([]string, 0, len(slots))
+	for _, slot := range slots {
+		record, _, err := slot.lock.Get(ctx)
+		if err != nil || record.HolderIdentity == "" {
+			continue
+		}
+		holders = append(holders, record.HolderIdentity)
+	}
+	if len(holders) == 0 {
+		holders = append(holders, "unknown")
+	}
+	return holders
+}
+
+func runLeaderElection(ctx context.Context, slot *lockSlot, onStartedLeading func(context.Context)) {
+	defer close(slot.done)
 	leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
-		Lock:            lock,
+		Lock:            slot.lock,
+		Name:            slot.name,
 		ReleaseOnCancel: true,
 		LeaseDuration:   10 * time.Second,
 		RenewDeadline:   5 * time.Second,
 		RetryPeriod:     2 * time.Second,
 		Callbacks: leaderelection.LeaderCallbacks{
-			OnStartedLeading: func(c context.Context) {
-				// Indicate we've acquired the lock..
-				wg.Done()
-			},
+			OnStartedLeading: onStartedLeading,
 			OnStoppedLeading: func() {
-				// TODO This is fatal to our usage..
+				// Also called when the lock was never acquired, MigrationLock.Lost tells if the lock was held
 			},
 		},
 	})
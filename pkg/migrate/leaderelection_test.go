@@ -0,0 +1,64 @@
+package migrate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockNames(t *testing.T) {
+	require := require.New(t)
+
+	require.Equal([]string{LeaderElectionID}, LockConfig{Concurrency: 1}.lockNames("dc1", "r1"))
+	require.Equal([]string{LeaderElectionID, LeaderElectionID + "-1"}, LockConfig{Scope: LockScopeNamespace, Concurrency: 2}.lockNames("dc1", "r1"))
+	require.Equal([]string{"migrator.k8ssandra.io-dc1"}, LockConfig{Scope: LockScopeDatacenter, Concurrency: 1}.lockNames("dc1", "r1"))
+	require.Equal([]string{
+		"migrator.k8ssandra.io-my-dc-rack-1",
+		"migrator.k8ssandra.io-my-dc-rack-1-1",
+		"migrator.k8ssandra.io-my-dc-rack-1-2",
+	}, LockConfig{Scope: LockScopeRack, Concurrency: 3}.lockNames("My_DC", "Rack 1"))
+}
+
+func TestLockConfigValidate(t *testing.T) {
+	require := require.New(t)
+
+	require.NoError(LockConfig{Scope: LockScopeRack, Concurrency: 2}.Validate())
+	require.Error(LockConfig{Scope: "cluster", Concurrency: 1}.Validate())
+	require.NoError(LockConfig{}.Validate())
+	require.Error(LockConfig{Scope: LockScopeDatacenter, Concurrency: -1}.Validate())
+}
+
+func TestLockConfigResolve(t *testing.T) {
+	require := require.New(t)
+
+	// The first migration stores the defaults
+	cluster := &ClusterConfigMap{Datacenter: "dc1"}
+	resolved, store, err := LockConfig{}.resolve(cluster)
+	require.NoError(err)
+	require.True(store)
+	require.Equal(LockScopeNamespace, resolved.Scope)
+	require.Equal(1, resolved.Concurrency)
+	require.Equal(LockScopeNamespace, cluster.LockScope)
+	require.Equal(1, cluster.LockConcurrency)
+
+	cluster = &ClusterConfigMap{Datacenter: "dc1"}
+	resolved, store, err = LockConfig{Scope: LockScopeRack, Concurrency: 2, Timeout: time.Hour}.resolve(cluster)
+	require.NoError(err)
+	require.True(store)
+	require.Equal(LockConfig{Scope: LockScopeRack, Concurrency: 2, Timeout: time.Hour}, resolved)
+
+	// Later migrations use the stored values, with their own timeout
+	resolved, store, err = LockConfig{Timeout: time.Minute}.resolve(cluster)
+	require.NoError(err)
+	require.False(store)
+	require.Equal(LockConfig{Scope: LockScopeRack, Concurrency: 2, Timeout: time.Minute}, resolved)
+	require.Equal([]string{"migrator.k8ssandra.io-dc1-r1", "migrator.k8ssandra.io-dc1-r1-1"}, resolved.lockNames("dc1", "r1"))
+
+	_, _, err = LockConfig{Scope: LockScopeRack, Concurrency: 2}.resolve(cluster)
+	require.NoError(err)
+	_, _, err = LockConfig{Scope: LockScopeDatacenter}.resolve(cluster)
+	require.Error(err)
+	_, _, err = LockConfig{Concurrency: 3}.resolve(cluster)
+	require.Error(err)
+}
@@ -0,0 +1,119 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pterm/pterm"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	logbackFilename = "logback.xml"
+
+	LogbackVolumeName = "logback-config"
+	// The image copies the files in /config to the Cassandra configuration directory before starting
+	LogbackMountPath = "/config/logback.xml"
+	serverLogsPath   = "/var/log/cassandra"
+)
+
+var logbackFileElement = regexp.MustCompile(`<file>\s*([^<]+?)\s*</file>`)
+
+func logbackConfigMapName(datacenter string) string {
+	return getConfigMapName(datacenter, "logback")
+}
+
+func (p *ConfigParser) parseLogback() error {
+	logback, err := os.ReadFile(filepath.Join(p.cassConfigHome, logbackFilename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	p.logback = logback
+	return nil
+}
+
+// logbackExternalFiles returns the log files outside the Cassandra log directory. Only the log directory is persisted
+// and read by the server-system-logger in the pod.
+func logbackExternalFiles(logback []byte) []string {
+	files := make([]string, 0)
+	for _, match := range logbackFileElement.FindAllSubmatch(logback, -1) {
+		file := string(match[1])
+		if strings.HasPrefix(file, "${cassandra.logdir}") || strings.HasPrefix(file, serverLogsPath) {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+// storeLogbackConfig stores the logback.xml to a ConfigMap that is mounted to the Cassandra container
+func storeLogbackConfig(cli client.Client, namespace, datacenter string, logback []byte) error {
+	if len(logback) == 0 {
+		return nil
+	}
+
+	for _, file := range logbackExternalFiles(logback) {
+		pterm.Warning.Printf("logback.xml writes to %s, which is outside %s and will not be persisted\n", file, serverLogsPath)
+	}
+
+	configMap := &corev1.ConfigMap{}
+	configMapKey := types.NamespacedName{Name: logbackConfigMapName(datacenter), Namespace: namespace}
+	if err := cli.Get(context.TODO(), configMapKey, configMap); err != nil && !errors.IsNotFound(err) {
+		return err
+	} else if errors.IsNotFound(err) {
+		configMap.ObjectMeta.Name = configMapKey.Name
+		configMap.ObjectMeta.Namespace = configMapKey.Namespace
+		configMap.Data = map[string]string{
+			logbackFilename: string(logback),
+		}
+		return cli.Create(context.TODO(), configMap)
+	}
+
+	if !bytes.Equal([]byte(configMap.Data[logbackFilename]), logback) {
+		pterm.Warning.Printf("Local logback.xml differs from the one stored in ConfigMap %s, using the stored one\n", configMapKey.Name)
+	}
+
+	return nil
+}
+
+// existingLogbackConfig returns the volume and mount for the stored logback.xml, or nil if there is none
+func existingLogbackConfig(cli client.Client, namespace, datacenter string) (*corev1.Volume, *corev1.VolumeMount, error) {
+	configMap := &corev1.ConfigMap{}
+	configMapKey := types.NamespacedName{Name: logbackConfigMapName(datacenter), Namespace: namespace}
+	if err := cli.Get(context.TODO(), configMapKey, configMap); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	volume := &corev1.Volume{
+		Name: LogbackVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: configMapKey.Name,
+				},
+			},
+		},
+	}
+
+	mount := &corev1.VolumeMount{
+		Name:      LogbackVolumeName,
+		MountPath: LogbackMountPath,
+		SubPath:   logbackFilename,
+		ReadOnly:  true,
+	}
+
+	return volume, mount, nil
+}
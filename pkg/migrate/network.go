@@ -0,0 +1,322 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/burmanm/k8ssandra-client/pkg/cassdcutil"
+	cassdcapi "github.com/k8ssandra/cass-operator/apis/cassandra/v1beta1"
+	"github.com/k8ssandra/cass-operator/pkg/httphelper"
+	"github.com/pterm/pterm"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	waitutil "k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	podNetworkStartTimeout   = 10 * time.Minute
+	podNetworkReplaceTimeout = 60 * time.Minute
+)
+
+// NetworkMigrator moves an imported CassandraDatacenter from host networking to pod networking
+type NetworkMigrator struct {
+	client.Client
+	namespace  string
+	datacenter string
+
+	mgr        *cassdcutil.CassManager
+	mgmtClient httphelper.NodeMgmtClient
+}
+
+// nodeTarget is the state of a single node before it was moved to pod networking
+type nodeTarget struct {
+	podName string
+	podUID  types.UID
+	hostIP  string
+	hostID  string
+}
+
+func NewNetworkMigrator(cli client.Client, namespace, datacenter string) *NetworkMigrator {
+	return &NetworkMigrator{
+		Client:     cli,
+		namespace:  namespace,
+		datacenter: datacenter,
+		mgr:        cassdcutil.NewManager(cli),
+	}
+}
+
+// MigrateToPodNetwork disables host networking in the CassandraDatacenter and follows the rolling update node by node.
+// cass-operator updates one rack at a time and the StatefulSet restarts one pod at a time, reusing the existing data
+// volumes. If a node can not come back with its old data, it is replaced using the replace_address flow.
+func (n *NetworkMigrator) MigrateToPodNetwork(p *pterm.SpinnerPrinter) error {
+	p.UpdateText("Fetching CassandraDatacenter")
+
+	dc, err := n.mgr.CassandraDatacenter(n.datacenter, n.namespace)
+	if err != nil {
+		return err
+	}
+
+	if !dc.IsHostNetworkEnabled() {
+		pterm.Info.Println("CassandraDatacenter is already using pod networking")
+		return nil
+	}
+
+	if dc.Status.GetConditionStatus(cassdcapi.DatacenterReady) != corev1.ConditionTrue {
+		return fmt.Errorf("CassandraDatacenter %s is not ready, can't change networking", dc.Name)
+	}
+
+	mgmtClient, err := NewManagementClient(context.TODO(), n.Client)
+	if err != nil {
+		return err
+	}
+	n.mgmtClient = mgmtClient
+
+	p.UpdateText("Verifying cluster health")
+	targets, err := n.nodeTargets(dc)
+	if err != nil {
+		return err
+	}
+
+	if err := n.waitForRingHealth(dc); err != nil {
+		return err
+	}
+	pterm.Success.Println("All nodes are up and normal")
+
+	p.UpdateText("Disabling host networking in the CassandraDatacenter")
+	dc = dc.DeepCopy()
+	dc.Spec.Networking.HostNetwork = false
+	if err := n.Client.Update(context.TODO(), dc); err != nil {
+		return err
+	}
+	pterm.Success.Println("Host networking disabled, pods will be restarted one at a time")
+
+	for i, target := range targets {
+		p.UpdateText(fmt.Sprintf("Moving %s to pod networking (%d/%d)", target.podName, i+1, len(targets)))
+		if err := n.migrateNode(dc, target); err != nil {
+			pterm.Error.Printf("Failed to move %s to pod networking: %v\n", target.podName, err)
+			return err
+		}
+	}
+
+	p.UpdateText("Removing host network seeds")
+	if err := n.removeHostSeeds(dc); err != nil {
+		return err
+	}
+
+	p.UpdateText("Waiting for Datacenter to finish reconciliation...")
+	err = waitutil.PollImmediate(10*time.Second, 10*time.Minute, func() (bool, error) {
+		return n.mgr.RefreshStatus(dc, cassdcapi.DatacenterReady, corev1.ConditionTrue)
+	})
+	if err != nil {
+		return err
+	}
+
+	pterm.Success.Println("CassandraDatacenter is now using pod networking")
+
+	return nil
+}
+
+// nodeTargets returns the nodes of the datacenter in the order cass-operator and the StatefulSets will restart them
+func (n *NetworkMigrator) nodeTargets(dc *cassdcapi.CassandraDatacenter) ([]nodeTarget, error) {
+	pods, err := n.mgr.CassandraDatacenterPods(dc)
+	if err != nil {
+		return nil, err
+	}
+
+	rackPods := make(map[string][]corev1.Pod)
+	for _, pod := range pods.Items {
+		rack := pod.Labels[cassdcapi.RackLabel]
+		rackPods[rack] = append(rackPods[rack], pod)
+	}
+
+	targets := make([]nodeTarget, 0, len(pods.Items))
+	for _, rack := range dc.GetRacks() {
+		podsInRack := rackPods[rack.Name]
+
+		// StatefulSet rolling update starts from the highest ordinal
+		sort.Slice(podsInRack, func(i, j int) bool {
+			return podOrdinal(podsInRack[i].Name) > podOrdinal(podsInRack[j].Name)
+		})
+
+		for _, pod := range podsInRack {
+			nodeStatus, found := dc.Status.NodeStatuses[pod.Name]
+			if !found || nodeStatus.HostID == "" {
+				return nil, fmt.Errorf("no host ID found for pod %s", pod.Name)
+			}
+			targets = append(targets, nodeTarget{
+				podName: pod.Name,
+				podUID:  pod.UID,
+				hostIP:  pod.Status.PodIP,
+				hostID:  nodeStatus.HostID,
+			})
+		}
+	}
+
+	return targets, nil
+}
+
+func podOrdinal(podName string) int {
+	ordinal, err := strconv.Atoi(podName[strings.LastIndex(podName, "-")+1:])
+	if err != nil {
+		return -1
+	}
+	return ordinal
+}
+
+// migrateNode waits for the pod to be recreated in the pod network. If the pod fails to start with the existing data,
+// the node is replaced and the data is streamed from the other replicas.
+func (n *NetworkMigrator) migrateNode(dc *cassdcapi.CassandraDatacenter, target nodeTarget) error {
+	podKey := types.NamespacedName{Name: target.podName, Namespace: n.namespace}
+	pod := &corev1.Pod{}
+
+	err := waitutil.PollImmediate(10*time.Second, podNetworkStartTimeout, func() (bool, error) {
+		if err := n.Client.Get(context.TODO(), podKey, pod); err != nil {
+			if errors.IsNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		return pod.UID != target.podUID && !pod.Spec.HostNetwork && isServerReady(pod), nil
+	})
+
+	if err != nil {
+		if err != waitutil.ErrWaitTimeout {
+			return err
+		}
+		pterm.Warning.Printf("Pod %s did not start with the existing data, replacing the node %s\n", target.podName, target.hostIP)
+		if err := n.replaceNode(target); err != nil {
+			return err
+		}
+	} else {
+		hostID, err := n.hostIDForPod(pod)
+		if err != nil {
+			return err
+		}
+		if hostID != target.hostID {
+			return fmt.Errorf("pod %s came up with host ID %s, expected %s", target.podName, hostID, target.hostID)
+		}
+	}
+
+	if err := n.waitForRingHealth(dc); err != nil {
+		return err
+	}
+
+	pterm.Success.Printf("Pod %s is using pod networking\n", target.podName)
+	return nil
+}
+
+// replaceNode uses cass-operator's node replace process, which starts the node with replace_address
+func (n *NetworkMigrator) replaceNode(target nodeTarget) error {
+	dc, err := n.mgr.CassandraDatacenter(n.datacenter, n.namespace)
+	if err != nil {
+		return err
+	}
+
+	dc = dc.DeepCopy()
+	dc.Spec.ReplaceNodes = append(dc.Spec.ReplaceNodes, target.podName)
+	if err := n.Client.Update(context.TODO(), dc); err != nil {
+		return err
+	}
+
+	// Wait for cass-operator to pick up the replace request..
+	err = waitutil.PollImmediate(5*time.Second, podNetworkStartTimeout, func() (bool, error) {
+		dc, err := n.mgr.CassandraDatacenter(n.datacenter, n.namespace)
+		if err != nil {
+			return false, err
+		}
+		return len(dc.Spec.ReplaceNodes) == 0, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// ..and then for the streaming to finish
+	return waitutil.PollImmediate(10*time.Second, podNetworkReplaceTimeout, func() (bool, error) {
+		dc, err := n.mgr.CassandraDatacenter(n.datacenter, n.namespace)
+		if err != nil {
+			return false, err
+		}
+		for _, replacing := range dc.Status.NodeReplacements {
+			if replacing == target.podName {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+}
+
+func (n *NetworkMigrator) hostIDForPod(pod *corev1.Pod) (string, error) {
+	endpoints, err := n.mgmtClient.CallMetadataEndpointsEndpoint(pod)
+	if err != nil {
+		return "", err
+	}
+
+	for _, endpoint := range endpoints.Entity {
+		if endpoint.EndpointIP == pod.Status.PodIP || endpoint.GetRpcAddress() == pod.Status.PodIP {
+			return endpoint.HostID, nil
+		}
+	}
+
+	return "", fmt.Errorf("pod %s was not found from the ring", pod.Name)
+}
+
+// waitForRingHealth waits until every node in the ring is alive and in NORMAL state, that is, no node is
+// joining, leaving or streaming data
+func (n *NetworkMigrator) waitForRingHealth(dc *cassdcapi.CassandraDatacenter) error {
+	return waitutil.PollImmediate(10*time.Second, podNetworkReplaceTimeout, func() (bool, error) {
+		pods, err := n.mgr.CassandraDatacenterPods(dc)
+		if err != nil {
+			return false, err
+		}
+
+		for _, pod := range pods.Items {
+			if !isServerReady(&pod) {
+				continue
+			}
+
+			endpoints, err := n.mgmtClient.CallMetadataEndpointsEndpoint(&pod)
+			if err != nil {
+				// Try the next pod
+				continue
+			}
+
+			for _, endpoint := range endpoints.Entity {
+				if endpoint.IsAlive != "true" || !endpoint.HasStatus(httphelper.StatusNormal) {
+					return false, nil
+				}
+			}
+			return true, nil
+		}
+
+		return false, nil
+	})
+}
+
+// removeHostSeeds removes the additional seeds created by the import process, they point to the host network addresses
+func (n *NetworkMigrator) removeHostSeeds(dc *cassdcapi.CassandraDatacenter) error {
+	endpoints := &corev1.Endpoints{}
+	endpointsKey := types.NamespacedName{Name: dc.GetAdditionalSeedsServiceName(), Namespace: n.namespace}
+	if err := n.Client.Get(context.TODO(), endpointsKey, endpoints); err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	if len(dc.Spec.AdditionalSeeds) > 0 {
+		// These are managed by the cass-operator
+		return nil
+	}
+
+	if err := n.Client.Delete(context.TODO(), endpoints); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -41,12 +42,25 @@ func NewNodeMigrator(cli client.Client, namespace string) *NodeMigrator {
 
 func (n *NodeMigrator) MigrateNode(p *pterm.SpinnerPrinter) error {
 	n.p = p
-	p.UpdateText("Getting Cassandra node information")
+	n.timer = newStepTimer()
 
-	cfgParser := NewParser()
-	if err := cfgParser.ParseConfigDirectories(n.CassConfigOverride, n.DseConfigOverride, n.CassandraHome); err != nil {
+	err := n.migrateNode()
+	n.finishSteps(err)
+	return err
+}
+
+func (n *NodeMigrator) migrateNode() error {
+	n.startStep("Getting Cassandra node information")
+
+	cfgParser, err := newInstallationParser(n.Container, n.CassConfigOverride, n.DseConfigOverride, n.CassandraHome)
+	if err != nil {
 		return err
 	}
+	if n.Container != nil {
+		defer func() {
+			_ = n.Container.Cleanup()
+		}()
+	}
 
 	if err := cfgParser.ParseConfigs(); err != nil {
 		return err
@@ -61,8 +75,43 @@ func (n *NodeMigrator) MigrateNode(p *pterm.SpinnerPrinter) error {
 	}
 	pterm.Success.Println("Gathered information from local Cassandra node")
 
+	warnings, err := cfgParser.validateTopology(n.Datacenter, n.Rack, nil)
+	if err != nil {
+		pterm.Error.Println("Local topology configuration does not match the cluster")
+		return err
+	}
+	for _, warning := range warnings {
+		pterm.Warning.Println(warning)
+	}
+
+	n.startStep("Waiting for migrator lock")
+	if err := n.acquireLock(); err != nil {
+		pterm.Error.Println("Failed to acquire migrator lock")
+		return err
+	}
+	defer n.lock.Release()
+	pterm.Success.Printf("Acquired migrator lock %s\n", n.lock.Name)
+
+	n.startStep("Validating stored configuration")
+	if err := n.validateConfigs(); err != nil {
+		pterm.Error.Println("Stored configuration is not valid for the Kubernetes installation")
+		return err
+	}
+
+	n.startStep("Storing keystores and keys")
+	if err := n.storeSecrets(); err != nil {
+		pterm.Error.Println("Failed to store keystores and keys")
+		return err
+	}
+
+	n.startStep("Storing logging configuration")
+	if err := n.storeLogback(); err != nil {
+		pterm.Error.Println("Failed to store logging configuration")
+		return err
+	}
+
 	// TODO ValidateMountTargets needs a id to check against to validate cluster-wide ID matching
-	p.UpdateText("Validating storage rights")
+	n.startStep("Validating storage rights")
 	fsGroupId, err := n.ValidateMountTargets()
 	if err != nil {
 		pterm.Error.Println("Failed to validate storage access rights")
@@ -72,8 +121,40 @@ func (n *NodeMigrator) MigrateNode(p *pterm.SpinnerPrinter) error {
 	// TODO Store this fsGroupId to the configs
 	n.FSGroupId = fsGroupId
 
+	// TODO This should be modified in the cass-operator to make that function in two stages
+	//		to allow initialization from a []byte also. This is required to be initialized if we
+	//		wish to use advanced image configuration in this project
+	images.ParseImageConfig("/home/michael/image_config.yaml")
+
+	n.startStep("Reading scheduling constraints of node " + n.KubeNode)
+	if err := n.prepareScheduling(); err != nil {
+		pterm.Error.Println("Failed to derive the pod's tolerations and resources from the Kubernetes node")
+		return err
+	}
+	pterm.Success.Printf("Cassandra container resources: requests %s, limits %s\n", resourcesString(n.resources.Requests), resourcesString(n.resources.Limits))
+
+	n.startStep("Pulling images to node " + n.KubeNode)
+	if err := n.prePullImages(); err != nil {
+		pterm.Error.Println("Failed to pull the images to the Kubernetes node, the node was not drained")
+		return err
+	}
+	pterm.Success.Println("Pulled images to the Kubernetes node")
+
+	n.startStep("Creating a restore point of the current node")
+	if err := n.backupNode(); err != nil {
+		pterm.Error.Println("Failed to create a restore point, the node was not drained")
+		return err
+	}
+	pterm.Success.Println("Stored configuration backup of the local Cassandra node")
+
+	if err := n.lock.Lost(); err != nil {
+		pterm.Error.Println("Lost the migrator lock, the node was not drained")
+		return err
+	}
+
 	// Drain and shutdown the current node
-	p.UpdateText("Draining and shutting down the current node")
+	n.timer.setDowntime(true)
+	n.startStep("Draining and shutting down the current node")
 	if err := n.drainAndShutdownNode(); err != nil {
 		return err
 	}
@@ -82,34 +163,122 @@ func (n *NodeMigrator) MigrateNode(p *pterm.SpinnerPrinter) error {
 	// Parse configuration..
 
 	// Create PVC + PV
-	p.UpdateText("Mounting directories to Kubernetes")
+	n.startStep("Mounting directories to Kubernetes")
 	if err := n.createVolumeMounts(); err != nil {
 		return err
 	}
 	pterm.Success.Println("Mounted local directories to Kubernetes")
 
+	if err := n.lock.Lost(); err != nil {
+		pterm.Error.Println("Lost the migrator lock, the node is drained and stopped but the pod was not created. Start the local node or run the import again")
+		return err
+	}
+
 	// Create the pod
-	p.UpdateText("Creating pod that runs Cassandra in Kubernetes")
-	// TODO This should be modified in the cass-operator to make that function in two stages
-	//		to allow initialization from a []byte also. This is required to be initialized if we
-	//		wish to use advanced image configuration in this project
-	images.ParseImageConfig("/home/michael/image_config.yaml")
+	n.startStep("Creating pod that runs Cassandra in Kubernetes")
 	if err := n.CreatePod(); err != nil {
 		return err
 	}
+	n.podCreated = true
 
 	pterm.Success.Println("Created Cassandra pod to the Kubernetes")
 
 	// Run startCassandra on the node
-	p.UpdateText("Starting Cassandra node on the Kubernetes cluster")
+	n.startStep("Starting Cassandra node on the Kubernetes cluster")
 
 	if err := n.StartPod(); err != nil {
 		return err
 	}
 
+	n.timer.setDowntime(false)
 	pterm.Success.Println("Cassandra pod has successfully started")
 	// pterm.Warning.Println("Failed to start Cassandra node")
 
+	n.startStep("Verifying the pod against the node before the migration")
+	if err := n.verifyStartedPod(); err != nil {
+		pterm.Warning.Printf("Migrated pod could not be verified: %v. Use 'import verify %s' to check it again\n", err, n.Datacenter)
+	} else {
+		pterm.Success.Println("Host ID, tokens, tables and data sizes of the pod match the node before the migration and the schema is in agreement")
+	}
+
+	n.startStep("Disabling the Cassandra service on the host")
+	disabled, err := n.disableHostService()
+	n.backup.DisabledServices = disabled
+	if recordErr := recordBackup(n.Client, n.Namespace, n.Datacenter, n.backup); recordErr != nil && err == nil {
+		err = recordErr
+	}
+	if err != nil {
+		pterm.Error.Println("Cassandra pod is running, but the host service could not be disabled. Disable it manually before the host is restarted")
+		return err
+	}
+	pterm.Success.Printf("Disabled host services %v and guarded against manual starts\n", disabled)
+
+	return nil
+}
+
+// acquireLock waits for the migrator lock of the node's scope, using the scope and concurrency stored for the datacenter
+func (n *NodeMigrator) acquireLock() error {
+	lockConfig, err := storedLockConfig(n.Client, n.Namespace, n.Datacenter, n.Lock)
+	if err != nil {
+		return err
+	}
+
+	lock, err := AcquireMigrationLock(context.Background(), n.Namespace, lockConfig, n.Datacenter, n.Rack, func(holders []string) {
+		if n.p != nil {
+			n.p.UpdateText(fmt.Sprintf("Waiting for migrator lock, held by %s", strings.Join(holders, ", ")))
+		}
+	})
+	if err != nil {
+		return err
+	}
+	n.lock = lock
+
+	return nil
+}
+
+// validateConfigs validates the stored configuration before the local node is drained
+func (n *NodeMigrator) validateConfigs() error {
+	configs, _, err := fetchConfigFiles(n.Client, n.Namespace, n.Datacenter)
+	if err != nil {
+		return err
+	}
+
+	issues, err := ValidateConfigMap(configs, n.ServerType, n.ServerVersion)
+	if err != nil {
+		return err
+	}
+
+	return reportConfigIssues(issues)
+}
+
+// storeSecrets adds the local keystores and keys to the datacenter's Secrets
+func (n *NodeMigrator) storeSecrets() error {
+	if err := storeImportSecrets(n.Client, n.Namespace, n.Datacenter, n.configs); err != nil {
+		return err
+	}
+
+	secrets, err := existingImportSecrets(n.Client, n.Namespace, n.Datacenter)
+	if err != nil {
+		return err
+	}
+	n.secrets = secrets
+
+	return nil
+}
+
+// storeLogback stores the local logback.xml if no other node has stored one yet
+func (n *NodeMigrator) storeLogback() error {
+	if err := storeLogbackConfig(n.Client, n.Namespace, n.Datacenter, n.configs.logback); err != nil {
+		return err
+	}
+
+	volume, mount, err := existingLogbackConfig(n.Client, n.Namespace, n.Datacenter)
+	if err != nil {
+		return err
+	}
+	n.logbackVolume = volume
+	n.logbackMount = mount
+
 	return nil
 }
 
@@ -120,8 +289,12 @@ func (n *NodeMigrator) getNodetoolPath() string {
 	return fmt.Sprintf("%s/bin", n.CassandraHome)
 }
 
+func (n *NodeMigrator) nodetool(args ...string) (string, error) {
+	return runNodetool(n.Container, n.getNodetoolPath(), args...)
+}
+
 func (n *NodeMigrator) getNodeInfo(cassConfig map[string]interface{}) error {
-	output, err := execNodetool(n.getNodetoolPath(), "info")
+	output, err := n.nodetool("info")
 	if err != nil {
 		return err
 	}
@@ -143,16 +316,7 @@ func (n *NodeMigrator) getNodeInfo(cassConfig map[string]interface{}) error {
 		}
 	}
 
-	configMap := &corev1.ConfigMap{}
-	configMapKey := types.NamespacedName{Name: configMapName(n.Datacenter), Namespace: n.Namespace}
-	if err := n.Client.Get(context.TODO(), configMapKey, configMap); err != nil {
-		return err
-	}
-
-	b := configMap.BinaryData["clusterInfo"]
-
-	clusterConfigMap := ClusterConfigMap{}
-	err = json.Unmarshal(b, &clusterConfigMap)
+	clusterConfigMap, err := fetchClusterConfigMap(n.Client, n.Namespace, n.Datacenter)
 	if err != nil {
 		return err
 	}
@@ -171,6 +335,7 @@ func (n *NodeMigrator) getNodeInfo(cassConfig map[string]interface{}) error {
 	n.ServerType = clusterConfigMap.ServerType
 	n.ServerVersion = clusterConfigMap.ServerVersion
 	n.Cluster = clusterConfigMap.Cluster
+	n.DseWorkloads = clusterConfigMap.DseWorkloads
 
 	kubeNode, err := n.getLocalKubeNode(cassConfig)
 	if err != nil {
@@ -244,17 +409,43 @@ func (n *NodeMigrator) getLocalKubeNode(cassConfig map[string]interface{}) (stri
 		}
 	}
 
+	if n.Container != nil {
+		// The listen address of a container with bridged networking is not the host's address
+		if hostname, err := os.Hostname(); err == nil {
+			for _, node := range nodes.Items {
+				if node.Name == hostname || node.Labels[corev1.LabelHostname] == hostname {
+					return node.Name, nil
+				}
+			}
+		}
+	}
+
 	return "", fmt.Errorf("failed to find local Kubernetes node")
 }
 
 func (n *NodeMigrator) drainAndShutdownNode() error {
-	_, err := execNodetool(n.getNodetoolPath(), "drain")
+	if n.Container != nil {
+		// The runtime would restart the container once Cassandra exits
+		if err := n.Container.DisableRestart(); err != nil {
+			return err
+		}
+	}
+
+	_, err := n.nodetool("drain")
 	if err != nil {
 		return err
 	}
 
-	_, err = execNodetool(n.getNodetoolPath(), "stopdaemon")
-	return err
+	if err := n.captureBeforeState(); err != nil {
+		pterm.Warning.Printf("Unable to capture the state of the node, the migrated pod can not be verified: %v\n", err)
+	}
+
+	_, err = n.nodetool("stopdaemon")
+	if err != nil || n.Container == nil {
+		return err
+	}
+
+	return n.Container.Stop()
 }
 
 func (n *NodeMigrator) getGenerateName() string {
@@ -276,21 +467,34 @@ func (n *NodeMigrator) isSeed() bool {
 }
 
 func (n *NodeMigrator) CreatePod() error {
+	pod, err := n.buildPod()
+	if err != nil {
+		return err
+	}
+
+	if err := n.Client.Create(context.TODO(), pod); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (n *NodeMigrator) buildPod() (*corev1.Pod, error) {
 	enableServiceLinks := true
 
 	containers, err := n.buildContainers()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	initContainers, err := n.buildInitContainers()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	volumes, err := n.buildVolumes()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	userId := int64(999)
@@ -328,20 +532,23 @@ func (n *NodeMigrator) CreatePod() error {
 				RunAsGroup: &userGroup,
 				FSGroup:    &fsGroup,
 			},
-			Tolerations: []corev1.Toleration{},
+			Tolerations: n.tolerations,
 			Volumes:     volumes,
 		},
 	}
 
-	if err := n.Client.Create(context.TODO(), pod); err != nil {
-		return err
+	if n.PodNetwork {
+		// The volumes decide the node, adopted pods are not pinned
+		pod.Spec.HostNetwork = false
+		pod.Spec.DNSPolicy = corev1.DNSClusterFirst
+		pod.Spec.NodeName = ""
 	}
 
-	return nil
+	return pod, nil
 }
 
 func (n *NodeMigrator) podAffinity() *corev1.Affinity {
-	return &corev1.Affinity{
+	affinity := &corev1.Affinity{
 		NodeAffinity: &corev1.NodeAffinity{
 			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
 				NodeSelectorTerms: []corev1.NodeSelectorTerm{
@@ -381,11 +588,17 @@ func (n *NodeMigrator) podAffinity() *corev1.Affinity {
 			},
 		},
 	}
+
+	if n.PodNetwork {
+		affinity.NodeAffinity = nil
+	}
+
+	return affinity
 }
 
 func (n *NodeMigrator) StartPod() error {
-	// TODO Could we instead of host networking also use nodeReplace to replace all the existing nodes with the data we already have? Thus moving to Kubernetes
-	// networking? Perhaps investigate in CNI networking to see if we could have node visible in two networks
+	// The pod is started with host networking to keep the node reachable by the nodes that are not yet migrated. After the
+	// commit, NetworkMigrator can move the datacenter to pod networking
 
 	// Create ManagementClient
 	mgmtClient, err := NewManagementClient(context.TODO(), n.Client)
@@ -513,15 +726,13 @@ func (n *NodeMigrator) buildVolumes() ([]corev1.Volume, error) {
 
 	volumes = append(volumes, vServerLogs)
 
-	// vServerEncryption := corev1.Volume{
-	// 	Name: "encryption-cred-storage",
-	// 	VolumeSource: corev1.VolumeSource{
-	// 		Secret: &corev1.SecretVolumeSource{
-	// 			SecretName: fmt.Sprintf("%s-keystore", n.Datacenter),
-	// 		},
-	// 	},
-	// }
-	// volumes = append(volumes, vServerEncryption)
+	for _, secret := range n.secrets {
+		volumes = append(volumes, secret.Volume())
+	}
+
+	if n.logbackVolume != nil {
+		volumes = append(volumes, *n.logbackVolume)
+	}
 
 	return volumes, nil
 }
@@ -552,7 +763,7 @@ func (n *NodeMigrator) buildInitContainers() ([]corev1.Container, error) {
 	// serverCfg.Resources = *getResourcesOrDefault(&dc.Spec.ConfigBuilderResources, &DefaultsConfigInitContainer)
 
 	// Convert the bool to a string for the env var setting
-	useHostIpForBroadcast := "true"
+	useHostIpForBroadcast := strconv.FormatBool(!n.PodNetwork)
 
 	envDefaults := []corev1.EnvVar{
 		{Name: "POD_IP", ValueFrom: selectorFromFieldPath("status.podIP")},
@@ -590,6 +801,18 @@ func (n *NodeMigrator) getModelValues() serverconfig.NodeConfig {
 	solrEnabled := 0
 	sparkEnabled := 0
 
+	if n.ServerType == "dse" && n.DseWorkloads != nil {
+		if n.DseWorkloads.AnalyticsEnabled {
+			sparkEnabled = 1
+		}
+		if n.DseWorkloads.GraphEnabled {
+			graphEnabled = 1
+		}
+		if n.DseWorkloads.SearchEnabled {
+			solrEnabled = 1
+		}
+	}
+
 	modelValues := serverconfig.GetModelValues(
 		seeds,
 		n.Cluster,
@@ -615,16 +838,15 @@ func (n *NodeMigrator) getModelValues() serverconfig.NodeConfig {
 func (n *NodeMigrator) getConfigDataEnVars() ([]corev1.EnvVar, error) {
 	envVars := make([]corev1.EnvVar, 0)
 
-	configs := &corev1.ConfigMap{}
-	configMapKey := types.NamespacedName{Name: getConfigMapName(n.Datacenter, "cass-config"), Namespace: n.Namespace}
-	if err := n.Client.Get(context.TODO(), configMapKey, configs); err != nil {
+	_, files, err := fetchConfigFiles(n.Client, n.Namespace, n.Datacenter)
+	if err != nil {
 		return nil, err
 	}
 
 	modelValues := n.getModelValues()
 	// configsData := make(map[string]map[string]interface{})
 
-	for k, v := range configs.Data {
+	for k, v := range files {
 		yamlData := make(serverconfig.NodeConfig)
 		if err := yaml.Unmarshal([]byte(v), &yamlData); err != nil {
 			return nil, err
@@ -681,7 +903,7 @@ func (n *NodeMigrator) buildContainers() ([]corev1.Container, error) {
 	}
 	cassContainer.Image = serverImage
 
-	// TODO Container resource restrictions
+	cassContainer.Resources = n.resources
 
 	cassContainer.LivenessProbe = probe(8080, "/api/v0/probes/liveness", 15, 15)
 	cassContainer.ReadinessProbe = probe(8080, "/api/v0/probes/readiness", 20, 10)
@@ -712,11 +934,11 @@ func (n *NodeMigrator) buildContainers() ([]corev1.Container, error) {
 	}
 
 	// Extra DSE workloads
-	// if dc.Spec.ServerType == "dse" && dc.Spec.DseWorkloads != nil {
-	// 	envDefaults = append(
-	// 		envDefaults,
-	// 		corev1.EnvVar{Name: "JVM_EXTRA_OPTS", Value: getJvmExtraOpts(dc)})
-	// }
+	if n.ServerType == "dse" && n.DseWorkloads != nil {
+		envDefaults = append(
+			envDefaults,
+			corev1.EnvVar{Name: "JVM_EXTRA_OPTS", Value: jvmExtraOpts(n.DseWorkloads)})
+	}
 
 	// cassContainer.Env = combineEnvSlices(envDefaults, cassContainer.Env)
 
@@ -724,7 +946,7 @@ func (n *NodeMigrator) buildContainers() ([]corev1.Container, error) {
 
 	// Combine ports
 
-	portDefaults, err := GetContainerPorts()
+	portDefaults, err := GetContainerPorts(n.ServerType, n.DseWorkloads)
 	if err != nil {
 		return nil, err
 	}
@@ -753,12 +975,16 @@ func (n *NodeMigrator) buildContainers() ([]corev1.Container, error) {
 				Name:      PvcName,
 				MountPath: "/var/lib/cassandra",
 			},
-			// {
-			// 	Name:      "encryption-cred-storage",
-			// 	MountPath: "/etc/encryption/",
-			// },
 		}...)
 
+	for _, secret := range n.secrets {
+		volumeMounts = append(volumeMounts, secret.mounts...)
+	}
+
+	if n.logbackMount != nil {
+		volumeMounts = append(volumeMounts, *n.logbackMount)
+	}
+
 	// volumeMounts = append(volumeMounts, cassContainer.VolumeMounts)
 	// cassContainer.VolumeMounts = combineVolumeMountSlices(volumeMounts, generateStorageConfigVolumesMount(dc))
 	cassContainer.VolumeMounts = volumeMounts
@@ -790,35 +1016,15 @@ func probe(port int, path string, initDelay int, period int) *corev1.Probe {
 	}
 }
 
-func namedPort(name string, port int) corev1.ContainerPort {
-	return corev1.ContainerPort{Name: name, ContainerPort: int32(port)}
-}
-
 // GetContainerPorts will return the container ports for the pods in a statefulset based on the provided config
-func GetContainerPorts() ([]corev1.ContainerPort, error) {
-
-	nativePort := cassdcapi.DefaultNativePort
-	internodePort := cassdcapi.DefaultInternodePort
-
-	// Note: Port Names cannot be more than 15 characters
-
-	ports := []corev1.ContainerPort{
-		namedPort("native", nativePort),
-		namedPort("tls-native", 9142),
-		namedPort("internode", internodePort),
-		namedPort("tls-internode", 7001),
-		namedPort("jmx", 7199),
-		namedPort("mgmt-api-http", 8080),
-		namedPort("prometheus", 9103),
-		namedPort("thrift", 9160),
+func GetContainerPorts(serverType string, workloads *cassdcapi.DseWorkloads) ([]corev1.ContainerPort, error) {
+	// Use the same port definitions as cass-operator will use once the CassandraDatacenter is created
+	dc := &cassdcapi.CassandraDatacenter{
+		Spec: cassdcapi.CassandraDatacenterSpec{
+			ServerType:   serverType,
+			DseWorkloads: workloads,
+		},
 	}
 
-	// if dc.Spec.ServerType == "dse" {
-	// 	ports = append(
-	// 		ports,
-	// 		namedPort("internode-msg", 8609),
-	// 	)
-	// }
-
-	return ports, nil
+	return dc.GetContainerPorts()
 }
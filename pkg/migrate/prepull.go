@@ -0,0 +1,170 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	waitutil "k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	prePullContainerPrefix = "pull-"
+	prePullTimeout         = 10 * time.Minute
+)
+
+var imagePullFailures = []string{"ErrImagePull", "ImagePullBackOff", "InvalidImageName", "ErrImageNeverPull"}
+
+func (n *NodeMigrator) getPrePullPodName() string {
+	return n.getPodName() + "-prepull"
+}
+
+// podImages returns the images of the containers in the order they appear, without duplicates
+func podImages(containers ...[]corev1.Container) []string {
+	seen := make(map[string]bool)
+	imageList := make([]string, 0)
+	for _, list := range containers {
+		for _, container := range list {
+			if container.Image == "" || seen[container.Image] {
+				continue
+			}
+			seen[container.Image] = true
+			imageList = append(imageList, container.Image)
+		}
+	}
+	return imageList
+}
+
+// migrationImages returns every image the Cassandra pod will use
+func (n *NodeMigrator) migrationImages() ([]string, error) {
+	containers, err := n.buildContainers()
+	if err != nil {
+		return nil, err
+	}
+
+	initContainers, err := n.buildInitContainers()
+	if err != nil {
+		return nil, err
+	}
+
+	return podImages(initContainers, containers), nil
+}
+
+// prePullPod creates a pod pinned to the target node with a container per image. The containers exit immediately, the
+// kubelet only needs to pull the images to create them.
+func (n *NodeMigrator) prePullPod(imageList []string) *corev1.Pod {
+	containers := make([]corev1.Container, 0, len(imageList))
+	for i, image := range imageList {
+		containers = append(containers, corev1.Container{
+			Name:            fmt.Sprintf("%s%d", prePullContainerPrefix, i),
+			Image:           image,
+			ImagePullPolicy: corev1.PullIfNotPresent,
+			Command:         []string{"sh", "-c", "exit 0"},
+		})
+	}
+
+	var gracePeriod int64 = 0
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      n.getPrePullPodName(),
+			Namespace: n.Namespace,
+		},
+		Spec: corev1.PodSpec{
+			NodeName:                      n.KubeNode,
+			RestartPolicy:                 corev1.RestartPolicyNever,
+			TerminationGracePeriodSeconds: &gracePeriod,
+			Tolerations:                   n.tolerations,
+			Containers:                    containers,
+		},
+	}
+}
+
+// imagesPulled returns true once every container of the pod has been created, which requires the image to be present
+// on the node. A failed pull is returned as an error.
+func imagesPulled(pod *corev1.Pod) (bool, error) {
+	if len(pod.Status.ContainerStatuses) < len(pod.Spec.Containers) {
+		return false, nil
+	}
+
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Waiting != nil {
+			for _, reason := range imagePullFailures {
+				if status.State.Waiting.Reason == reason {
+					return false, fmt.Errorf("unable to pull image %s: %s %s", status.Image, reason, status.State.Waiting.Message)
+				}
+			}
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// prePullImages pulls the images of the Cassandra pod to the target node while the local node is still running, so
+// that the pull is not part of the downtime
+func (n *NodeMigrator) prePullImages() error {
+	imageList, err := n.migrationImages()
+	if err != nil {
+		return err
+	}
+
+	pod := n.prePullPod(imageList)
+	if err := n.Client.Create(context.TODO(), pod); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return err
+		}
+		// Left behind by an interrupted migration, replace it as the images could have changed
+		if err := n.deletePrePullPod(); err != nil {
+			return err
+		}
+		pod = n.prePullPod(imageList)
+		if err := n.Client.Create(context.TODO(), pod); err != nil {
+			return err
+		}
+	}
+
+	defer func() {
+		_ = n.deletePrePullPod()
+	}()
+
+	podKey := types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace}
+	err = waitutil.PollImmediate(2*time.Second, prePullTimeout, func() (bool, error) {
+		if err := n.Client.Get(context.TODO(), podKey, pod); err != nil {
+			return false, err
+		}
+		return imagesPulled(pod)
+	})
+	if err != nil {
+		return fmt.Errorf("images %s were not pulled to node %s: %w", strings.Join(imageList, ", "), n.KubeNode, err)
+	}
+
+	return nil
+}
+
+func (n *NodeMigrator) deletePrePullPod() error {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      n.getPrePullPodName(),
+			Namespace: n.Namespace,
+		},
+	}
+
+	if err := n.Client.Delete(context.TODO(), pod); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	// The name is reused on the next attempt, wait until the pod is gone
+	return waitutil.PollImmediate(time.Second, time.Minute, func() (bool, error) {
+		err := n.Client.Get(context.TODO(), types.NamespacedName{Name: pod.Name, Namespace: pod.Namespace}, &corev1.Pod{})
+		if errors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+}
@@ -0,0 +1,57 @@
+package migrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestPodImages(t *testing.T) {
+	require := require.New(t)
+
+	initContainers := []corev1.Container{{Name: "config", Image: "datastax/cass-config-builder:1.0.4"}}
+	containers := []corev1.Container{
+		{Name: "cassandra", Image: "k8ssandra/cass-management-api:4.0.3"},
+		{Name: "logger", Image: "k8ssandra/system-logger:v1.10.0"},
+		{Name: "other", Image: "datastax/cass-config-builder:1.0.4"},
+	}
+
+	require.Equal([]string{
+		"datastax/cass-config-builder:1.0.4",
+		"k8ssandra/cass-management-api:4.0.3",
+		"k8ssandra/system-logger:v1.10.0",
+	}, podImages(initContainers, containers))
+}
+
+func TestImagesPulled(t *testing.T) {
+	require := require.New(t)
+
+	n := &NodeMigrator{Cluster: "Test Cluster", Datacenter: "dc1", Rack: "r1", Ordinal: "0", KubeNode: "worker1", Namespace: "migrate"}
+	pod := n.prePullPod([]string{"image-a", "image-b"})
+	require.Equal("testcluster-dc1-r1-sts-0-prepull", pod.Name)
+	require.Equal("worker1", pod.Spec.NodeName)
+	require.Len(pod.Spec.Containers, 2)
+
+	pulled, err := imagesPulled(pod)
+	require.NoError(err)
+	require.False(pulled)
+
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+		{Name: "pull-0", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0}}},
+		{Name: "pull-1", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ContainerCreating"}}},
+	}
+	pulled, err = imagesPulled(pod)
+	require.NoError(err)
+	require.False(pulled)
+
+	pod.Status.ContainerStatuses[1].State = corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}
+	pulled, err = imagesPulled(pod)
+	require.NoError(err)
+	require.True(pulled)
+
+	pod.Status.ContainerStatuses[1].Image = "image-b"
+	pod.Status.ContainerStatuses[1].State = corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}}
+	_, err = imagesPulled(pod)
+	require.Error(err)
+}
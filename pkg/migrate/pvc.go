@@ -13,6 +13,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeexec "k8s.io/kubectl/pkg/cmd/exec"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	cassdcapi "github.com/k8ssandra/cass-operator/apis/cassandra/v1beta1"
@@ -26,6 +27,12 @@ type NodeMigrator struct {
 	DseConfigOverride  string
 	CassConfigOverride string
 
+	// Container is set if the local node runs in a Docker or Podman container
+	Container *ContainerInstallation
+
+	// ExecOptions runs nodetool in the migrated pod to verify it against the local node
+	ExecOptions *kubeexec.ExecOptions
+
 	configs *ConfigParser
 
 	// Nodetool describecluster has this information (cluster name)
@@ -43,9 +50,49 @@ type NodeMigrator struct {
 
 	ServerType    string
 	ServerVersion string
+	DseWorkloads  *cassdcapi.DseWorkloads
 
 	FSGroupId int
 
+	// Snapshot takes a nodetool snapshot before the node is drained
+	Snapshot bool
+
+	// TolerateTaints confirms adding tolerations for the taints of the target node
+	TolerateTaints bool
+
+	// PodNetwork runs the pod in the pod network without pinning it to KubeNode. Pods adopted from a StatefulSet
+	// already use the pod network.
+	PodNetwork bool
+
+	// tolerations and resources are derived from the target node
+	tolerations []corev1.Toleration
+	resources   corev1.ResourceRequirements
+
+	// Lock defines which other nodes can be migrated at the same time
+	Lock LockConfig
+
+	// lock is held from the node information lookup until the migration has finished
+	lock *MigrationLock
+
+	// backup is the restore point taken before the node was drained
+	backup NodeBackup
+
+	// verification has the state of the drained node, compared to the started pod
+	verification *NodeVerification
+
+	// secrets are the Secrets created from the local keystores and keys
+	secrets []importSecret
+
+	// logbackVolume and logbackMount mount the stored logback.xml, nil if the node had none
+	logbackVolume *corev1.Volume
+	logbackMount  *corev1.VolumeMount
+
+	// timer measures the migration steps
+	timer *stepTimer
+
+	// podCreated is set once the pod exists, the audit Events are then also emitted for the pod
+	podCreated bool
+
 	p *pterm.SpinnerPrinter
 }
 
@@ -71,9 +118,7 @@ func parseDataPaths(cassandraYaml map[string]interface{}) ([]string, map[string]
 	additionalDirectories := make(map[string]string)
 	dataDirectories := make([]string, 0)
 
-	// TODO We will need additional mounts at least for:
-	//		dse.yaml => system_key_directory (encryption keys - how do we handle this?)
-	//		dse.yaml => kerberos-file paths (or how does cass-config-builder handle it?)
+	// dse.yaml's system_key_directory and Kerberos keytab are stored as Secrets, see parseDseSecretFiles
 
 	for key, val := range cassandraYaml {
 		if strings.HasSuffix(key, "_directory") {
@@ -89,8 +134,35 @@ func parseDataPaths(cassandraYaml map[string]interface{}) ([]string, map[string]
 	return dataDirectories, additionalDirectories, nil
 }
 
-func (n *NodeMigrator) ValidateMountTargets() (int, error) {
+// hostDataPaths returns the data directories on the host. If the node runs in a container, the directories are the
+// bind mounted host directories.
+func (n *NodeMigrator) hostDataPaths() ([]string, map[string]string, error) {
 	dataDirs, additionalDirs, err := parseDataPaths(n.configs.CassYaml())
+	if err != nil || n.Container == nil {
+		return dataDirs, additionalDirs, err
+	}
+
+	for i, dir := range dataDirs {
+		hostPath, err := n.Container.HostPath(dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		dataDirs[i] = hostPath
+	}
+
+	for key, dir := range additionalDirs {
+		hostPath, err := n.Container.HostPath(dir)
+		if err != nil {
+			return nil, nil, err
+		}
+		additionalDirs[key] = hostPath
+	}
+
+	return dataDirs, additionalDirs, nil
+}
+
+func (n *NodeMigrator) ValidateMountTargets() (int, error) {
+	dataDirs, additionalDirs, err := n.hostDataPaths()
 	if err != nil {
 		return -1, err
 	}
@@ -155,7 +227,7 @@ func GetFsGroup(path string) (uint32, error) {
 }
 
 func (n *NodeMigrator) FixGroupRights() error {
-	dataDirs, additionalDirs, err := parseDataPaths(n.configs.CassYaml())
+	dataDirs, additionalDirs, err := n.hostDataPaths()
 	if err != nil {
 		return err
 	}
@@ -203,7 +275,7 @@ func FixDirectoryRights(path string) error {
 }
 
 func (n *NodeMigrator) createVolumeMounts() error {
-	dataDirs, additionalDirs, err := parseDataPaths(n.configs.CassYaml())
+	dataDirs, additionalDirs, err := n.hostDataPaths()
 	if err != nil {
 		return err
 	}
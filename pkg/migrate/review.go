@@ -0,0 +1,317 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/burmanm/k8ssandra-client/pkg/editor"
+	"github.com/pterm/pterm"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	// nodeSpecificKeys are expected to differ between the nodes and are not reported by the diff
+	nodeSpecificKeys = []string{
+		"listen_address",
+		"listen_interface",
+		"rpc_address",
+		"rpc_interface",
+		"broadcast_address",
+		"broadcast_rpc_address",
+		"initial_token",
+		"seed_provider",
+	}
+)
+
+// ConfigReviewer is used to review and modify the stored configuration before the nodes are migrated
+type ConfigReviewer struct {
+	client.Client
+	namespace  string
+	datacenter string
+}
+
+func NewConfigReviewer(cli client.Client, namespace, datacenter string) *ConfigReviewer {
+	return &ConfigReviewer{
+		Client:     cli,
+		namespace:  namespace,
+		datacenter: datacenter,
+	}
+}
+
+// Show writes the stored configuration per file
+func (c *ConfigReviewer) Show(w io.Writer) error {
+	_, files, err := fetchConfigFiles(c.Client, c.namespace, c.datacenter)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(w, "# %s\n%s\n", definitionsFileName(name), files[name])
+	}
+
+	return nil
+}
+
+// Diff compares the stored configuration to the configuration of the local node and writes the differences. Returns
+// true if there were differences.
+func (c *ConfigReviewer) Diff(w io.Writer, cassConfigDir, dseConfigDir, cassandraHome string) (bool, error) {
+	_, files, err := fetchConfigFiles(c.Client, c.namespace, c.datacenter)
+	if err != nil {
+		return false, err
+	}
+
+	cfgParser := NewParser()
+	if err := cfgParser.ParseConfigDirectories(cassConfigDir, dseConfigDir, cassandraHome); err != nil {
+		return false, err
+	}
+
+	if err := cfgParser.ParseConfigs(); err != nil {
+		return false, err
+	}
+
+	// Apply the same modifications as the import does before storing the configs
+	parseEncryptionStores(cfgParser.CassYaml())
+	if dseYaml := cfgParser.DseYaml(); dseYaml != nil {
+		if _, err := parseDseSecretFiles(dseYaml, cfgParser.DseHome(), cfgParser.localFile); err != nil {
+			return false, err
+		}
+	}
+
+	stored := make(map[string]map[string]interface{}, len(files))
+	for name, data := range files {
+		yamlConf := make(map[string]interface{})
+		if err := yaml.Unmarshal([]byte(data), &yamlConf); err != nil {
+			return false, err
+		}
+		stored[name] = yamlConf
+	}
+
+	local := make(map[string]map[string]interface{}, len(cfgParser.Yamls()))
+	for name, yamlConf := range cfgParser.Yamls() {
+		// Roundtrip to get the same types as the stored ones
+		out, err := yaml.Marshal(yamlConf)
+		if err != nil {
+			return false, err
+		}
+		localConf := make(map[string]interface{})
+		if err := yaml.Unmarshal(out, &localConf); err != nil {
+			return false, err
+		}
+		local[name] = localConf
+	}
+
+	differences := diffConfigs(stored, local)
+	for _, difference := range differences {
+		fmt.Fprintln(w, difference)
+	}
+
+	return len(differences) > 0, nil
+}
+
+// diffConfigs returns the differences between the stored and local configuration as "-" (stored) and "+" (local) lines
+func diffConfigs(stored, local map[string]map[string]interface{}) []string {
+	names := make(map[string]bool)
+	for name := range stored {
+		names[name] = true
+	}
+	for name := range local {
+		names[name] = true
+	}
+
+	differences := make([]string, 0)
+	for _, name := range sortedKeys(names) {
+		storedValues := flattenConfig("", stored[name])
+		localValues := flattenConfig("", local[name])
+
+		keys := make(map[string]bool)
+		for key := range storedValues {
+			keys[key] = true
+		}
+		for key := range localValues {
+			keys[key] = true
+		}
+
+		fileDiffs := make([]string, 0)
+		for _, key := range sortedKeys(keys) {
+			if isNodeSpecificKey(key) {
+				continue
+			}
+
+			storedValue, inStored := storedValues[key]
+			localValue, inLocal := localValues[key]
+			if inStored && inLocal && reflect.DeepEqual(storedValue, localValue) {
+				continue
+			}
+
+			if inStored {
+				fileDiffs = append(fileDiffs, fmt.Sprintf("- %s: %v", key, storedValue))
+			}
+			if inLocal {
+				fileDiffs = append(fileDiffs, fmt.Sprintf("+ %s: %v", key, localValue))
+			}
+		}
+
+		if len(fileDiffs) > 0 {
+			differences = append(differences, fmt.Sprintf("# %s", definitionsFileName(name)))
+			differences = append(differences, fileDiffs...)
+		}
+	}
+
+	return differences
+}
+
+// flattenConfig returns the nested settings with dot separated keys, such as client_encryption_options.enabled
+func flattenConfig(prefix string, yamlConf map[string]interface{}) map[string]interface{} {
+	values := make(map[string]interface{})
+	for key, value := range yamlConf {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			for nestedKey, nestedValue := range flattenConfig(key, nested) {
+				values[nestedKey] = nestedValue
+			}
+			continue
+		}
+		values[key] = value
+	}
+	return values
+}
+
+func isNodeSpecificKey(key string) bool {
+	rootKey := strings.SplitN(key, ".", 2)[0]
+	for _, nodeKey := range nodeSpecificKeys {
+		if rootKey == nodeKey {
+			return true
+		}
+	}
+	return false
+}
+
+// Edit opens the stored configuration in an editor and stores the modified configuration if it passes the validation.
+// If the modified configuration is invalid, the editor is reopened until the issues are fixed or the file is saved
+// without modifications.
+func (c *ConfigReviewer) Edit() error {
+	configs, files, err := fetchConfigFiles(c.Client, c.namespace, c.datacenter)
+	if err != nil {
+		return err
+	}
+
+	clusterConfigMap, err := fetchClusterConfigMap(c.Client, c.namespace, c.datacenter)
+	if err != nil {
+		return err
+	}
+
+	original, err := configsToDocument(files)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp("", fmt.Sprintf("%s-*.yaml", configs.Name))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(original); err != nil {
+		f.Close()
+		return err
+	}
+	f.Close()
+
+	previous := original
+	for {
+		if err := editor.OpenEditor(f.Name()); err != nil {
+			return err
+		}
+
+		modified, err := os.ReadFile(f.Name())
+		if err != nil {
+			return err
+		}
+
+		if bytes.Equal(modified, previous) {
+			if bytes.Equal(modified, original) {
+				pterm.Info.Println("Configuration was not modified")
+				return nil
+			}
+			return fmt.Errorf("configuration has invalid settings, edit cancelled")
+		}
+		previous = modified
+
+		data, err := documentToConfigs(modified)
+		if err != nil {
+			pterm.Error.Printf("Unable to parse the modified configuration: %v\n", err)
+			continue
+		}
+
+		issues, err := ValidateConfigMap(&corev1.ConfigMap{Data: data}, clusterConfigMap.ServerType, clusterConfigMap.ServerVersion)
+		if err != nil {
+			pterm.Error.Printf("Unable to parse the modified configuration: %v\n", err)
+			continue
+		}
+
+		if err := reportConfigIssues(issues); err != nil {
+			pterm.Error.Println(err)
+			continue
+		}
+
+		if err := setConfigFiles(configs, data); err != nil {
+			return err
+		}
+		return c.Client.Update(context.TODO(), configs)
+	}
+}
+
+// configsToDocument renders the ConfigMap data as a single YAML document with the files as the root keys
+func configsToDocument(data map[string]string) ([]byte, error) {
+	document := make(map[string]interface{}, len(data))
+	for name, content := range data {
+		yamlConf := make(map[string]interface{})
+		if err := yaml.Unmarshal([]byte(content), &yamlConf); err != nil {
+			return nil, err
+		}
+		document[name] = yamlConf
+	}
+	return yaml.Marshal(document)
+}
+
+func documentToConfigs(document []byte) (map[string]string, error) {
+	files := make(map[string]map[string]interface{})
+	if err := yaml.Unmarshal(document, &files); err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]string, len(files))
+	for name, yamlConf := range files {
+		out, err := yaml.Marshal(yamlConf)
+		if err != nil {
+			return nil, err
+		}
+		data[name] = string(out)
+	}
+	return data, nil
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,62 @@
+package migrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	require := require.New(t)
+
+	stored := map[string]map[string]interface{}{
+		cassYamlKey: {
+			"num_tokens":     16,
+			"listen_address": "10.0.0.1",
+			"client_encryption_options": map[string]interface{}{
+				"enabled": false,
+			},
+		},
+		"jvm-server-options": {
+			"max_heap_size": "8G",
+		},
+	}
+
+	local := map[string]map[string]interface{}{
+		cassYamlKey: {
+			"num_tokens":     16,
+			"listen_address": "10.0.0.2",
+			"client_encryption_options": map[string]interface{}{
+				"enabled": true,
+			},
+		},
+		"jvm-server-options": {
+			"max_heap_size": "8G",
+		},
+	}
+
+	require.Equal([]string{
+		"# cassandra.yaml",
+		"- client_encryption_options.enabled: false",
+		"+ client_encryption_options.enabled: true",
+	}, diffConfigs(stored, local))
+
+	local[cassYamlKey]["client_encryption_options"].(map[string]interface{})["enabled"] = false
+	require.Empty(diffConfigs(stored, local))
+}
+
+func TestConfigDocumentRoundtrip(t *testing.T) {
+	require := require.New(t)
+
+	data := map[string]string{
+		cassYamlKey:          "num_tokens: 16\n",
+		"jvm-server-options": "max_heap_size: 8G\n",
+	}
+
+	document, err := configsToDocument(data)
+	require.NoError(err)
+
+	parsed, err := documentToConfigs(document)
+	require.NoError(err)
+	require.Equal(data, parsed)
+}
@@ -0,0 +1,285 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pterm/pterm"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var (
+	// minimumOffHeap is the memory left for the off-heap structures, metaspace and thread stacks on top of the heap
+	minimumOffHeap = resource.MustParse("1Gi")
+
+	// defaultMemoryRequest is requested when the heap is calculated by Cassandra. There's no limit, so Cassandra sizes
+	// the heap from the node's memory as it did on the host.
+	defaultMemoryRequest = resource.MustParse("4Gi")
+
+	// defaultCPURequest is the CPU requested for the Cassandra container, the container is not limited
+	defaultCPURequest = resource.MustParse("1")
+)
+
+// taintTolerations returns a toleration for each taint of the node
+func taintTolerations(taints []corev1.Taint) []corev1.Toleration {
+	tolerations := make([]corev1.Toleration, 0, len(taints))
+	for _, taint := range taints {
+		toleration := corev1.Toleration{
+			Key:      taint.Key,
+			Operator: corev1.TolerationOpEqual,
+			Value:    taint.Value,
+			Effect:   taint.Effect,
+		}
+		if taint.Value == "" {
+			toleration.Operator = corev1.TolerationOpExists
+		}
+		tolerations = append(tolerations, toleration)
+	}
+	return tolerations
+}
+
+func taintsString(taints []corev1.Taint) string {
+	parts := make([]string, 0, len(taints))
+	for _, taint := range taints {
+		parts = append(parts, taint.ToString())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// parseHeapSize parses the cass-config-builder heap size, such as 8G or 8192M, to bytes
+func parseHeapSize(value string) (int64, error) {
+	if !heapSizeValue.MatchString(value) {
+		return 0, fmt.Errorf("invalid heap size %s", value)
+	}
+
+	multiplier := int64(1)
+	switch strings.ToUpper(value[len(value)-1:]) {
+	case "K":
+		multiplier = 1024
+	case "M":
+		multiplier = 1024 * 1024
+	case "G":
+		multiplier = 1024 * 1024 * 1024
+	}
+	if multiplier > 1 {
+		value = value[:len(value)-1]
+	}
+
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return size * multiplier, nil
+}
+
+// storedMaxHeap returns the max_heap_size of the stored JVM options, zero if the heap is calculated by Cassandra
+func storedMaxHeap(files map[string]string) (int64, error) {
+	for _, key := range []string{jvmOptionsKey, jvmServerOptionsKey} {
+		content, found := files[key]
+		if !found {
+			continue
+		}
+		options := make(map[string]interface{})
+		if err := yaml.Unmarshal([]byte(content), options); err != nil {
+			return 0, err
+		}
+		if value, found := options["max_heap_size"]; found {
+			return parseHeapSize(fmt.Sprintf("%v", value))
+		}
+	}
+	return 0, nil
+}
+
+// containerResources returns the resources of the Cassandra container. Memory is twice the heap, leaving the rest for
+// the off-heap structures and the page cache, but never more than is available on the node. Without a set heap, a fixed
+// amount of memory is requested and the container is not limited. CPU is only requested, Cassandra is sensitive to CPU
+// throttling.
+func containerResources(availableMemory, availableCPU resource.Quantity, maxHeap int64) (corev1.ResourceRequirements, error) {
+	resources := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceMemory: minQuantity(defaultMemoryRequest, availableMemory),
+		},
+	}
+
+	if maxHeap > 0 {
+		heap := resource.NewQuantity(maxHeap, resource.BinarySI)
+		required := heap.DeepCopy()
+		required.Add(minimumOffHeap)
+		if availableMemory.Cmp(required) < 0 {
+			return corev1.ResourceRequirements{}, fmt.Errorf("node has %s memory available, the heap of %s requires at least %s", availableMemory.String(), heap.String(), required.String())
+		}
+
+		memory := minQuantity(*resource.NewQuantity(2*maxHeap, resource.BinarySI), availableMemory)
+		resources.Requests[corev1.ResourceMemory] = memory
+		resources.Limits = corev1.ResourceList{
+			corev1.ResourceMemory: memory,
+		}
+	}
+
+	if availableCPU.Sign() > 0 {
+		resources.Requests[corev1.ResourceCPU] = minQuantity(defaultCPURequest, availableCPU)
+	}
+
+	return resources, nil
+}
+
+func minQuantity(a, b resource.Quantity) resource.Quantity {
+	if b.Cmp(a) < 0 {
+		return b.DeepCopy()
+	}
+	return a.DeepCopy()
+}
+
+// availableResources returns the allocatable memory and CPU of the node minus the requests of the pods already running
+// on it. The pods of this migration are not counted, they're replaced.
+func (n *NodeMigrator) availableResources(node *corev1.Node) (resource.Quantity, resource.Quantity, error) {
+	memory := node.Status.Allocatable.Memory().DeepCopy()
+	cpu := node.Status.Allocatable.Cpu().DeepCopy()
+
+	pods := &corev1.PodList{}
+	if err := n.Client.List(context.TODO(), pods, client.MatchingFields{"spec.nodeName": node.Name}); err != nil {
+		return memory, cpu, err
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName != node.Name || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		if pod.Namespace == n.Namespace && (pod.Name == n.getPodName() || pod.Name == n.getPrePullPodName()) {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			memory.Sub(*container.Resources.Requests.Memory())
+			cpu.Sub(*container.Resources.Requests.Cpu())
+		}
+	}
+
+	return memory, cpu, nil
+}
+
+// prepareScheduling reads the taints and free resources of the target node. The pod tolerates the node's taints only if
+// TolerateTaints is set.
+func (n *NodeMigrator) prepareScheduling() error {
+	node := &corev1.Node{}
+	if err := n.Client.Get(context.TODO(), types.NamespacedName{Name: n.KubeNode}, node); err != nil {
+		return err
+	}
+
+	if len(node.Spec.Taints) > 0 {
+		if !n.TolerateTaints {
+			return fmt.Errorf("node %s has taints %s, confirm adding the matching tolerations with --tolerate-node-taints", n.KubeNode, taintsString(node.Spec.Taints))
+		}
+		n.tolerations = taintTolerations(node.Spec.Taints)
+		pterm.Info.Printf("Tolerating taints %s of node %s\n", taintsString(node.Spec.Taints), n.KubeNode)
+	}
+
+	_, files, err := fetchConfigFiles(n.Client, n.Namespace, n.Datacenter)
+	if err != nil {
+		return err
+	}
+
+	maxHeap, err := storedMaxHeap(files)
+	if err != nil {
+		return err
+	}
+
+	memory, cpu, err := n.availableResources(node)
+	if err != nil {
+		return err
+	}
+
+	resources, err := containerResources(memory, cpu, maxHeap)
+	if err != nil {
+		return err
+	}
+	n.resources = resources
+
+	return nil
+}
+
+// datacenterScheduling returns the resources and tolerations for the CassandraDatacenter from the migrated pods. Every
+// pod of the datacenter gets the same resources, so the smallest requests of the pods are used, which fit every node.
+// The limits decide how much memory Cassandra has, nodes with different limits can not be merged to one datacenter.
+// Every toleration of any pod is kept.
+func datacenterScheduling(pods []corev1.Pod) (corev1.ResourceRequirements, []corev1.Toleration, error) {
+	resources := corev1.ResourceRequirements{}
+	tolerations := make([]corev1.Toleration, 0)
+	limits := make([]string, 0, len(pods))
+	uniformLimits := true
+
+	for i, pod := range pods {
+		for _, container := range pod.Spec.Containers {
+			if container.Name != CassandraContainerName {
+				continue
+			}
+			if i == 0 {
+				resources.Limits = container.Resources.Limits.DeepCopy()
+				resources.Requests = container.Resources.Requests.DeepCopy()
+			} else {
+				if !equality.Semantic.DeepEqual(resources.Limits, container.Resources.Limits) {
+					uniformLimits = false
+				}
+				resources.Requests = minResources(resources.Requests, container.Resources.Requests)
+			}
+			limits = append(limits, fmt.Sprintf("%s (%s)", pod.Name, resourcesString(container.Resources.Limits)))
+		}
+
+	Tolerations:
+		for _, toleration := range pod.Spec.Tolerations {
+			for _, existing := range tolerations {
+				if existing.MatchToleration(&toleration) {
+					continue Tolerations
+				}
+			}
+			tolerations = append(tolerations, toleration)
+		}
+	}
+
+	if !uniformLimits {
+		return resources, tolerations, fmt.Errorf("migrated pods have different resource limits because their nodes differ in size: %s. Set the same max_heap_size for every node or migrate the nodes of different sizes to separate datacenters", strings.Join(limits, ", "))
+	}
+
+	return resources, tolerations, nil
+}
+
+// minResources returns the smaller quantity of each resource. A resource missing from any of the lists is not kept.
+func minResources(current, other corev1.ResourceList) corev1.ResourceList {
+	result := corev1.ResourceList{}
+	for name, quantity := range current {
+		if otherQuantity, found := other[name]; found {
+			result[name] = minQuantity(quantity, otherQuantity)
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// resourcesString formats the resource list as cpu=4, memory=16Gi
+func resourcesString(list corev1.ResourceList) string {
+	if len(list) == 0 {
+		return "none"
+	}
+
+	names := make([]string, 0, len(list))
+	for name := range list {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		quantity := list[corev1.ResourceName(name)]
+		parts = append(parts, fmt.Sprintf("%s=%s", name, quantity.String()))
+	}
+	return strings.Join(parts, ", ")
+}
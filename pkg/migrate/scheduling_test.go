@@ -0,0 +1,116 @@
+package migrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestTaintTolerations(t *testing.T) {
+	require := require.New(t)
+
+	tolerations := taintTolerations([]corev1.Taint{
+		{Key: "dedicated", Value: "cassandra", Effect: corev1.TaintEffectNoSchedule},
+		{Key: "storage", Effect: corev1.TaintEffectNoExecute},
+	})
+
+	require.Equal([]corev1.Toleration{
+		{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "cassandra", Effect: corev1.TaintEffectNoSchedule},
+		{Key: "storage", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute},
+	}, tolerations)
+}
+
+func TestParseHeapSize(t *testing.T) {
+	require := require.New(t)
+
+	size, err := parseHeapSize("8G")
+	require.NoError(err)
+	require.Equal(int64(8*1024*1024*1024), size)
+
+	size, err = parseHeapSize("512m")
+	require.NoError(err)
+	require.Equal(int64(512*1024*1024), size)
+
+	_, err = parseHeapSize("8GB")
+	require.Error(err)
+
+	heap, err := storedMaxHeap(map[string]string{jvmServerOptionsKey: "max_heap_size: 4G\n"})
+	require.NoError(err)
+	require.Equal(int64(4*1024*1024*1024), heap)
+
+	heap, err = storedMaxHeap(map[string]string{"cassandra-yaml": "num_tokens: 16\n"})
+	require.NoError(err)
+	require.Equal(int64(0), heap)
+}
+
+func TestContainerResources(t *testing.T) {
+	require := require.New(t)
+
+	gi := int64(1024 * 1024 * 1024)
+
+	// Twice the heap fits to the node
+	resources, err := containerResources(resource.MustParse("30Gi"), resource.MustParse("7500m"), 8*gi)
+	require.NoError(err)
+	require.Equal("memory=16Gi", resourcesString(resources.Limits))
+	require.Equal("cpu=1, memory=16Gi", resourcesString(resources.Requests))
+
+	// Capped to the available memory
+	resources, err = containerResources(resource.MustParse("12Gi"), resource.MustParse("500m"), 8*gi)
+	require.NoError(err)
+	require.Equal("memory=12Gi", resourcesString(resources.Limits))
+	require.Equal("cpu=500m, memory=12Gi", resourcesString(resources.Requests))
+
+	// Heap is calculated by Cassandra from the node's memory
+	resources, err = containerResources(resource.MustParse("12Gi"), resource.MustParse("4"), 0)
+	require.NoError(err)
+	require.Empty(resources.Limits)
+	require.Equal("cpu=1, memory=4Gi", resourcesString(resources.Requests))
+
+	_, err = containerResources(resource.MustParse("8Gi"), resource.MustParse("4"), 8*gi)
+	require.Error(err)
+}
+
+func TestDatacenterScheduling(t *testing.T) {
+	require := require.New(t)
+
+	pod := func(request, limit string, tolerations ...corev1.Toleration) corev1.Pod {
+		resources := corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(request)},
+		}
+		if limit != "" {
+			resources.Limits = corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(limit)}
+		}
+		return corev1.Pod{
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name:      CassandraContainerName,
+						Resources: resources,
+					},
+					{Name: SystemLoggerContainerName},
+				},
+				Tolerations: tolerations,
+			},
+		}
+	}
+
+	dedicated := corev1.Toleration{Key: "dedicated", Operator: corev1.TolerationOpEqual, Value: "cassandra", Effect: corev1.TaintEffectNoSchedule}
+	storage := corev1.Toleration{Key: "storage", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoExecute}
+
+	resources, tolerations, err := datacenterScheduling([]corev1.Pod{pod("16Gi", "16Gi", dedicated), pod("16Gi", "16Gi", dedicated)})
+	require.NoError(err)
+	require.Equal("memory=16Gi", resourcesString(resources.Limits))
+	require.Equal([]corev1.Toleration{dedicated}, tolerations)
+
+	// Requests can differ, the smallest fits every node
+	resources, tolerations, err = datacenterScheduling([]corev1.Pod{pod("6Gi", "", dedicated), pod("4Gi", "", storage)})
+	require.NoError(err)
+	require.Equal("memory=4Gi", resourcesString(resources.Requests))
+	require.Empty(resources.Limits)
+	require.Equal([]corev1.Toleration{dedicated, storage}, tolerations)
+
+	_, _, err = datacenterScheduling([]corev1.Pod{pod("12Gi", "12Gi"), pod("16Gi", "16Gi")})
+	require.Error(err)
+}
@@ -0,0 +1,181 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	cassdcapi "github.com/k8ssandra/cass-operator/apis/cassandra/v1beta1"
+	"github.com/pterm/pterm"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// importSecret is a Secret created by the import process from local files. It is mounted to the Cassandra container
+// of the migrated pods and the CassandraDatacenter
+type importSecret struct {
+	secretName string
+	volumeName string
+	mounts     []corev1.VolumeMount
+}
+
+func (s importSecret) Volume() corev1.Volume {
+	return corev1.Volume{
+		Name: s.volumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{
+				SecretName: s.secretName,
+			},
+		},
+	}
+}
+
+// importSecrets lists all the Secrets the import process could create for the datacenter
+func importSecrets(datacenter string) []importSecret {
+	return []importSecret{
+		{
+			secretName: encryptionSecretName(datacenter),
+			volumeName: EncryptionVolumeName,
+			mounts: []corev1.VolumeMount{
+				{
+					Name:      EncryptionVolumeName,
+					MountPath: EncryptionMountPath,
+					ReadOnly:  true,
+				},
+			},
+		},
+		{
+			secretName: systemKeySecretName(datacenter),
+			volumeName: SystemKeyVolumeName,
+			mounts: []corev1.VolumeMount{
+				{
+					Name:      SystemKeyVolumeName,
+					MountPath: SystemKeyMountPath,
+					ReadOnly:  true,
+				},
+			},
+		},
+		{
+			secretName: kerberosSecretName(datacenter),
+			volumeName: KerberosVolumeName,
+			mounts: []corev1.VolumeMount{
+				{
+					Name:      KerberosVolumeName,
+					MountPath: KerberosMountPath,
+					ReadOnly:  true,
+				},
+				{
+					Name:      KerberosVolumeName,
+					MountPath: krb5ConfPath,
+					SubPath:   krb5ConfKey,
+					ReadOnly:  true,
+				},
+			},
+		},
+	}
+}
+
+// existingImportSecrets returns the Secrets that have been created for the datacenter
+func existingImportSecrets(cli client.Client, namespace, datacenter string) ([]importSecret, error) {
+	secrets := make([]importSecret, 0)
+	for _, s := range importSecrets(datacenter) {
+		secret := &corev1.Secret{}
+		secretKey := types.NamespacedName{Name: s.secretName, Namespace: namespace}
+		if err := cli.Get(context.TODO(), secretKey, secret); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		mounts := make([]corev1.VolumeMount, 0, len(s.mounts))
+		for _, mount := range s.mounts {
+			if _, found := secret.Data[mount.SubPath]; mount.SubPath != "" && !found {
+				// Mounting a missing key would prevent the pod from starting
+				continue
+			}
+			mounts = append(mounts, mount)
+		}
+		s.mounts = mounts
+
+		secrets = append(secrets, s)
+	}
+
+	return secrets, nil
+}
+
+// storeSecretData creates the Secret from the local files. If the Secret already exists, the missing keys are added
+// and the existing ones are verified to match the local node.
+func storeSecretData(cli client.Client, namespace, name string, data map[string][]byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	secret := &corev1.Secret{}
+	secretKey := types.NamespacedName{Name: name, Namespace: namespace}
+	if err := cli.Get(context.TODO(), secretKey, secret); err != nil && !errors.IsNotFound(err) {
+		return err
+	} else if errors.IsNotFound(err) {
+		secret.ObjectMeta.Name = secretKey.Name
+		secret.ObjectMeta.Namespace = secretKey.Namespace
+		secret.Data = data
+		return cli.Create(context.TODO(), secret)
+	}
+
+	updated := false
+	for key, content := range data {
+		existing, found := secret.Data[key]
+		if !found {
+			if secret.Data == nil {
+				secret.Data = make(map[string][]byte)
+			}
+			secret.Data[key] = content
+			updated = true
+		} else if !bytes.Equal(existing, content) {
+			pterm.Warning.Printf("Local %s differs from the one stored in the Secret %s, the stored one is used by all the nodes\n", key, secretKey.Name)
+		}
+	}
+
+	if updated {
+		return cli.Update(context.TODO(), secret)
+	}
+
+	return nil
+}
+
+func importSecretName(datacenter, suffix string) string {
+	return fmt.Sprintf("%s-%s", cassdcapi.CleanupForKubernetes(datacenter), suffix)
+}
+
+// storeImportSecrets copies the local keystores, system keys and Kerberos files to Secrets and rewrites the paths in
+// the parsed configuration files to point to the Secrets mounted in the pod
+func storeImportSecrets(cli client.Client, namespace, datacenter string, cfgParser *ConfigParser) error {
+	stores := parseEncryptionStores(cfgParser.CassYaml())
+	encryptionData, err := readEncryptionStores(cfgParser.CassandraHome(), stores, cfgParser.localFile)
+	if err != nil {
+		return err
+	}
+
+	if dseYaml := cfgParser.DseYaml(); dseYaml != nil {
+		files, err := parseDseSecretFiles(dseYaml, cfgParser.DseHome(), cfgParser.localFile)
+		if err != nil {
+			return err
+		}
+
+		for key, content := range files.truststores {
+			encryptionData[key] = content
+		}
+
+		if err := storeSecretData(cli, namespace, systemKeySecretName(datacenter), files.systemKeys); err != nil {
+			return err
+		}
+
+		if err := storeSecretData(cli, namespace, kerberosSecretName(datacenter), files.kerberos); err != nil {
+			return err
+		}
+	}
+
+	return storeSecretData(cli, namespace, encryptionSecretName(datacenter), encryptionData)
+}
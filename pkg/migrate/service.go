@@ -0,0 +1,273 @@
+package migrate
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// MigratedMarkerFile is written to the configuration directory after the node has been moved to Kubernetes
+	MigratedMarkerFile = "k8ssandra-migrated"
+
+	guardStart = "# BEGIN k8ssandra import guard"
+	guardEnd   = "# END k8ssandra import guard"
+
+	serviceSystemd    = "systemd"
+	serviceSysV       = "sysv"
+	serviceSupervisor = "supervisor"
+)
+
+var (
+	serviceNames = []string{"cassandra", "dse"}
+
+	supervisorConfDirs = []string{"/etc/supervisor/conf.d", "/etc/supervisord.d"}
+	supervisorProgram  = regexp.MustCompile(`^\[program:(.+)\]$`)
+	supervisorAuto     = regexp.MustCompile(`^(autostart|autorestart)\s*=.*$`)
+
+	runCommand = func(name string, args ...string) (string, error) {
+		out, err := exec.Command(name, args...).CombinedOutput()
+		return strings.TrimSpace(string(out)), err
+	}
+)
+
+// hostService is the service manager entry that started Cassandra on the host
+type hostService struct {
+	kind string
+	name string
+	// path is the supervisor configuration file
+	path string
+}
+
+func (s hostService) String() string {
+	return fmt.Sprintf("%s:%s", s.kind, s.name)
+}
+
+// detectHostServices finds the service entries with the given names that could start Cassandra on the host after a
+// reboot
+func detectHostServices(names []string) ([]hostService, error) {
+	services := make([]hostService, 0)
+
+	for _, name := range names {
+		if _, err := exec.LookPath("systemctl"); err == nil {
+			state, _ := runCommand("systemctl", "is-enabled", name)
+			// is-enabled returns non-zero also for disabled units, but the state is still printed
+			if state == "enabled" || state == "static" || state == "generated" || state == "indirect" || state == "disabled" {
+				services = append(services, hostService{kind: serviceSystemd, name: name})
+				continue
+			}
+		}
+
+		if _, err := os.Stat(filepath.Join("/etc/init.d", name)); err == nil {
+			services = append(services, hostService{kind: serviceSysV, name: name})
+		}
+	}
+
+	for _, dir := range supervisorConfDirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			programs, err := supervisorPrograms(path)
+			if err != nil {
+				return nil, err
+			}
+			for _, program := range programs {
+				services = append(services, hostService{kind: serviceSupervisor, name: program, path: path})
+			}
+		}
+	}
+
+	return services, nil
+}
+
+// supervisorPrograms returns the programs in the supervisor configuration that run Cassandra or DSE
+func supervisorPrograms(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	programs := make([]string, 0)
+	program := ""
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if match := supervisorProgram.FindStringSubmatch(line); match != nil {
+			program = match[1]
+			continue
+		}
+		if program != "" && strings.HasPrefix(line, "command") {
+			for _, name := range serviceNames {
+				if strings.Contains(line, "bin/"+name) {
+					programs = append(programs, program)
+					break
+				}
+			}
+		}
+	}
+
+	return programs, nil
+}
+
+// disableSupervisorAutostart sets autostart and autorestart to false for the program
+func disableSupervisorAutostart(path, program string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(string(content), "\n")
+	modified := make([]string, 0, len(lines)+2)
+	inProgram := false
+
+	flush := func() {
+		if !inProgram {
+			return
+		}
+		// Keep the empty lines between the sections
+		end := len(modified)
+		for end > 0 && strings.TrimSpace(modified[end-1]) == "" {
+			end--
+		}
+		trailing := append([]string{}, modified[end:]...)
+		modified = append(append(modified[:end], "autostart=false", "autorestart=false"), trailing...)
+	}
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			flush()
+			inProgram = trimmed == fmt.Sprintf("[program:%s]", program)
+			modified = append(modified, line)
+			continue
+		}
+		if inProgram && supervisorAuto.MatchString(trimmed) {
+			continue
+		}
+		modified = append(modified, line)
+	}
+	flush()
+
+	return os.WriteFile(path, []byte(strings.Join(modified, "\n")), 0644)
+}
+
+// disable prevents the service from starting Cassandra at boot or by a manual service start
+func (s hostService) disable() error {
+	var err error
+	var out string
+
+	switch s.kind {
+	case serviceSystemd:
+		if out, err = runCommand("systemctl", "disable", s.name); err == nil {
+			// Masking also prevents manual starts
+			out, err = runCommand("systemctl", "mask", s.name)
+		}
+	case serviceSysV:
+		if _, lookErr := exec.LookPath("update-rc.d"); lookErr == nil {
+			out, err = runCommand("update-rc.d", s.name, "disable")
+		} else {
+			out, err = runCommand("chkconfig", s.name, "off")
+		}
+	case serviceSupervisor:
+		if err = disableSupervisorAutostart(s.path, s.name); err == nil {
+			out, err = runCommand("supervisorctl", "update")
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("unable to disable %s: %v %s", s, err, out)
+	}
+
+	return nil
+}
+
+// writeStartGuard writes the marker file and a check to the cassandra-env.sh that refuses to start Cassandra while the
+// marker file exists. nodetool and the other tools source the same file, so the check only runs when it is sourced by
+// the cassandra or dse-cassandra start scripts or by "dse cassandra".
+func writeStartGuard(confDir, podName string) error {
+	marker := filepath.Join(confDir, MigratedMarkerFile)
+	content := fmt.Sprintf("Data directories of this node are used by the Kubernetes pod %s since %s\n", podName, time.Now().UTC().Format(time.RFC3339))
+	if err := os.WriteFile(marker, []byte(content), 0644); err != nil {
+		return err
+	}
+
+	envPath := filepath.Join(confDir, cassandraEnvFilename)
+	env, err := os.ReadFile(envPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	// Replace the guard written by an earlier import
+	if start := strings.Index(string(env), guardStart); start >= 0 {
+		if end := strings.Index(string(env), guardEnd); end > start {
+			env = append(env[:start:start], strings.TrimPrefix(string(env[end+len(guardEnd):]), "\n")...)
+		}
+	}
+
+	guard := fmt.Sprintf(`%s
+case "$(basename -- "$0")" in
+    cassandra|dse-cassandra) k8ssandra_start=true ;;
+    dse) [ "$1" = "cassandra" ] && k8ssandra_start=true ;;
+esac
+if [ "$k8ssandra_start" = "true" ] && [ -f "%s" ]; then
+    echo "This node has been migrated to Kubernetes, refusing to start: $(cat "%s")" >&2
+    exit 1
+fi
+unset k8ssandra_start
+%s
+`, guardStart, marker, marker, guardEnd)
+
+	info, err := os.Stat(envPath)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(envPath, append([]byte(guard), env...), info.Mode())
+}
+
+// disableHostService disables the local service entries and guards against manual starts of the host Cassandra
+func (n *NodeMigrator) disableHostService() ([]string, error) {
+	names := serviceNames
+	if n.Container != nil {
+		// Units created by podman generate systemd
+		names = append([]string{"container-" + n.Container.Name}, serviceNames...)
+	}
+
+	services, err := detectHostServices(names)
+	if err != nil {
+		return nil, err
+	}
+
+	disabled := make([]string, 0, len(services)+1)
+	for _, service := range services {
+		if err := service.disable(); err != nil {
+			return disabled, err
+		}
+		disabled = append(disabled, service.String())
+	}
+
+	if n.Container != nil {
+		// The restart policy was disabled before the drain and the configuration is inside the container
+		disabled = append(disabled, n.Container.String())
+		return disabled, nil
+	}
+
+	if err := writeStartGuard(n.configs.cassConfigHome, n.getPodName()); err != nil {
+		return disabled, err
+	}
+
+	return disabled, nil
+}
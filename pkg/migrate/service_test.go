@@ -0,0 +1,97 @@
+package migrate
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSupervisorAutostart(t *testing.T) {
+	require := require.New(t)
+	path := filepath.Join(t.TempDir(), "cassandra.conf")
+
+	conf := `[program:cassandra]
+command=/opt/cassandra/bin/cassandra -f
+autostart=true
+autorestart=true
+user=cassandra
+
+[program:exporter]
+command=/opt/exporter/bin/exporter
+autostart=true
+`
+	require.NoError(os.WriteFile(path, []byte(conf), 0644))
+
+	programs, err := supervisorPrograms(path)
+	require.NoError(err)
+	require.Equal([]string{"cassandra"}, programs)
+
+	require.NoError(disableSupervisorAutostart(path, "cassandra"))
+
+	modified, err := os.ReadFile(path)
+	require.NoError(err)
+	require.Equal(`[program:cassandra]
+command=/opt/cassandra/bin/cassandra -f
+user=cassandra
+autostart=false
+autorestart=false
+
+[program:exporter]
+command=/opt/exporter/bin/exporter
+autostart=true
+`, string(modified))
+}
+
+func TestWriteStartGuard(t *testing.T) {
+	require := require.New(t)
+	confDir := t.TempDir()
+
+	env := "MAX_HEAP_SIZE=\"8G\"\n"
+	require.NoError(os.WriteFile(filepath.Join(confDir, cassandraEnvFilename), []byte(env), 0755))
+
+	require.NoError(writeStartGuard(confDir, "cluster1-dc1-rack1-sts-0"))
+	require.NoError(writeStartGuard(confDir, "cluster1-dc1-rack1-sts-0"))
+
+	marker, err := os.ReadFile(filepath.Join(confDir, MigratedMarkerFile))
+	require.NoError(err)
+	require.Contains(string(marker), "cluster1-dc1-rack1-sts-0")
+
+	guarded, err := os.ReadFile(filepath.Join(confDir, cassandraEnvFilename))
+	require.NoError(err)
+	require.Equal(1, strings.Count(string(guarded), guardStart))
+	require.True(strings.HasSuffix(string(guarded), env))
+
+	// Only the start scripts are stopped, nodetool sources the same file
+	binDir := t.TempDir()
+	for _, script := range []string{"cassandra", "nodetool", "dse"} {
+		content := fmt.Sprintf(". %s\necho started\n", filepath.Join(confDir, cassandraEnvFilename))
+		require.NoError(os.WriteFile(filepath.Join(binDir, script), []byte(content), 0755))
+	}
+	run := func(script string, args ...string) bool {
+		return exec.Command("sh", append([]string{filepath.Join(binDir, script)}, args...)...).Run() == nil
+	}
+	require.False(run("cassandra", "-f"))
+	require.True(run("nodetool", "status"))
+	require.False(run("dse", "cassandra"))
+	require.True(run("dse", "nodetool", "status"))
+
+	// The guard of an earlier import is replaced
+	oldGuard := guardStart + "\nexit 1\n" + guardEnd + "\n"
+	require.NoError(os.WriteFile(filepath.Join(confDir, cassandraEnvFilename), []byte(oldGuard+env), 0755))
+	require.NoError(writeStartGuard(confDir, "cluster1-dc1-rack1-sts-0"))
+	require.True(run("nodetool", "status"))
+	guarded, err = os.ReadFile(filepath.Join(confDir, cassandraEnvFilename))
+	require.NoError(err)
+	require.Equal(1, strings.Count(string(guarded), guardStart))
+	require.True(strings.HasSuffix(string(guarded), env))
+
+	// The guard must not break the heap parsing of the import
+	heapSettings, err := parseCassandraEnvFile(filepath.Join(confDir, cassandraEnvFilename))
+	require.NoError(err)
+	require.Equal("8G", heapSettings["max_heap_size"])
+}
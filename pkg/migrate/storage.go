@@ -0,0 +1,184 @@
+package migrate
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ClusterConfigMapVersion is the schema version of the ClusterConfigMap written by this version. Version 0 is the
+	// uncompressed clusterInfo written before the schema was versioned.
+	ClusterConfigMapVersion = 1
+
+	clusterInfoKey   = "clusterInfo"
+	compressedSuffix = ".gz"
+
+	// configMapSizeLimit leaves room for the metadata in the 1 MiB limit of a Kubernetes object
+	configMapSizeLimit = 900 * 1024
+)
+
+func compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// encodeClusterInfo stores the compressed ClusterConfigMap with the current schema version to the ConfigMap
+func encodeClusterInfo(configMap *corev1.ConfigMap, clusterConfigMap *ClusterConfigMap) error {
+	clusterConfigMap.SchemaVersion = ClusterConfigMapVersion
+
+	b, err := json.Marshal(clusterConfigMap)
+	if err != nil {
+		return err
+	}
+
+	compressed, err := compress(b)
+	if err != nil {
+		return err
+	}
+
+	if len(compressed) > configMapSizeLimit {
+		return fmt.Errorf("cluster information is too large to be stored in ConfigMap %s (%d bytes compressed)", configMap.Name, len(compressed))
+	}
+
+	if configMap.BinaryData == nil {
+		configMap.BinaryData = make(map[string][]byte)
+	}
+	delete(configMap.BinaryData, clusterInfoKey)
+	configMap.BinaryData[clusterInfoKey+compressedSuffix] = compressed
+
+	return nil
+}
+
+// decodeClusterInfo reads the ClusterConfigMap written by this or any older version
+func decodeClusterInfo(configMap *corev1.ConfigMap) (*ClusterConfigMap, error) {
+	b, found := configMap.BinaryData[clusterInfoKey]
+	if compressed, isCompressed := configMap.BinaryData[clusterInfoKey+compressedSuffix]; isCompressed {
+		decompressed, err := decompress(compressed)
+		if err != nil {
+			return nil, err
+		}
+		b = decompressed
+	} else if !found {
+		return nil, fmt.Errorf("ConfigMap %s has no cluster information", configMap.Name)
+	}
+
+	clusterConfigMap := &ClusterConfigMap{}
+	if err := json.Unmarshal(b, clusterConfigMap); err != nil {
+		return nil, err
+	}
+
+	if clusterConfigMap.SchemaVersion > ClusterConfigMapVersion {
+		return nil, fmt.Errorf("ConfigMap %s was created by a newer version (schema %d), upgrade the client", configMap.Name, clusterConfigMap.SchemaVersion)
+	}
+
+	return clusterConfigMap, nil
+}
+
+// fetchClusterConfigMap fetches the cluster information stored by the init process
+func fetchClusterConfigMap(cli client.Client, namespace, datacenter string) (*ClusterConfigMap, error) {
+	configMap := &corev1.ConfigMap{}
+	configMapKey := types.NamespacedName{Name: configMapName(datacenter), Namespace: namespace}
+	if err := cli.Get(context.TODO(), configMapKey, configMap); err != nil {
+		return nil, err
+	}
+
+	return decodeClusterInfo(configMap)
+}
+
+// configFiles returns the configuration files stored in the ConfigMap. Files are stored compressed in BinaryData if
+// they would not fit the ConfigMap otherwise.
+func configFiles(configMap *corev1.ConfigMap) (map[string]string, error) {
+	files := make(map[string]string, len(configMap.Data)+len(configMap.BinaryData))
+	for name, content := range configMap.Data {
+		files[name] = content
+	}
+
+	for key, compressed := range configMap.BinaryData {
+		if !strings.HasSuffix(key, compressedSuffix) {
+			continue
+		}
+		content, err := decompress(compressed)
+		if err != nil {
+			return nil, fmt.Errorf("unable to decompress %s: %w", key, err)
+		}
+		files[strings.TrimSuffix(key, compressedSuffix)] = string(content)
+	}
+
+	return files, nil
+}
+
+// setConfigFiles replaces the configuration files in the ConfigMap, compressing them if they're too large
+func setConfigFiles(configMap *corev1.ConfigMap, files map[string]string) error {
+	size := 0
+	for name, content := range files {
+		size += len(name) + len(content)
+	}
+
+	configMap.Data = make(map[string]string, len(files))
+	configMap.BinaryData = nil
+
+	if size <= configMapSizeLimit {
+		for name, content := range files {
+			configMap.Data[name] = content
+		}
+		return nil
+	}
+
+	configMap.BinaryData = make(map[string][]byte, len(files))
+	compressedSize := 0
+	for name, content := range files {
+		compressed, err := compress([]byte(content))
+		if err != nil {
+			return err
+		}
+		configMap.BinaryData[name+compressedSuffix] = compressed
+		compressedSize += len(name) + len(compressedSuffix) + len(compressed)
+	}
+
+	if compressedSize > configMapSizeLimit {
+		return fmt.Errorf("configuration files are too large to be stored in ConfigMap %s (%d bytes compressed)", configMap.Name, compressedSize)
+	}
+
+	return nil
+}
+
+// fetchConfigFiles fetches the ConfigMap with the stored configuration files
+func fetchConfigFiles(cli client.Client, namespace, datacenter string) (*corev1.ConfigMap, map[string]string, error) {
+	configMap := &corev1.ConfigMap{}
+	configMapKey := types.NamespacedName{Name: getConfigMapName(datacenter, "cass-config"), Namespace: namespace}
+	if err := cli.Get(context.TODO(), configMapKey, configMap); err != nil {
+		return nil, nil, err
+	}
+
+	files, err := configFiles(configMap)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return configMap, files, nil
+}
@@ -0,0 +1,85 @@
+package migrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestClusterInfoEncoding(t *testing.T) {
+	require := require.New(t)
+
+	nodeInfos := make([]NodetoolNodeInfo, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		nodeInfos = append(nodeInfos, NodetoolNodeInfo{
+			Status:  "up",
+			State:   "normal",
+			Address: fmt.Sprintf("10.0.%d.%d", i/256, i%256),
+			HostId:  fmt.Sprintf("%08x-0000-0000-0000-000000000000", i),
+			Rack:    fmt.Sprintf("rack%d", i%3),
+			Ordinal: fmt.Sprintf("%d", i/3),
+		})
+	}
+
+	configMap := &corev1.ConfigMap{}
+	require.NoError(encodeClusterInfo(configMap, &ClusterConfigMap{Cluster: "cluster1", NodeInfos: nodeInfos}))
+
+	decoded, err := decodeClusterInfo(configMap)
+	require.NoError(err)
+	require.Equal(ClusterConfigMapVersion, decoded.SchemaVersion)
+	require.Equal(nodeInfos, decoded.NodeInfos)
+}
+
+func TestClusterInfoSchemaVersions(t *testing.T) {
+	require := require.New(t)
+
+	// Written before the schema was versioned
+	legacy, err := json.Marshal(ClusterConfigMap{Cluster: "cluster1", Datacenter: "dc1"})
+	require.NoError(err)
+	configMap := &corev1.ConfigMap{BinaryData: map[string][]byte{clusterInfoKey: legacy}}
+
+	decoded, err := decodeClusterInfo(configMap)
+	require.NoError(err)
+	require.Equal(0, decoded.SchemaVersion)
+	require.Equal("dc1", decoded.Datacenter)
+
+	future, err := json.Marshal(ClusterConfigMap{SchemaVersion: ClusterConfigMapVersion + 1})
+	require.NoError(err)
+	configMap.BinaryData[clusterInfoKey] = future
+
+	_, err = decodeClusterInfo(configMap)
+	require.Error(err)
+}
+
+func TestConfigFilesCompression(t *testing.T) {
+	require := require.New(t)
+
+	small := map[string]string{cassYamlKey: "num_tokens: 16\n"}
+	configMap := &corev1.ConfigMap{}
+	require.NoError(setConfigFiles(configMap, small))
+	require.Equal(small, configMap.Data)
+	require.Empty(configMap.BinaryData)
+
+	var sb strings.Builder
+	for i := 0; sb.Len() < configMapSizeLimit; i++ {
+		fmt.Fprintf(&sb, "setting_%d: %d\n", i, i)
+	}
+	large := map[string]string{cassYamlKey: "num_tokens: 16\n", "dse-yaml": sb.String()}
+	require.NoError(setConfigFiles(configMap, large))
+	require.Empty(configMap.Data)
+	require.Equal(2, len(configMap.BinaryData))
+
+	files, err := configFiles(configMap)
+	require.NoError(err)
+	require.Equal(large, files)
+
+	// Random data does not compress
+	random := make([]byte, configMapSizeLimit+1)
+	rand.Read(random)
+	require.Error(setConfigFiles(configMap, map[string]string{"dse-yaml": string(random)}))
+}
@@ -0,0 +1,118 @@
+package migrate
+
+import (
+	"time"
+)
+
+// StepTiming is the duration of one step of the node migration
+type StepTiming struct {
+	Name     string
+	Duration time.Duration
+	// Downtime is set for the steps run while neither the local node nor the pod was serving requests
+	Downtime bool
+}
+
+// stepTimer measures the steps of the node migration, each step lasts until the next one starts
+type stepTimer struct {
+	now      func() time.Time
+	timings  []StepTiming
+	current  string
+	started  time.Time
+	downtime bool
+	// currentDowntime is the downtime state when the running step started
+	currentDowntime bool
+}
+
+func newStepTimer() *stepTimer {
+	return &stepTimer{
+		now:     time.Now,
+		timings: make([]StepTiming, 0),
+	}
+}
+
+// start ends the running step and starts measuring the next one
+func (t *stepTimer) start(name string) {
+	t.stop()
+	t.current = name
+	t.started = t.now()
+	t.currentDowntime = t.downtime
+}
+
+// stop ends the running step
+func (t *stepTimer) stop() {
+	if t.current == "" {
+		return
+	}
+	t.timings = append(t.timings, StepTiming{
+		Name:     t.current,
+		Duration: t.now().Sub(t.started),
+		Downtime: t.currentDowntime,
+	})
+	t.current = ""
+}
+
+// setDowntime marks the steps started after this call as downtime, or ends the downtime
+func (t *stepTimer) setDowntime(downtime bool) {
+	t.downtime = downtime
+}
+
+// downtimeTotal returns the sum of the steps that were run while the node was down
+func downtimeTotal(timings []StepTiming) time.Duration {
+	var total time.Duration
+	for _, timing := range timings {
+		if timing.Downtime {
+			total += timing.Duration
+		}
+	}
+	return total
+}
+
+// startStep updates the progress text and starts measuring the step
+func (n *NodeMigrator) startStep(text string) {
+	if n.timer == nil {
+		n.timer = newStepTimer()
+	}
+	previous := n.timer.current
+	n.timer.start(text)
+	if previous != "" {
+		n.audit(previous, AuditSucceeded, n.timer.timings[len(n.timer.timings)-1].Duration, "")
+	}
+	if n.p != nil {
+		n.p.UpdateText(text)
+	}
+}
+
+// finishSteps ends the running step and records the result of the step and the whole node migration
+func (n *NodeMigrator) finishSteps(err error) {
+	current := n.timer.current
+	n.timer.stop()
+
+	result, message := AuditSucceeded, ""
+	if err != nil {
+		result, message = AuditFailed, err.Error()
+	}
+
+	var total time.Duration
+	for _, timing := range n.timer.timings {
+		total += timing.Duration
+	}
+
+	if current != "" {
+		n.audit(current, result, n.timer.timings[len(n.timer.timings)-1].Duration, message)
+	}
+	n.audit("Node migration", result, total, message)
+}
+
+// Timings returns the durations of the steps of the last MigrateNode call
+func (n *NodeMigrator) Timings() []StepTiming {
+	if n.timer == nil {
+		return nil
+	}
+	n.timer.stop()
+	return n.timer.timings
+}
+
+// DowntimeTotal returns the time the node was not serving requests during the last MigrateNode call
+func (n *NodeMigrator) DowntimeTotal() time.Duration {
+	return downtimeTotal(n.Timings())
+}
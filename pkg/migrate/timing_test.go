@@ -0,0 +1,37 @@
+package migrate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStepTimer(t *testing.T) {
+	require := require.New(t)
+
+	now := time.Date(2022, 5, 1, 12, 0, 0, 0, time.UTC)
+	timer := newStepTimer()
+	timer.now = func() time.Time { return now }
+
+	timer.start("prepare")
+	now = now.Add(30 * time.Second)
+	timer.setDowntime(true)
+	timer.start("drain")
+	now = now.Add(10 * time.Second)
+	timer.start("start pod")
+	now = now.Add(50 * time.Second)
+	timer.setDowntime(false)
+	timer.start("disable service")
+	now = now.Add(time.Second)
+	timer.stop()
+	timer.stop()
+
+	require.Equal([]StepTiming{
+		{Name: "prepare", Duration: 30 * time.Second},
+		{Name: "drain", Duration: 10 * time.Second, Downtime: true},
+		{Name: "start pod", Duration: 50 * time.Second, Downtime: true},
+		{Name: "disable service", Duration: time.Second},
+	}, timer.timings)
+	require.Equal(60*time.Second, downtimeTotal(timer.timings))
+}
@@ -0,0 +1,119 @@
+package migrate
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	rackDcFilename   = "cassandra-rackdc.properties"
+	topologyFilename = "cassandra-topology.properties"
+
+	// operatorSnitch is the snitch cass-operator configures, it reads the rack and datacenter from cassandra-rackdc.properties
+	operatorSnitch = "GossipingPropertyFileSnitch"
+	propertySnitch = "PropertyFileSnitch"
+)
+
+// parseProperties reads a Java properties file with key=value pairs. Missing file is not an error.
+func parseProperties(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	defer f.Close()
+
+	properties := make(map[string]string)
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		setting := strings.SplitN(line, "=", 2)
+		if len(setting) < 2 {
+			continue
+		}
+		// IPv6 addresses in the topology file have the colons escaped
+		key := strings.ReplaceAll(strings.TrimSpace(setting[0]), `\:`, ":")
+		properties[key] = strings.TrimSpace(setting[1])
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return properties, nil
+}
+
+func (p *ConfigParser) parseTopology() error {
+	rackDc, err := parseProperties(filepath.Join(p.cassConfigHome, rackDcFilename))
+	if err != nil {
+		return err
+	}
+	p.rackDc = rackDc
+
+	topology, err := parseProperties(filepath.Join(p.cassConfigHome, topologyFilename))
+	if err != nil {
+		return err
+	}
+	p.topology = topology
+
+	return nil
+}
+
+// EndpointSnitch returns the snitch's class name without the org.apache.cassandra.locator package
+func (p *ConfigParser) EndpointSnitch() string {
+	snitch, _ := p.CassYaml()["endpoint_snitch"].(string)
+	return strings.TrimPrefix(snitch, "org.apache.cassandra.locator.")
+}
+
+// validateTopology verifies that the local topology files match the datacenter and racks reported by gossip. The
+// returned warnings are about settings cass-operator will replace.
+func (p *ConfigParser) validateTopology(datacenter, rack string, nodeInfos []NodetoolNodeInfo) ([]string, error) {
+	warnings := make([]string, 0)
+
+	snitch := p.EndpointSnitch()
+	if snitch != "" && snitch != operatorSnitch {
+		warnings = append(warnings, fmt.Sprintf("endpoint_snitch %s will be replaced with %s, the datacenter and rack names are kept", snitch, operatorSnitch))
+	}
+
+	if len(p.rackDc) > 0 && (snitch == "" || snitch == operatorSnitch) {
+		expectedDc := p.rackDc["dc"] + p.rackDc["dc_suffix"]
+		if expectedDc != datacenter {
+			return nil, fmt.Errorf("%s has datacenter %s, but the node is in datacenter %s", rackDcFilename, expectedDc, datacenter)
+		}
+		if p.rackDc["rack"] != rack {
+			return nil, fmt.Errorf("%s has rack %s, but the node is in rack %s", rackDcFilename, p.rackDc["rack"], rack)
+		}
+		if p.rackDc["prefer_local"] == "true" {
+			warnings = append(warnings, fmt.Sprintf("prefer_local in %s is not carried over", rackDcFilename))
+		}
+	}
+
+	if len(p.topology) > 0 && snitch == propertySnitch {
+		for _, nodeInfo := range nodeInfos {
+			location, found := p.topology[nodeInfo.Address]
+			if !found {
+				location = p.topology["default"]
+			}
+			nodeDc := nodeInfo.Datacenter
+			if nodeDc == "" {
+				nodeDc = datacenter
+			}
+			parts := strings.SplitN(location, ":", 2)
+			if len(parts) < 2 || parts[0] != nodeDc || parts[1] != nodeInfo.Rack {
+				return nil, fmt.Errorf("%s has location %s for node %s, but the node is in datacenter %s and rack %s", topologyFilename, location, nodeInfo.Address, nodeDc, nodeInfo.Rack)
+			}
+		}
+	}
+
+	return warnings, nil
+}
@@ -0,0 +1,81 @@
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTopology(t *testing.T) {
+	require := require.New(t)
+	confDir := t.TempDir()
+
+	rackDc := "dc=dc\ndc_suffix=1\nrack=rack1\n"
+	topology := "10.0.0.1=dc1:rack1\n10.0.0.2=dc1:rack2\ndefault=dc1:rack1\n"
+	require.NoError(os.WriteFile(filepath.Join(confDir, rackDcFilename), []byte(rackDc), 0644))
+	require.NoError(os.WriteFile(filepath.Join(confDir, topologyFilename), []byte(topology), 0644))
+
+	parser := NewParser()
+	parser.cassConfigHome = confDir
+	parser.yamls[cassYamlKey] = map[string]interface{}{
+		"endpoint_snitch": "org.apache.cassandra.locator.GossipingPropertyFileSnitch",
+	}
+	require.NoError(parser.parseTopology())
+
+	warnings, err := parser.validateTopology("dc1", "rack1", nil)
+	require.NoError(err)
+	require.Empty(warnings)
+
+	_, err = parser.validateTopology("dc1", "rack2", nil)
+	require.Error(err)
+
+	_, err = parser.validateTopology("dc2", "rack1", nil)
+	require.Error(err)
+
+	parser.yamls[cassYamlKey]["endpoint_snitch"] = "PropertyFileSnitch"
+	nodeInfos := []NodetoolNodeInfo{
+		{Address: "10.0.0.1", Rack: "rack1"},
+		{Address: "10.0.0.2", Rack: "rack2"},
+		{Address: "10.0.0.3", Rack: "rack1"},
+	}
+	warnings, err = parser.validateTopology("dc1", "rack2", nodeInfos)
+	require.NoError(err)
+	require.Equal(1, len(warnings))
+
+	nodeInfos[2].Datacenter = "dc1"
+	_, err = parser.validateTopology("dc1", "rack1", nodeInfos)
+	require.NoError(err)
+
+	// The default location is in another datacenter than the node
+	nodeInfos[2].Datacenter = "dc2"
+	_, err = parser.validateTopology("dc1", "rack1", nodeInfos)
+	require.Error(err)
+
+	nodeInfos[2].Datacenter = ""
+	_, err = parser.validateTopology("dc2", "rack1", nodeInfos)
+	require.Error(err)
+
+	nodeInfos[2].Rack = "rack3"
+	_, err = parser.validateTopology("dc1", "rack1", nodeInfos)
+	require.Error(err)
+}
+
+func TestLogbackExternalFiles(t *testing.T) {
+	require := require.New(t)
+
+	logback := []byte(`<configuration>
+  <appender name="SYSTEMLOG" class="ch.qos.logback.core.rolling.RollingFileAppender">
+    <file>${cassandra.logdir}/system.log</file>
+  </appender>
+  <appender name="AUDIT" class="ch.qos.logback.core.rolling.RollingFileAppender">
+    <file> /var/log/audit/audit.log </file>
+  </appender>
+  <appender name="DEBUG" class="ch.qos.logback.core.rolling.RollingFileAppender">
+    <file>/var/log/cassandra/debug.log</file>
+  </appender>
+</configuration>`)
+
+	require.Equal([]string{"/var/log/audit/audit.log"}, logbackExternalFiles(logback))
+}
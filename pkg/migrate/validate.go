@@ -0,0 +1,368 @@
+package migrate
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/burmanm/definitions-parser/pkg/types"
+	"github.com/burmanm/definitions-parser/pkg/types/generated"
+	"github.com/pterm/pterm"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ConfigIssue is a problem in the stored configuration that cass-config-builder would fail or silently ignore
+type ConfigIssue struct {
+	File    string
+	Key     string
+	Message string
+	// Fatal issues prevent the node from starting in Kubernetes
+	Fatal bool
+}
+
+func (i ConfigIssue) String() string {
+	if i.Key == "" {
+		return fmt.Sprintf("%s: %s", i.File, i.Message)
+	}
+	return fmt.Sprintf("%s: %s %s", i.File, i.Key, i.Message)
+}
+
+var (
+	// renamedKeys41 are the cassandra.yaml keys renamed in Cassandra 4.1. The new names take durations, data sizes and
+	// rates with units, such as "10s" or "16MiB". Cassandra 4.1 still accepts the old names.
+	renamedKeys41 = map[string]string{
+		"read_request_timeout_in_ms":                           "read_request_timeout",
+		"range_request_timeout_in_ms":                          "range_request_timeout",
+		"write_request_timeout_in_ms":                          "write_request_timeout",
+		"counter_write_request_timeout_in_ms":                  "counter_write_request_timeout",
+		"cas_contention_timeout_in_ms":                         "cas_contention_timeout",
+		"truncate_request_timeout_in_ms":                       "truncate_request_timeout",
+		"request_timeout_in_ms":                                "request_timeout",
+		"slow_query_log_timeout_in_ms":                         "slow_query_log_timeout",
+		"permissions_validity_in_ms":                           "permissions_validity",
+		"permissions_update_interval_in_ms":                    "permissions_update_interval",
+		"roles_validity_in_ms":                                 "roles_validity",
+		"roles_update_interval_in_ms":                          "roles_update_interval",
+		"credentials_validity_in_ms":                           "credentials_validity",
+		"credentials_update_interval_in_ms":                    "credentials_update_interval",
+		"max_hint_window_in_ms":                                "max_hint_window",
+		"hinted_handoff_throttle_in_kb":                        "hinted_handoff_throttle",
+		"max_hints_file_size_in_mb":                            "max_hints_file_size",
+		"commitlog_segment_size_in_mb":                         "commitlog_segment_size",
+		"commitlog_sync_period_in_ms":                          "commitlog_sync_period",
+		"commitlog_total_space_in_mb":                          "commitlog_total_space",
+		"compaction_throughput_mb_per_sec":                     "compaction_throughput",
+		"compaction_large_partition_warning_threshold_mb":      "compaction_large_partition_warning_threshold",
+		"stream_throughput_outbound_megabits_per_sec":          "stream_throughput_outbound",
+		"inter_dc_stream_throughput_outbound_megabits_per_sec": "inter_dc_stream_throughput_outbound",
+		"key_cache_size_in_mb":                                 "key_cache_size",
+		"counter_cache_size_in_mb":                             "counter_cache_size",
+		"file_cache_size_in_mb":                                "file_cache_size",
+		"native_transport_max_frame_size_in_mb":                "native_transport_max_frame_size",
+		"column_index_size_in_kb":                              "column_index_size",
+		"column_index_cache_size_in_kb":                        "column_index_cache_size",
+		"batch_size_warn_threshold_in_kb":                      "batch_size_warn_threshold",
+		"batch_size_fail_threshold_in_kb":                      "batch_size_fail_threshold",
+		"memtable_heap_space_in_mb":                            "memtable_heap_space",
+		"memtable_offheap_space_in_mb":                         "memtable_offheap_space",
+		"hints_flush_period_in_ms":                             "hints_flush_period",
+		"batchlog_replay_throttle_in_kb":                       "batchlog_replay_throttle",
+		"prepared_statements_cache_size_mb":                    "prepared_statements_cache_size",
+		"row_cache_size_in_mb":                                 "row_cache_size",
+		"trickle_fsync_interval_in_kb":                         "trickle_fsync_interval",
+		"sstable_preemptive_open_interval_in_mb":               "sstable_preemptive_open_interval",
+		"index_summary_capacity_in_mb":                         "index_summary_capacity",
+		"index_summary_resize_interval_in_minutes":             "index_summary_resize_interval",
+		"dynamic_snitch_update_interval_in_ms":                 "dynamic_snitch_update_interval",
+		"dynamic_snitch_reset_interval_in_ms":                  "dynamic_snitch_reset_interval",
+		"gc_log_threshold_in_ms":                               "gc_log_threshold",
+		"gc_warn_threshold_in_ms":                              "gc_warn_threshold",
+		"max_value_size_in_mb":                                 "max_value_size",
+		"native_transport_idle_timeout_in_ms":                  "native_transport_idle_timeout",
+		"cdc_total_space_in_mb":                                "cdc_total_space",
+		"cache_load_timeout_seconds":                           "cache_load_timeout",
+		"streaming_keep_alive_period_in_secs":                  "streaming_keep_alive_period",
+		"internode_tcp_connect_timeout_in_ms":                  "internode_tcp_connect_timeout",
+		"internode_tcp_user_timeout_in_ms":                     "internode_tcp_user_timeout",
+		"commitlog_sync_group_window_in_ms":                    "commitlog_sync_group_window",
+		"networking_cache_size_in_mb":                          "networking_cache_size",
+		"repair_session_space_in_mb":                           "repair_session_space",
+		"enable_user_defined_functions":                        "user_defined_functions_enabled",
+		"enable_scripted_user_defined_functions":               "scripted_user_defined_functions_enabled",
+		"enable_materialized_views":                            "materialized_views_enabled",
+		"enable_sasi_indexes":                                  "sasi_indexes_enabled",
+		"enable_transient_replication":                         "transient_replication_enabled",
+		"enable_drop_compact_storage":                          "drop_compact_storage_enabled",
+	}
+
+	heapSizeValue = regexp.MustCompile(`^[0-9]+[kKmMgG]?$`)
+	unitValue     = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?[a-zA-Z/]+$`)
+
+	supportedGarbageCollectors = []string{"G1GC", "CMS"}
+)
+
+// removedSettingsBaseline is the last Cassandra version with the settings that were removed in Cassandra 4.0
+const removedSettingsBaseline = "3.11.0"
+
+// ValidateConfigMap validates the configuration stored in the <dc>-cass-config ConfigMap
+func ValidateConfigMap(configMap *corev1.ConfigMap, serverType, serverVersion string) ([]ConfigIssue, error) {
+	files, err := configFiles(configMap)
+	if err != nil {
+		return nil, err
+	}
+
+	yamls := make(map[string]map[string]interface{}, len(files))
+	for name, data := range files {
+		yamlConf := make(map[string]interface{})
+		if err := yaml.Unmarshal([]byte(data), &yamlConf); err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %w", name, err)
+		}
+		yamls[name] = yamlConf
+	}
+
+	return validateConfigs(serverType, serverVersion, yamls), nil
+}
+
+// validateConfigs checks every stored file against the definitions of the server type and version, and against the
+// known changes between versions. The issues are sorted by file and key.
+func validateConfigs(serverType, serverVersion string, yamls map[string]map[string]interface{}) []ConfigIssue {
+	issues := make([]ConfigIssue, 0)
+
+	for name, yamlConf := range yamls {
+		issues = append(issues, validateFile(name, serverType, serverVersion, yamlConf)...)
+		if strings.HasSuffix(name, "-options") {
+			issues = append(issues, validateJvmOptions(name, yamlConf)...)
+		}
+	}
+
+	if cassYaml, found := yamls[cassYamlKey]; found && serverType == "cassandra" {
+		issues = append(issues, validateCassandraVersion(serverVersion, cassYaml)...)
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].File != issues[j].File {
+			return issues[i].File < issues[j].File
+		}
+		return issues[i].Key < issues[j].Key
+	})
+
+	return issues
+}
+
+// validateFile checks the settings against the definitions of the server version. cass-config-builder ignores the
+// files and settings without definitions, values that do not match the type of the definition fail the config
+// generation.
+func validateFile(name, serverType, serverVersion string, yamlConf map[string]interface{}) []ConfigIssue {
+	fileName := definitionsFileName(name)
+	issues := make([]ConfigIssue, 0)
+
+	properties := generated.PropertiesFinder(serverType, serverVersion, name)
+	if properties == nil {
+		return append(issues, ConfigIssue{File: fileName, Message: fmt.Sprintf("has no definitions for %s %s and will be ignored", serverType, serverVersion)})
+	}
+
+	for key, value := range yamlConf {
+		if key == additionalJvmOptionsKey {
+			// Checked by validateJvmOptions
+			continue
+		}
+
+		meta, found := properties[key]
+		if !found {
+			// validateCassandraVersion reports the removed and renamed settings
+			if !removedInCassandraVersion(serverType, serverVersion, name, key) && !renamedInCassandraVersion(serverType, serverVersion, name, key) {
+				issues = append(issues, ConfigIssue{File: fileName, Key: key, Message: "is not a known setting and will be ignored"})
+			}
+			continue
+		}
+
+		if msg := typeMismatch(value, meta); msg != "" {
+			issues = append(issues, ConfigIssue{File: fileName, Key: key, Message: msg, Fatal: true})
+		}
+	}
+
+	return issues
+}
+
+// typeMismatch compares the value to the builder type of the definition. Empty values use the defaults.
+func typeMismatch(value interface{}, meta types.Metadata) string {
+	if value == nil {
+		return ""
+	}
+
+	switch meta.BuilderType {
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Sprintf("expects a boolean, got %v", value)
+		}
+	case "int", "long":
+		if _, ok := value.(int); !ok {
+			return fmt.Sprintf("expects an integer, got %v", value)
+		}
+	case "double", "float":
+		switch value.(type) {
+		case int, float64:
+		default:
+			return fmt.Sprintf("expects a number, got %v", value)
+		}
+	case "map", "dict":
+		if _, ok := value.(map[string]interface{}); !ok {
+			return fmt.Sprintf("expects a map, got %v", value)
+		}
+	case "list":
+		switch value.(type) {
+		case []interface{}, []string:
+		default:
+			return fmt.Sprintf("expects a list, got %v", value)
+		}
+	default:
+		// Strings and the values with units, validateCassandraVersion checks the units
+		switch value.(type) {
+		case map[string]interface{}, []interface{}, []string:
+			return fmt.Sprintf("expects a single value, got %v", value)
+		}
+	}
+
+	return ""
+}
+
+// removedInCassandraVersion checks if the cassandra.yaml setting was defined in Cassandra 3.11, but is no longer
+// defined in the Cassandra 4.0 or newer server version
+func removedInCassandraVersion(serverType, serverVersion, name, key string) bool {
+	if serverType != "cassandra" || name != cassYamlKey || !serverVersionAtLeast(serverVersion, 4, 0) {
+		return false
+	}
+
+	if _, found := generated.PropertiesFinder(serverType, serverVersion, name)[key]; found {
+		return false
+	}
+
+	_, found := generated.PropertiesFinder(serverType, removedSettingsBaseline, name)[key]
+	return found
+}
+
+// renamedInCassandraVersion checks if the cassandra.yaml setting was renamed in Cassandra 4.1 or older
+func renamedInCassandraVersion(serverType, serverVersion, name, key string) bool {
+	if serverType != "cassandra" || name != cassYamlKey || !serverVersionAtLeast(serverVersion, 4, 1) {
+		return false
+	}
+
+	_, found := renamedKeys41[key]
+	return found
+}
+
+// validateJvmOptions checks the values of the keys the import translates from the JVM options
+func validateJvmOptions(name string, options map[string]interface{}) []ConfigIssue {
+	fileName := definitionsFileName(name)
+	issues := make([]ConfigIssue, 0)
+
+	for _, setting := range heapOptions {
+		if value, found := options[setting]; found && !heapSizeValue.MatchString(fmt.Sprintf("%v", value)) {
+			issues = append(issues, ConfigIssue{File: fileName, Key: setting, Message: fmt.Sprintf("is not a valid heap size: %v", value), Fatal: true})
+		}
+	}
+
+	if value, found := options["garbage_collector"]; found {
+		supported := false
+		for _, gc := range supportedGarbageCollectors {
+			if value == gc {
+				supported = true
+			}
+		}
+		if !supported {
+			issues = append(issues, ConfigIssue{File: fileName, Key: "garbage_collector", Message: fmt.Sprintf("has unsupported value %v", value), Fatal: true})
+		}
+	}
+
+	if value, found := options[additionalJvmOptionsKey]; found {
+		if _, ok := value.([]interface{}); !ok {
+			if _, ok := value.([]string); !ok {
+				issues = append(issues, ConfigIssue{File: fileName, Key: additionalJvmOptionsKey, Message: "expects a list of options", Fatal: true})
+			}
+		}
+	}
+
+	return issues
+}
+
+// validateCassandraVersion reports the keys that were removed or renamed in the Cassandra version running in the cluster
+func validateCassandraVersion(serverVersion string, cassYaml map[string]interface{}) []ConfigIssue {
+	issues := make([]ConfigIssue, 0)
+
+	for key := range cassYaml {
+		if removedInCassandraVersion("cassandra", serverVersion, cassYamlKey, key) {
+			issues = append(issues, ConfigIssue{File: cassYamlFilename, Key: key, Message: "was removed in Cassandra 4.0", Fatal: true})
+		}
+	}
+
+	if serverVersionAtLeast(serverVersion, 4, 1) {
+		for oldKey, newKey := range renamedKeys41 {
+			if _, found := cassYaml[oldKey]; found {
+				issues = append(issues, ConfigIssue{File: cassYamlFilename, Key: oldKey, Message: fmt.Sprintf("was renamed to %s in Cassandra 4.1, the old name is deprecated", newKey)})
+			}
+
+			if value, found := cassYaml[newKey]; found && value != nil && !strings.HasSuffix(newKey, "_enabled") {
+				if !unitValue.MatchString(fmt.Sprintf("%v", value)) {
+					issues = append(issues, ConfigIssue{File: cassYamlFilename, Key: newKey, Message: fmt.Sprintf("expects a value with a unit, got %v", value), Fatal: true})
+				}
+			}
+		}
+	}
+
+	return issues
+}
+
+// serverVersionAtLeast compares the major and minor parts of the version, such as 4.0.5
+func serverVersionAtLeast(version string, major, minor int) bool {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+
+	versionMajor, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	// Pre-releases have suffixes, such as 5.0-beta1
+	minorPart := parts[1]
+	if idx := strings.IndexFunc(minorPart, func(r rune) bool { return r < '0' || r > '9' }); idx >= 0 {
+		minorPart = minorPart[:idx]
+	}
+	versionMinor, err := strconv.Atoi(minorPart)
+	if err != nil {
+		return false
+	}
+
+	return versionMajor > major || (versionMajor == major && versionMinor >= minor)
+}
+
+// definitionsFileName returns the original file name for the cass-config-builder key, such as cassandra.yaml for cassandra-yaml
+func definitionsFileName(name string) string {
+	idx := strings.LastIndex(name, "-")
+	if idx < 0 {
+		return name
+	}
+	return name[:idx] + "." + name[idx+1:]
+}
+
+// reportConfigIssues prints the issues and returns an error if any of them would prevent the node from starting
+func reportConfigIssues(issues []ConfigIssue) error {
+	fatal := 0
+	for _, issue := range issues {
+		if issue.Fatal {
+			fatal++
+			pterm.Error.Println(issue.String())
+		} else {
+			pterm.Warning.Println(issue.String())
+		}
+	}
+
+	if fatal > 0 {
+		return fmt.Errorf("found %d invalid settings in the configuration, fix them before migrating the nodes", fatal)
+	}
+
+	return nil
+}
@@ -0,0 +1,164 @@
+package migrate
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/burmanm/definitions-parser/pkg/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCassandraVersion(t *testing.T) {
+	require := require.New(t)
+
+	cassYaml := map[string]interface{}{
+		"start_rpc":                  false,
+		"read_request_timeout_in_ms": 5000,
+		"write_request_timeout":      2000,
+		"range_request_timeout":      "10s",
+		"materialized_views_enabled": true,
+	}
+
+	issues := validateCassandraVersion("4.0.5", cassYaml)
+	require.Equal(1, len(issues))
+	require.Equal("start_rpc", issues[0].Key)
+
+	issues = validateConfigs("cassandra", "4.1.0", map[string]map[string]interface{}{cassYamlKey: cassYaml})
+	require.Equal(3, len(issues))
+	require.Equal("read_request_timeout_in_ms", issues[0].Key)
+	require.False(issues[0].Fatal)
+	require.Equal("start_rpc", issues[1].Key)
+	require.True(issues[1].Fatal)
+	require.Equal("write_request_timeout", issues[2].Key)
+	require.True(issues[2].Fatal)
+
+	// DSE 6.8 uses the 3.11 style settings, the 4.1 names are not known
+	issues = validateConfigs("dse", "6.8.25", map[string]map[string]interface{}{cassYamlKey: cassYaml})
+	require.Equal(3, len(issues))
+	for _, issue := range issues {
+		require.False(issue.Fatal)
+		require.Contains(issue.Message, "not a known setting")
+	}
+}
+
+func TestValidateJvmOptions(t *testing.T) {
+	require := require.New(t)
+
+	issues := validateJvmOptions("jvm-server-options", map[string]interface{}{
+		"initial_heap_size": "8G",
+		"max_heap_size":     "8 gigabytes",
+		"garbage_collector": "ZGC",
+	})
+	require.Equal(2, len(issues))
+	for _, issue := range issues {
+		require.Equal("jvm-server.options", issue.File)
+		require.True(issue.Fatal)
+	}
+}
+
+func TestValidateParsedConfigs(t *testing.T) {
+	require := require.New(t)
+	confDir := filepath.Join("..", "..", "testfiles")
+	parser := NewParser()
+	require.NoError(parser.ParseConfigDirectories(confDir, confDir, ""))
+	require.NoError(parser.ParseConfigs())
+
+	for _, issue := range validateConfigs("cassandra", "4.0.5", parser.Yamls()) {
+		require.False(issue.Fatal, issue.String())
+		require.NotEqual(cassYamlFilename, issue.File, issue.String())
+	}
+}
+
+func TestValidateYaml(t *testing.T) {
+	require := require.New(t)
+
+	cassYaml := map[string]interface{}{
+		"num_tokens":             "sixteen",
+		"concurrent_reads":       32,
+		"authenticator":          "PasswordAuthenticator",
+		"not_a_cassandra_option": true,
+		"start_rpc":              false,
+		"key_cache_size_in_mb":   nil,
+	}
+
+	issues := validateConfigs("cassandra", "4.0.5", map[string]map[string]interface{}{cassYamlKey: cassYaml})
+	require.Equal(3, len(issues))
+	require.Equal("not_a_cassandra_option", issues[0].Key)
+	require.False(issues[0].Fatal)
+	require.Equal("num_tokens", issues[1].Key)
+	require.True(issues[1].Fatal)
+	require.Equal("start_rpc", issues[2].Key)
+
+	// Thrift settings are still valid in 3.11 and DSE 6.8
+	issues = validateFile(cassYamlKey, "cassandra", "3.11.13", cassYaml)
+	require.Equal(2, len(issues))
+	issues = validateFile(cassYamlKey, "dse", "6.8.25", cassYaml)
+	require.Equal(2, len(issues))
+
+	issues = validateFile("dse-yaml", "dse", "6.8.25", map[string]interface{}{"unknown": 1, "async_bootstrap_reindex": "yes"})
+	require.Equal(2, len(issues))
+
+	// Settings that are only in the definitions of some versions
+	validSettings := map[string]interface{}{
+		"auto_bootstrap":                        false,
+		"otc_coalescing_strategy":               "DISABLED",
+		"enable_user_defined_functions_threads": true,
+	}
+	require.Empty(validateFile(cassYamlKey, "cassandra", "3.11.13", validSettings))
+	require.Empty(validateFile(cassYamlKey, "cassandra", "4.0.5", validSettings))
+	require.Empty(validateFile(cassYamlKey, "dse", "6.8.25", validSettings))
+
+	// cass-config-builder does not render files without definitions
+	issues = validateFile("unknown-yaml", "cassandra", "4.0.5", map[string]interface{}{})
+	require.Equal(1, len(issues))
+	require.Equal("unknown.yaml: has no definitions for cassandra 4.0.5 and will be ignored", issues[0].String())
+}
+
+func TestValidateJvmOptionsDefinitions(t *testing.T) {
+	require := require.New(t)
+
+	issues := validateConfigs("cassandra", "4.0.5", map[string]map[string]interface{}{
+		"jvm11-server-options": {
+			"garbage_collector":     "G1GC",
+			"max_gc_pause_millis":   "long",
+			"not_a_jvm_option":      true,
+			additionalJvmOptionsKey: []string{"-XX:+AlwaysPreTouch"},
+		},
+	})
+	require.Equal(2, len(issues))
+	require.Equal("max_gc_pause_millis", issues[0].Key)
+	require.True(issues[0].Fatal)
+	require.Equal("not_a_jvm_option", issues[1].Key)
+	require.False(issues[1].Fatal)
+}
+
+func TestTypeMismatch(t *testing.T) {
+	require := require.New(t)
+
+	builderType := func(t string) types.Metadata {
+		return types.Metadata{BuilderType: t}
+	}
+
+	require.Empty(typeMismatch(true, builderType("boolean")))
+	require.NotEmpty(typeMismatch("yes", builderType("boolean")))
+	require.Empty(typeMismatch(256, builderType("int")))
+	require.NotEmpty(typeMismatch("256MiB", builderType("int")))
+	require.Empty(typeMismatch(0.5, builderType("double")))
+	require.Empty(typeMismatch(1, builderType("double")))
+	require.Empty(typeMismatch("org.apache.cassandra.auth.PasswordAuthenticator", builderType("string")))
+	require.NotEmpty(typeMismatch([]interface{}{"a"}, builderType("string")))
+	require.Empty(typeMismatch(map[string]interface{}{}, builderType("map")))
+	require.NotEmpty(typeMismatch("none", builderType("list")))
+	require.Empty(typeMismatch(nil, builderType("int")))
+}
+
+func TestServerVersionAtLeast(t *testing.T) {
+	require := require.New(t)
+
+	require.True(serverVersionAtLeast("4.1.0", 4, 1))
+	require.True(serverVersionAtLeast("5.0-beta1", 4, 1))
+	require.True(serverVersionAtLeast("4.0.5", 4, 0))
+	require.False(serverVersionAtLeast("4.0.5", 4, 1))
+	require.False(serverVersionAtLeast("3.11.13", 4, 0))
+	require.False(serverVersionAtLeast("", 4, 0))
+}
@@ -0,0 +1,572 @@
+package migrate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pterm/pterm"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/kubectl/pkg/cmd/exec"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// VerificationStarted is checked once the migrated pod has started, VerificationCommitted after "import commit"
+	// and VerificationManual by "import verify"
+	VerificationStarted   = "started"
+	VerificationCommitted = "committed"
+	VerificationManual    = "verify"
+)
+
+// sizeTolerance is the relative change of a keyspace's data size allowed after the migration. Compactions and
+// flushes change the size of a running node, so the sizes only detect lost data.
+const (
+	sizeTolerance      = 0.1
+	minSizeToleranceMB = 1
+)
+
+// KeyspaceData is the on-disk data of a keyspace on the node
+type KeyspaceData struct {
+	Tables    []string `json:"tables"`
+	SSTables  int      `json:"sstables"`
+	SizeBytes int64    `json:"sizeBytes"`
+}
+
+// NodeState is the identity and data of a node, compared before and after the migration
+type NodeState struct {
+	HostID     string   `json:"hostId"`
+	Address    string   `json:"address"`
+	Datacenter string   `json:"datacenter"`
+	Rack       string   `json:"rack"`
+	Tokens     []string `json:"tokens"`
+	// SchemaVersion is the node's schema version, SchemaAgreement is set if every reachable node has the same version
+	SchemaVersion   string `json:"schemaVersion"`
+	SchemaAgreement bool   `json:"schemaAgreement"`
+	// OwnedRanges are the primary token ranges of the node in the ring
+	OwnedRanges []string                `json:"ownedRanges"`
+	Keyspaces   map[string]KeyspaceData `json:"keyspaces"`
+	Captured    time.Time               `json:"captured"`
+}
+
+// VerificationCheck is a comparison of the node's state to the state before the migration
+type VerificationCheck struct {
+	Stage      string    `json:"stage"`
+	State      NodeState `json:"state"`
+	Mismatches []string  `json:"mismatches,omitempty"`
+	// Changes are the expected differences, such as compacted SSTables, recorded for information
+	Changes []string `json:"changes,omitempty"`
+}
+
+// NodeVerification is the verification record of a migrated node. Only the state before the migration and the
+// latest check are kept.
+type NodeVerification struct {
+	HostID    string             `json:"hostId"`
+	Pod       string             `json:"pod"`
+	Before    NodeState          `json:"before"`
+	LastCheck *VerificationCheck `json:"lastCheck,omitempty"`
+}
+
+// Latest returns the last check of the node, nil if the node has not been checked
+func (v *NodeVerification) Latest() *VerificationCheck {
+	return v.LastCheck
+}
+
+func verificationsConfigMapName(datacenter string) string {
+	return getConfigMapName(datacenter, "migrate-verification")
+}
+
+// captureNodeState reads the node's identity, ring and data with nodetool. The node must be running.
+func captureNodeState(nodetool func(args ...string) (string, error)) (NodeState, error) {
+	state := NodeState{
+		Captured: time.Now().UTC(),
+	}
+
+	info, err := nodetool("info", "-T")
+	if err != nil {
+		return state, err
+	}
+	state.HostID, state.Datacenter, state.Rack, state.Tokens = parseNodetoolInfo(info)
+	if state.HostID == "" || len(state.Tokens) == 0 {
+		return state, fmt.Errorf("unable to parse host ID and tokens from nodetool info")
+	}
+
+	ring, err := nodetool("ring")
+	if err != nil {
+		return state, err
+	}
+	ringTokens := parseNodetoolRing(ring)
+	state.Address = ringTokens[state.Tokens[0]]
+	state.OwnedRanges = primaryRanges(state.Tokens, ringTokens)
+
+	cluster, err := nodetool("describecluster")
+	if err != nil {
+		return state, err
+	}
+	state.SchemaVersion, state.SchemaAgreement = parseSchemaVersion(cluster, state.Address)
+
+	stats, err := nodetool("tablestats")
+	if err != nil {
+		return state, err
+	}
+	state.Keyspaces = parseTablestats(stats)
+
+	return state, nil
+}
+
+// parseNodetoolInfo parses the host ID, datacenter, rack and tokens from nodetool info -T
+func parseNodetoolInfo(output string) (string, string, string, []string) {
+	var hostID, datacenter, rack string
+	tokens := make([]string, 0)
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(parts[1])
+		switch strings.TrimSpace(parts[0]) {
+		case "ID":
+			hostID = value
+		case "Data Center":
+			datacenter = value
+		case "Rack":
+			rack = value
+		case "Token":
+			tokens = append(tokens, value)
+		}
+	}
+	return hostID, datacenter, rack, tokens
+}
+
+// parseNodetoolRing returns the address owning each token of the ring
+func parseNodetoolRing(output string) map[string]string {
+	tokens := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 7 || net.ParseIP(fields[0]) == nil {
+			continue
+		}
+		tokens[fields[len(fields)-1]] = fields[0]
+	}
+	return tokens
+}
+
+// primaryRanges returns the (previous, token] ranges of the node's tokens in the ring
+func primaryRanges(tokens []string, ring map[string]string) []string {
+	sorted := make([]*big.Int, 0, len(ring))
+	for token := range ring {
+		if value, ok := new(big.Int).SetString(token, 10); ok {
+			sorted = append(sorted, value)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Cmp(sorted[j]) < 0
+	})
+
+	ranges := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		value, ok := new(big.Int).SetString(token, 10)
+		if !ok || len(sorted) == 0 {
+			continue
+		}
+		i := sort.Search(len(sorted), func(i int) bool {
+			return sorted[i].Cmp(value) >= 0
+		})
+		previous := sorted[(i-1+len(sorted))%len(sorted)]
+		ranges = append(ranges, fmt.Sprintf("(%s,%s]", previous, value))
+	}
+	sort.Strings(ranges)
+	return ranges
+}
+
+// parseSchemaVersion finds the schema version of the address from nodetool describecluster and whether every reachable
+// node agrees on it
+func parseSchemaVersion(output, address string) (string, bool) {
+	version := ""
+	versions := 0
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), ":", 2)
+		if len(parts) != 2 || !strings.HasPrefix(strings.TrimSpace(parts[1]), "[") {
+			continue
+		}
+		if parts[0] != "UNREACHABLE" {
+			versions++
+		}
+		hosts := strings.Trim(strings.TrimSpace(parts[1]), "[]")
+		for _, host := range strings.Split(hosts, ",") {
+			host = strings.TrimPrefix(strings.TrimSpace(host), "/")
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+			if host == address {
+				version = parts[0]
+			}
+		}
+	}
+	return version, version != "" && version != "UNREACHABLE" && versions == 1
+}
+
+// parseTablestats lists the tables and sums the SSTable counts and live data sizes of the tables per keyspace. System keyspaces change
+// when the node starts and are not included.
+func parseTablestats(output string) map[string]KeyspaceData {
+	keyspaces := make(map[string]KeyspaceData)
+	keyspace := ""
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		key, value := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+
+		if key == "Keyspace" {
+			keyspace = value
+			if strings.HasPrefix(keyspace, "system") || strings.HasPrefix(keyspace, "dse_") {
+				keyspace = ""
+				continue
+			}
+			keyspaces[keyspace] = KeyspaceData{}
+			continue
+		}
+		if keyspace == "" {
+			continue
+		}
+
+		data := keyspaces[keyspace]
+		switch key {
+		case "Table":
+			data.Tables = append(data.Tables, value)
+		case "SSTable count":
+			count, err := strconv.Atoi(value)
+			if err != nil {
+				continue
+			}
+			data.SSTables += count
+		case "Space used (live)":
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				continue
+			}
+			data.SizeBytes += size
+		}
+		keyspaces[keyspace] = data
+	}
+	for keyspace, data := range keyspaces {
+		sort.Strings(data.Tables)
+		keyspaces[keyspace] = data
+	}
+	return keyspaces
+}
+
+// compareNodeStates lists the differences of the node's identity, token ownership, schema agreement and data after
+// the migration. SSTable counts and schema changes happen during normal operation, they are listed by nodeChanges.
+func compareNodeStates(before, after NodeState) []string {
+	mismatches := make([]string, 0)
+	compare := func(name, b, a string) {
+		if b != a {
+			mismatches = append(mismatches, fmt.Sprintf("%s changed from %s to %s", name, b, a))
+		}
+	}
+
+	compare("host ID", before.HostID, after.HostID)
+	compare("datacenter", before.Datacenter, after.Datacenter)
+	compare("rack", before.Rack, after.Rack)
+
+	if !after.SchemaAgreement {
+		mismatches = append(mismatches, fmt.Sprintf("schema version %s of the node is not in agreement with the cluster", after.SchemaVersion))
+	}
+
+	if !equalStrings(before.Tokens, after.Tokens) {
+		mismatches = append(mismatches, fmt.Sprintf("tokens changed, %d tokens before and %d after", len(before.Tokens), len(after.Tokens)))
+	}
+	if !equalStrings(before.OwnedRanges, after.OwnedRanges) {
+		mismatches = append(mismatches, "owned token ranges changed")
+	}
+
+	keyspaces := make([]string, 0, len(before.Keyspaces))
+	for keyspace := range before.Keyspaces {
+		keyspaces = append(keyspaces, keyspace)
+	}
+	sort.Strings(keyspaces)
+
+	for _, keyspace := range keyspaces {
+		b := before.Keyspaces[keyspace]
+		a, found := after.Keyspaces[keyspace]
+		if !found {
+			mismatches = append(mismatches, fmt.Sprintf("keyspace %s is missing", keyspace))
+			continue
+		}
+		for _, table := range missingStrings(b.Tables, a.Tables) {
+			mismatches = append(mismatches, fmt.Sprintf("table %s.%s is missing", keyspace, table))
+		}
+		if !withinSizeTolerance(b.SizeBytes, a.SizeBytes) {
+			mismatches = append(mismatches, fmt.Sprintf("keyspace %s data size changed from %d to %d bytes", keyspace, b.SizeBytes, a.SizeBytes))
+		}
+	}
+
+	return mismatches
+}
+
+// nodeChanges lists the expected differences of the node's state after the migration
+func nodeChanges(before, after NodeState) []string {
+	changes := make([]string, 0)
+	if before.SchemaVersion != after.SchemaVersion {
+		changes = append(changes, fmt.Sprintf("schema version changed from %s to %s", before.SchemaVersion, after.SchemaVersion))
+	}
+
+	keyspaces := make([]string, 0, len(before.Keyspaces))
+	for keyspace := range before.Keyspaces {
+		keyspaces = append(keyspaces, keyspace)
+	}
+	sort.Strings(keyspaces)
+
+	for _, keyspace := range keyspaces {
+		b := before.Keyspaces[keyspace]
+		if a, found := after.Keyspaces[keyspace]; found && a.SSTables != b.SSTables {
+			changes = append(changes, fmt.Sprintf("keyspace %s SSTable count changed from %d to %d", keyspace, b.SSTables, a.SSTables))
+		}
+	}
+	return changes
+}
+
+// withinSizeTolerance allows the size to change by sizeTolerance or minSizeToleranceMB, whichever is larger
+func withinSizeTolerance(before, after int64) bool {
+	tolerance := int64(float64(before) * sizeTolerance)
+	if minTolerance := int64(minSizeToleranceMB * 1024 * 1024); tolerance < minTolerance {
+		tolerance = minTolerance
+	}
+	diff := after - before
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= tolerance
+}
+
+// missingStrings returns the values of before which are not in after
+func missingStrings(before, after []string) []string {
+	found := make(map[string]bool, len(after))
+	for _, value := range after {
+		found[value] = true
+	}
+	missing := make([]string, 0)
+	for _, value := range before {
+		if !found[value] {
+			missing = append(missing, value)
+		}
+	}
+	return missing
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sortedA := append([]string{}, a...)
+	sortedB := append([]string{}, b...)
+	sort.Strings(sortedA)
+	sort.Strings(sortedB)
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// recordVerification stores the node's compressed verification record to the datacenter's verification ConfigMap.
+// Nodes can be migrated concurrently, so conflicts are retried.
+func recordVerification(cli client.Client, namespace, datacenter string, verification NodeVerification) error {
+	b, err := json.Marshal(verification)
+	if err != nil {
+		return err
+	}
+
+	compressed, err := compress(b)
+	if err != nil {
+		return err
+	}
+
+	key := verification.HostID + compressedSuffix
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap := &corev1.ConfigMap{}
+		configMapKey := types.NamespacedName{Name: verificationsConfigMapName(datacenter), Namespace: namespace}
+		if err := cli.Get(context.TODO(), configMapKey, configMap); err != nil {
+			if !errors.IsNotFound(err) {
+				return err
+			}
+			configMap.ObjectMeta.Name = configMapKey.Name
+			configMap.ObjectMeta.Namespace = configMapKey.Namespace
+			configMap.BinaryData = map[string][]byte{
+				key: compressed,
+			}
+			return cli.Create(context.TODO(), configMap)
+		}
+
+		if configMap.BinaryData == nil {
+			configMap.BinaryData = make(map[string][]byte)
+		}
+		// Records written by older versions are uncompressed
+		delete(configMap.Data, verification.HostID)
+		configMap.BinaryData[key] = compressed
+
+		size := 0
+		for name, data := range configMap.BinaryData {
+			size += len(name) + len(data)
+		}
+		if size > configMapSizeLimit {
+			return fmt.Errorf("verification records are too large to be stored in ConfigMap %s (%d bytes compressed)", configMap.Name, size)
+		}
+
+		return cli.Update(context.TODO(), configMap)
+	})
+}
+
+// FetchVerifications returns the verification records of the datacenter's migrated nodes, sorted by pod name
+func FetchVerifications(cli client.Client, namespace, datacenter string) ([]NodeVerification, error) {
+	configMap := &corev1.ConfigMap{}
+	configMapKey := types.NamespacedName{Name: verificationsConfigMapName(datacenter), Namespace: namespace}
+	if err := cli.Get(context.TODO(), configMapKey, configMap); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return decodeVerifications(configMap)
+}
+
+// decodeVerifications reads the compressed records and the uncompressed records written by older versions
+func decodeVerifications(configMap *corev1.ConfigMap) ([]NodeVerification, error) {
+	records, err := configFiles(configMap)
+	if err != nil {
+		return nil, err
+	}
+
+	verifications := make([]NodeVerification, 0, len(records))
+	for _, data := range records {
+		verification := NodeVerification{}
+		if err := json.Unmarshal([]byte(data), &verification); err != nil {
+			return nil, err
+		}
+		verifications = append(verifications, verification)
+	}
+
+	sort.Slice(verifications, func(i, j int) bool {
+		return verifications[i].Pod < verifications[j].Pod
+	})
+
+	return verifications, nil
+}
+
+// verifyPod compares the state of the node running in the pod to the state before the migration and records the check
+func verifyPod(cli client.Client, execOptions *exec.ExecOptions, namespace, datacenter, stage string, verification *NodeVerification) (*VerificationCheck, error) {
+	pod := &corev1.Pod{}
+	if err := cli.Get(context.TODO(), types.NamespacedName{Name: verification.Pod, Namespace: namespace}, pod); err != nil {
+		return nil, err
+	}
+
+	container, err := NewPodInstallation(execOptions, pod, CassandraContainerName)
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := captureNodeState(container.Nodetool)
+	if err != nil {
+		return nil, err
+	}
+
+	verification.LastCheck = &VerificationCheck{
+		Stage:      stage,
+		State:      after,
+		Mismatches: compareNodeStates(verification.Before, after),
+		Changes:    nodeChanges(verification.Before, after),
+	}
+
+	if err := recordVerification(cli, namespace, datacenter, *verification); err != nil {
+		return nil, err
+	}
+
+	return verification.Latest(), nil
+}
+
+// VerifyNodes checks every migrated node of the datacenter against its state before the migration. The returned
+// error lists the nodes with mismatches.
+func VerifyNodes(cli client.Client, execOptions *exec.ExecOptions, namespace, datacenter, stage string) ([]NodeVerification, error) {
+	verifications, err := FetchVerifications(cli, namespace, datacenter)
+	if err != nil {
+		return nil, err
+	}
+
+	failed := make([]string, 0)
+	for i := range verifications {
+		verification := &verifications[i]
+		check, err := verifyPod(cli, execOptions, namespace, datacenter, stage, verification)
+		if err != nil {
+			pterm.Error.Printf("Unable to verify pod %s: %v\n", verification.Pod, err)
+			failed = append(failed, verification.Pod)
+			continue
+		}
+		if len(check.Mismatches) > 0 {
+			failed = append(failed, verification.Pod)
+		}
+	}
+
+	if len(failed) > 0 {
+		return verifications, fmt.Errorf("verification failed for pods %s", strings.Join(failed, ", "))
+	}
+
+	return verifications, nil
+}
+
+// captureBeforeState stores the state of the drained local node. Drain has flushed the memtables, so the SSTables
+// are the ones the pod starts with.
+func (n *NodeMigrator) captureBeforeState() error {
+	before, err := captureNodeState(n.nodetool)
+	if err != nil {
+		return err
+	}
+
+	n.verification = &NodeVerification{
+		HostID: n.HostID,
+		Pod:    n.getPodName(),
+		Before: before,
+	}
+
+	return recordVerification(n.Client, n.Namespace, n.Datacenter, *n.verification)
+}
+
+// verifyStartedPod compares the started pod to the state captured before the node was stopped
+func (n *NodeMigrator) verifyStartedPod() error {
+	if n.verification == nil {
+		return fmt.Errorf("the state of the node was not captured before the migration")
+	}
+	if n.ExecOptions == nil {
+		return fmt.Errorf("no Kubernetes exec configuration to run nodetool in the pod")
+	}
+
+	check, err := verifyPod(n.Client, n.ExecOptions, n.Namespace, n.Datacenter, VerificationStarted, n.verification)
+	if err != nil {
+		return err
+	}
+
+	for _, change := range check.Changes {
+		pterm.Info.Println(change)
+	}
+	for _, mismatch := range check.Mismatches {
+		pterm.Warning.Println(mismatch)
+	}
+	if len(check.Mismatches) > 0 {
+		return fmt.Errorf("pod %s does not match the node before the migration", n.verification.Pod)
+	}
+
+	return nil
+}
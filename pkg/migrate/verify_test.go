@@ -0,0 +1,186 @@
+package migrate
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const nodetoolInfoOutput = `ID                     : 0a6b1a3e-5f3c-4c59-9bd4-7c1a0b0c1d01
+Gossip active          : true
+Native Transport active: true
+Load                   : 1.2 GiB
+Generation No          : 1666000000
+Uptime (seconds)       : 3600
+Heap Memory (MB)       : 512.00 / 2048.00
+Data Center            : dc1
+Rack                   : r1
+Exceptions             : 0
+Token                  : -3074457345618258603
+Token                  : 3074457345618258601
+`
+
+const nodetoolRingOutput = `
+Datacenter: dc1
+==========
+Address     Rack        Status State   Load            Owns                Token
+                                                                           6148914691236517202
+10.0.1.10   r1          Up     Normal  1.2 GiB         33.33%              -9223372036854775808
+10.0.1.11   r1          Up     Normal  1.1 GiB         33.33%              -3074457345618258603
+10.0.1.11   r1          Up     Normal  1.1 GiB         33.33%              3074457345618258601
+10.0.1.10   r1          Up     Normal  1.2 GiB         33.33%              6148914691236517202
+
+  Warning: "nodetool ring" is used to output all the tokens of a node.
+`
+
+const nodetoolDescribeClusterOutput = `Cluster Information:
+	Name: Test Cluster
+	Snitch: org.apache.cassandra.locator.GossipingPropertyFileSnitch
+	DynamicEndPointSnitch: enabled
+	Partitioner: org.apache.cassandra.dht.Murmur3Partitioner
+	Schema versions:
+		8e2a4c7b-1f6e-3a4d-9c2b-5d6e7f8a9b0c: [10.0.1.10:7000, 10.0.1.11:7000]
+
+		UNREACHABLE: [10.0.1.13]
+`
+
+const nodetoolTablestatsOutput = `Total number of tables: 40
+----------------
+Keyspace : system_auth
+	Read Count: 10
+		Table: roles
+		SSTable count: 1
+		Space used (live): 5124
+----------------
+Keyspace : shop
+	Read Count: 100
+	Write Count: 20
+		Table: orders
+		SSTable count: 3
+		Space used (live): 1048576
+		Space used (total): 1048576
+
+		Table: customers
+		SSTable count: 2
+		Space used (live): 4096
+----------------
+`
+
+func TestCaptureNodeState(t *testing.T) {
+	require := require.New(t)
+
+	outputs := map[string]string{
+		"info":            nodetoolInfoOutput,
+		"ring":            nodetoolRingOutput,
+		"describecluster": nodetoolDescribeClusterOutput,
+		"tablestats":      nodetoolTablestatsOutput,
+	}
+	state, err := captureNodeState(func(args ...string) (string, error) {
+		return outputs[args[0]], nil
+	})
+	require.NoError(err)
+
+	require.Equal("0a6b1a3e-5f3c-4c59-9bd4-7c1a0b0c1d01", state.HostID)
+	require.Equal("dc1", state.Datacenter)
+	require.Equal("r1", state.Rack)
+	require.Equal("10.0.1.11", state.Address)
+	require.Equal([]string{"-3074457345618258603", "3074457345618258601"}, state.Tokens)
+	require.Equal([]string{"(-3074457345618258603,3074457345618258601]", "(-9223372036854775808,-3074457345618258603]"}, state.OwnedRanges)
+
+	require.Len(state.Keyspaces, 1)
+	require.Equal("8e2a4c7b-1f6e-3a4d-9c2b-5d6e7f8a9b0c", state.SchemaVersion)
+	require.True(state.SchemaAgreement)
+	require.Equal(KeyspaceData{Tables: []string{"customers", "orders"}, SSTables: 5, SizeBytes: 1052672}, state.Keyspaces["shop"])
+}
+
+func TestPrimaryRangesWrapAround(t *testing.T) {
+	require := require.New(t)
+
+	ring := map[string]string{"-100": "10.0.1.10", "0": "10.0.1.11", "100": "10.0.1.12"}
+	require.Equal([]string{"(100,-100]"}, primaryRanges([]string{"-100"}, ring))
+	require.Equal([]string{"(-100,0]"}, primaryRanges([]string{"0"}, ring))
+
+	disagreement := nodetoolDescribeClusterOutput + "\t\t1b2c3d4e-5f6a-3b7c-8d9e-0f1a2b3c4d5e: [10.0.1.12]\n"
+	version, agreement := parseSchemaVersion(disagreement, "10.0.1.12")
+	require.Equal("1b2c3d4e-5f6a-3b7c-8d9e-0f1a2b3c4d5e", version)
+	require.False(agreement)
+	version, agreement = parseSchemaVersion(nodetoolDescribeClusterOutput, "10.0.1.13")
+	require.Equal("UNREACHABLE", version)
+	require.False(agreement)
+}
+
+func TestCompareNodeStates(t *testing.T) {
+	require := require.New(t)
+
+	before := NodeState{
+		HostID:          "host1",
+		Datacenter:      "dc1",
+		Rack:            "r1",
+		Tokens:          []string{"1", "2"},
+		OwnedRanges:     []string{"(0,1]", "(1,2]"},
+		SchemaVersion:   "schema1",
+		SchemaAgreement: true,
+		Keyspaces: map[string]KeyspaceData{
+			"shop":  {Tables: []string{"customers", "orders"}, SSTables: 3, SizeBytes: 100 * 1024 * 1024},
+			"users": {Tables: []string{"users"}, SSTables: 1, SizeBytes: 10},
+		},
+	}
+
+	after := before
+	after.Tokens = []string{"2", "1"}
+	after.SchemaVersion = "schema2"
+	after.Keyspaces = map[string]KeyspaceData{
+		"shop":  {Tables: []string{"customers", "orders", "returns"}, SSTables: 2, SizeBytes: 95 * 1024 * 1024},
+		"users": {Tables: []string{"users"}, SSTables: 1, SizeBytes: 512 * 1024},
+	}
+	require.Empty(compareNodeStates(before, after))
+	require.Equal([]string{
+		"schema version changed from schema1 to schema2",
+		"keyspace shop SSTable count changed from 3 to 2",
+	}, nodeChanges(before, after))
+
+	after.Rack = "r2"
+	after.SchemaAgreement = false
+	after.Keyspaces = map[string]KeyspaceData{
+		"shop": {Tables: []string{"orders"}, SizeBytes: 80 * 1024 * 1024},
+	}
+	require.Equal([]string{
+		"rack changed from r1 to r2",
+		"schema version schema2 of the node is not in agreement with the cluster",
+		"table shop.customers is missing",
+		"keyspace shop data size changed from 104857600 to 83886080 bytes",
+		"keyspace users is missing",
+	}, compareNodeStates(before, after))
+
+	verification := NodeVerification{}
+	require.Nil(verification.Latest())
+	verification.LastCheck = &VerificationCheck{Stage: VerificationCommitted}
+	require.Equal(VerificationCommitted, verification.Latest().Stage)
+}
+
+func TestDecodeVerifications(t *testing.T) {
+	require := require.New(t)
+
+	b, err := json.Marshal(NodeVerification{HostID: "host2", Pod: "pod-2"})
+	require.NoError(err)
+	compressed, err := compress(b)
+	require.NoError(err)
+
+	configMap := &corev1.ConfigMap{
+		Data: map[string]string{
+			"host1": `{"hostId":"host1","pod":"pod-1","checks":[{"stage":"started"}]}`,
+		},
+		BinaryData: map[string][]byte{
+			"host2" + compressedSuffix: compressed,
+		},
+	}
+
+	verifications, err := decodeVerifications(configMap)
+	require.NoError(err)
+	require.Len(verifications, 2)
+	require.Equal("pod-1", verifications[0].Pod)
+	require.Nil(verifications[0].Latest())
+	require.Equal("host2", verifications[1].HostID)
+}